@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"scss-lsp/lsp"
+)
+
+// runCheck is `scss-lsp check`, it prints the diagnostics the server would
+// show and fails when there are any that are bad enough
+func runCheck(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	root := flags.String("root", ".", "the root of the workspace")
+	format := flags.String("format", lsp.FormatHuman, "human, json, sarif, checkstyle or junit")
+	fail_on := flags.String("fail-on", "error", "the severity that fails the check, error, warning, information, hint or never")
+	out := flags.String("out", "", "the file to write the report to instead of stdout")
+	baseline := flags.String("baseline", "", "the baseline file, relative to the root, the one of the config by default")
+	update_baseline := flags.Bool("update-baseline", false, "record the current problems in the baseline instead of reporting them")
+	list_rules := flags.Bool("rules", false, "list the rules and what they check")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: scss-lsp check [-root dir] [-format name] [-fail-on severity] [-out file] [-baseline file] [-update-baseline] [-rules] [paths ...]")
+		fmt.Fprintln(flags.Output(), "without paths every file of the workspace is checked")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	severity, fails := lsp.ParseSeverity(*fail_on)
+	if !fails && *fail_on != "never" {
+		fmt.Fprintf(os.Stderr, "unknown severity %s\n", *fail_on)
+		flags.Usage()
+		return 2
+	}
+	root_path, err := filepath.Abs(*root)
+	if err == nil {
+		_, err = os.Stat(root_path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	paths := []string{}
+	for _, path := range flags.Args() {
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		paths = append(paths, absolute)
+	}
+
+	workspace := lsp.LoadWorkspace(root_path)
+	for _, message := range workspace.ConfigErrors {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", message)
+	}
+	if *list_rules {
+		fmt.Print(workspace.RuleDocs())
+		return 0
+	}
+	if *baseline != "" {
+		workspace.Config.Baseline = *baseline
+	}
+	if err := workspace.LoadBaseline(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *update_baseline {
+		count, err := workspace.UpdateBaseline(paths)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "the baseline has %d problems\n", count)
+		return 0
+	}
+
+	results := workspace.Check(paths)
+	report, err := lsp.FormatDiagnostics(*format, results)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flags.Usage()
+		return 2
+	}
+	if *out == "" {
+		fmt.Print(report)
+	} else if err := os.WriteFile(*out, []byte(report), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if fails && lsp.CountAtLeast(results, severity) > 0 {
+		return 1
+	}
+	return 0
+}
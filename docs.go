@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"scss-lsp/lsp"
+)
+
+// runDocs is `scss-lsp docs`, it writes a style guide of the public members
+// of the workspace from their SassDoc comments
+func runDocs(args []string) int {
+	flags := flag.NewFlagSet("docs", flag.ContinueOnError)
+	root := flags.String("root", ".", "the root of the workspace")
+	format := flags.String("format", "html", "html, markdown or json")
+	out := flags.String("out", "", "the file to write to instead of stdout")
+	source_url := flags.String("source-url", "", "put in front of the paths of source links, like https://example.com/repo/blob/main/")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: scss-lsp docs [-root dir] [-format html|markdown|json] [-out file] [-source-url url]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	root_path, err := filepath.Abs(*root)
+	if err == nil {
+		_, err = os.Stat(root_path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	groups := lsp.LoadWorkspace(root_path).StyleGuide(*source_url)
+	var output []byte
+	switch *format {
+	case "html":
+		html, err := lsp.StyleGuideHTML(groups)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		output = []byte(html)
+	case "markdown", "md":
+		output = []byte(lsp.StyleGuideMarkdown(groups))
+	case "json":
+		output, err = json.MarshalIndent(groups, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		output = append(output, '\n')
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %s\n", *format)
+		flags.Usage()
+		return 2
+	}
+
+	if *out == "" {
+		os.Stdout.Write(output)
+		return 0
+	}
+	if err := os.WriteFile(*out, output, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,168 @@
+package lsp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// the baseline is looked up in the root of the workspace, unless the config
+// says otherwise
+const baselineFileName = ".scss-lsp-baseline.json"
+
+const baselineVersion = 1
+
+// one known problem, the fingerprint does not include the line so it
+// survives lines being added above it
+type BaselineEntry struct {
+	Rule        string `json:"rule"`
+	Message     string `json:"message"`
+	Fingerprint string `json:"fingerprint"`
+	// how many times the same problem is on lines with the same text
+	Count int `json:"count"`
+}
+
+// the known problems of the workspace, they are not reported, the files are
+// relative to the root
+type Baseline struct {
+	Version int                        `json:"version"`
+	Files   map[string][]BaselineEntry `json:"files"`
+}
+
+func (lsp *Lsp) baselinePath() string {
+	path := lsp.Config.Baseline
+	if path == "" {
+		path = baselineFileName
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(lsp.RootPath, path)
+	}
+	return path
+}
+
+// baselineKey is the path of a file in the baseline
+func (lsp *Lsp) baselineKey(path string) string {
+	return filepath.ToSlash(lsp.relativePath(path))
+}
+
+// LoadBaseline reads the baseline the config points at, there is none when
+// the file doesnt exist
+func (lsp *Lsp) LoadBaseline() error {
+	lsp.Baseline = nil
+	data, err := os.ReadFile(lsp.baselinePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	baseline := &Baseline{}
+	if err := json.Unmarshal(data, baseline); err != nil {
+		return err
+	}
+	lsp.Baseline = baseline
+	return nil
+}
+
+// diagnosticFingerprint hashes the rule, the message and the text of the
+// line the diagnostic starts on, indentation aside
+func diagnosticFingerprint(input []byte, diagnostic protocol.Diagnostic) string {
+	lines := strings.Split(string(input), "\n")
+	line := ""
+	if int(diagnostic.Range.Start.Line) < len(lines) {
+		line = strings.TrimSpace(lines[diagnostic.Range.Start.Line])
+	}
+	hash := sha256.Sum256([]byte(diagnosticCode(diagnostic) + "\n" + diagnostic.Message + "\n" + line))
+	return hex.EncodeToString(hash[:8])
+}
+
+// withoutBaseline drops the diagnostics that are in the baseline, as many
+// of each as it has
+func (lsp *Lsp) withoutBaseline(path string, diagnostics []protocol.Diagnostic) []protocol.Diagnostic {
+	if lsp.Baseline == nil || len(lsp.Baseline.Files[lsp.baselineKey(path)]) == 0 {
+		return diagnostics
+	}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return diagnostics
+	}
+	known := map[string]int{}
+	for _, entry := range lsp.Baseline.Files[lsp.baselineKey(path)] {
+		known[entry.Fingerprint] += entry.Count
+	}
+	remaining := []protocol.Diagnostic{}
+	for _, diagnostic := range diagnostics {
+		fingerprint := diagnosticFingerprint(*input, diagnostic)
+		if known[fingerprint] > 0 {
+			known[fingerprint]--
+			continue
+		}
+		remaining = append(remaining, diagnostic)
+	}
+	return remaining
+}
+
+// UpdateBaseline records the current diagnostics of the files under paths,
+// every file without any, and writes the baseline. Files that were not
+// checked keep what the baseline had for them. It returns how many problems
+// the baseline has.
+func (lsp *Lsp) UpdateBaseline(paths []string) (int, error) {
+	baseline := &Baseline{Version: baselineVersion, Files: map[string][]BaselineEntry{}}
+	if lsp.Baseline != nil {
+		for key, entries := range lsp.Baseline.Files {
+			baseline.Files[key] = entries
+		}
+	}
+	// everything is new to an empty baseline
+	lsp.Baseline = nil
+	for _, path := range sortedPaths(lsp.Trees) {
+		if len(paths) > 0 && !isUnderAny(path, paths) {
+			continue
+		}
+		key := lsp.baselineKey(path)
+		delete(baseline.Files, key)
+		input, err := lsp.bytesFromFilePath(path)
+		if err != nil {
+			continue
+		}
+		entries := []BaselineEntry{}
+		index := map[string]int{}
+		for _, diagnostic := range lsp.getDiagnostics(path) {
+			fingerprint := diagnosticFingerprint(*input, diagnostic)
+			if idx, ok := index[fingerprint]; ok {
+				entries[idx].Count++
+				continue
+			}
+			index[fingerprint] = len(entries)
+			entries = append(entries, BaselineEntry{
+				Rule:        diagnosticCode(diagnostic),
+				Message:     diagnostic.Message,
+				Fingerprint: fingerprint,
+				Count:       1,
+			})
+		}
+		if len(entries) > 0 {
+			sort.SliceStable(entries, func(i, j int) bool { return entries[i].Rule < entries[j].Rule })
+			baseline.Files[key] = entries
+		}
+	}
+	lsp.Baseline = baseline
+
+	count := 0
+	for _, entries := range baseline.Files {
+		for _, entry := range entries {
+			count += entry.Count
+		}
+	}
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return count, err
+	}
+	return count, os.WriteFile(lsp.baselinePath(), append(data, '\n'), 0644)
+}
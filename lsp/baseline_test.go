@@ -0,0 +1,57 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"legacy.scss": ".a { @include missing; }\n.b { @include missing; }\n",
+		"main.scss":   ".c { color: red; }\n",
+	})
+	lsp := LoadWorkspace(root)
+	count, err := lsp.UpdateBaseline(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 problems in the baseline, got %d", count)
+	}
+	if _, err := os.Stat(filepath.Join(root, baselineFileName)); err != nil {
+		t.Fatal(err)
+	}
+	if diagnostics := lsp.getDiagnostics(filepath.Join(root, "legacy.scss")); len(diagnostics) != 0 {
+		t.Fatalf("expected the baseline to hide %+v", diagnostics)
+	}
+
+	// the known problems moved down a line, one is new
+	writeFiles(t, root, map[string]string{
+		"legacy.scss": "// moved\n.a { @include missing; }\n.b { @include missing; }\n.d { @include missing; }\n",
+		"main.scss":   ".c { @include missing; }\n",
+	})
+	lsp = LoadWorkspace(root)
+	diagnostics := lsp.getDiagnostics(filepath.Join(root, "legacy.scss"))
+	if len(diagnostics) != 1 || diagnostics[0].Range.Start.Line != 3 {
+		t.Fatalf("expected only the new problem, got %+v", diagnostics)
+	}
+	if diagnostics := lsp.getDiagnostics(filepath.Join(root, "main.scss")); len(diagnostics) != 1 {
+		t.Fatalf("expected the problem of another file, got %+v", diagnostics)
+	}
+
+	// updating one file keeps the others
+	count, err = lsp.UpdateBaseline([]string{filepath.Join(root, "main.scss")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || len(lsp.Baseline.Files["legacy.scss"]) != 2 || len(lsp.Baseline.Files["main.scss"]) != 1 {
+		t.Fatalf("unexpected baseline %+v", lsp.Baseline)
+	}
+
+	lsp.Config.Baseline = "missing.json"
+	if err := lsp.LoadBaseline(); err != nil || lsp.Baseline != nil {
+		t.Fatalf("expected no baseline, got %+v %v", lsp.Baseline, err)
+	}
+}
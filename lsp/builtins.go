@@ -0,0 +1,206 @@
+package lsp
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// the built-in modules of sass, sass:math, sass:color and the rest, with
+// their members and the old global functions they replace
+//
+//go:embed data/sass_modules.json
+var sassModulesJson []byte
+
+type builtinMember struct {
+	Name        string   `json:"name"`
+	Parameters  []string `json:"parameters"`
+	Description string   `json:"description"`
+	// the deprecated global function that does the same thing
+	Global  string `json:"global"`
+	Returns string `json:"returns"`
+}
+
+type builtinModule struct {
+	Name      string          `json:"name"`
+	Variables []builtinMember `json:"variables"`
+	Functions []builtinMember `json:"functions"`
+	Mixins    []builtinMember `json:"mixins"`
+}
+
+// a global function like darken() that has no member with the same
+// arguments, the replacement says how to call the module instead, {1} is
+// the first argument and {args} all of them
+type legacyFunction struct {
+	Name        string   `json:"name"`
+	Parameters  []string `json:"parameters"`
+	Description string   `json:"description"`
+	Module      string   `json:"module"`
+	Replacement string   `json:"replacement"`
+}
+
+// a global built-in function and what to use instead
+type builtinGlobal struct {
+	name        string
+	parameters  []string
+	description string
+	module      string
+	replacement string
+}
+
+var (
+	loadBuiltins   sync.Once
+	builtinModules map[string]builtinModule
+	builtinGlobals map[string]builtinGlobal
+)
+
+func getBuiltins() {
+	loadBuiltins.Do(func() {
+		var data struct {
+			Modules []builtinModule  `json:"modules"`
+			Legacy  []legacyFunction `json:"legacy"`
+		}
+		if err := json.Unmarshal(sassModulesJson, &data); err != nil {
+			// the file is part of the binary, this can only happen while
+			// working on it
+			panic(err)
+		}
+		builtinModules = map[string]builtinModule{}
+		builtinGlobals = map[string]builtinGlobal{}
+		for _, module := range data.Modules {
+			builtinModules[module.Name] = module
+			for _, function := range module.Functions {
+				if function.Global == "" {
+					continue
+				}
+				builtinGlobals[function.Global] = builtinGlobal{
+					name:        function.Global,
+					parameters:  function.Parameters,
+					description: function.Description,
+					module:      module.Name,
+					replacement: module.Name + "." + function.Name + "({args})",
+				}
+			}
+		}
+		for _, legacy := range data.Legacy {
+			builtinGlobals[legacy.Name] = builtinGlobal{
+				name:        legacy.Name,
+				parameters:  legacy.Parameters,
+				description: legacy.Description,
+				module:      legacy.Module,
+				replacement: legacy.Replacement,
+			}
+		}
+	})
+}
+
+// lookupBuiltinModule takes the url of the @use, like "sass:math"
+func lookupBuiltinModule(url string) (builtinModule, bool) {
+	if !strings.HasPrefix(url, "sass:") {
+		return builtinModule{}, false
+	}
+	getBuiltins()
+	module, ok := builtinModules[strings.TrimPrefix(url, "sass:")]
+	return module, ok
+}
+
+func lookupBuiltinGlobal(name string) (builtinGlobal, bool) {
+	getBuiltins()
+	global, ok := builtinGlobals[name]
+	return global, ok
+}
+
+// member finds a function, mixin or variable of the module, variables
+// start with $
+func (module builtinModule) member(name string, item_type string) (builtinMember, bool) {
+	members := module.Functions
+	switch {
+	case strings.HasPrefix(name, "$"):
+		members = module.Variables
+	case item_type == itemTypeMixin:
+		members = module.Mixins
+	}
+	for _, member := range members {
+		if member.Name == name {
+			return member, true
+		}
+	}
+	return builtinMember{}, false
+}
+
+func (member builtinMember) signature(namespace string) string {
+	if strings.HasPrefix(member.Name, "$") {
+		return namespace + "." + member.Name
+	}
+	return namespace + "." + member.Name + "(" + strings.Join(member.Parameters, ", ") + ")"
+}
+
+// documentation is the markdown shown in hover and completion
+func (member builtinMember) documentation(module string) string {
+	var sb strings.Builder
+	sb.WriteString("```scss\n")
+	if member.Returns != "" {
+		sb.WriteString("@function ")
+	}
+	sb.WriteString(member.signature(module))
+	sb.WriteString("\n```\n")
+	sb.WriteString(member.Description)
+	sb.WriteString("\n\nbuilt-in module sass:")
+	sb.WriteString(module)
+	return sb.String()
+}
+
+func (global builtinGlobal) signature() string {
+	return global.name + "(" + strings.Join(global.parameters, ", ") + ")"
+}
+
+// replacementName is the member of the module to use instead, like
+// color.adjust
+func (global builtinGlobal) replacementName() string {
+	return strings.SplitN(global.replacement, "(", 2)[0]
+}
+
+func (global builtinGlobal) documentation() string {
+	return fmt.Sprintf("```scss\n@function %s\n```\n%s\n\ndeprecated global function, use `%s` from sass:%s",
+		global.signature(), global.description, global.replacementName(), global.module)
+}
+
+// builtinHover shows the docs of ns.member of a built-in module and of the
+// global built-in functions
+func (lsp *Lsp) builtinHover(path string, input []byte, node *sitter.Node, position sitter.Point) (string, bool) {
+	word, before := wordAt(input, position)
+
+	if namespace, member, found := strings.Cut(word, "."); found {
+		statement, ok := lsp.namespaceModule(path, namespace)
+		if !ok {
+			return "", false
+		}
+		module, ok := lookupBuiltinModule(statement.url)
+		if !ok {
+			return "", false
+		}
+		item_type := itemTypeFunction
+		if strings.HasSuffix(strings.TrimRight(before, " \t"), "@include") {
+			item_type = itemTypeMixin
+		}
+		builtin_member, ok := module.member(member, item_type)
+		if !ok {
+			return "", false
+		}
+		return builtin_member.documentation(namespace), true
+	}
+
+	// a function of the workspace with the same name wins
+	if node.Type() != "function_name" || len(lsp.definitionsOfType(word, itemTypeFunction)) > 0 {
+		return "", false
+	}
+	global, ok := lookupBuiltinGlobal(word)
+	if !ok {
+		return "", false
+	}
+	return global.documentation(), true
+}
@@ -0,0 +1,104 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+const builtinSource = `@use "sass:math";
+@use "sass:meta";
+@use "colors" as c;
+.a {
+  width: math.div(10px, 2) + math.$pi;
+  height: math.nope(1) + math.$nope;
+  color: darken(c.$primary, 10%) c.$missing;
+  @include meta.load-css("x");
+}
+`
+
+func TestBuiltinDiagnostics(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/_colors.scss": "$primary: red;\n$-private: blue;\n",
+		path:                    builtinSource,
+	})
+
+	diagnostics := lsp.getDiagnostics(path)
+	messages := []string{}
+	for _, diagnostic := range diagnostics {
+		messages = append(messages, diagnostic.Message)
+	}
+	expected := []string{
+		"undefined member, math has no nope",
+		"undefined member, math has no $nope",
+		"undefined member, c has no $missing",
+		"darken() is deprecated, use color.adjust from sass:color",
+	}
+	if strings.Join(messages, "|") != strings.Join(expected, "|") {
+		t.Fatalf("unexpected diagnostics %v", messages)
+	}
+	if diagnostics[0].Range != rangeFromPoints(sitter.Point{Row: 5, Column: 10}, sitter.Point{Row: 5, Column: 19}) {
+		t.Fatalf("unexpected range %+v", diagnostics[0].Range)
+	}
+}
+
+func TestBuiltinCompletion(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{path: "@use \"sass:math\";\n@use \"sass:meta\";\n.a {\n  width: math.;\n  @include meta.\n}\n"})
+
+	labels := completionLabels(lsp.GetCompletions(path, protocol.Position{Line: 3, Character: 14}))
+	div, ok := labels["math.div"]
+	if !ok || div.TextEdit.NewText != `math.div(${1:\$number1}, ${2:\$number2})` {
+		t.Fatalf("unexpected math.div %+v", div)
+	}
+	if _, ok := labels["math.$pi"]; !ok {
+		t.Fatalf("expected math.$pi in %v", labels)
+	}
+
+	labels = completionLabels(lsp.GetCompletions(path, protocol.Position{Line: 4, Character: 16}))
+	load_css, ok := labels["meta.load-css"]
+	if !ok || load_css.TextEdit.NewText != `load-css(${1:\$url})` || load_css.TextEdit.Range.Start.Character != 16 {
+		t.Fatalf("unexpected meta.load-css %+v", load_css)
+	}
+}
+
+func TestSignatureHelp(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/_mixins.scss": "@mixin pad($x, $y: 0) {}\n",
+		path:                    "@use \"sass:math\";\n@import \"mixins\";\n.a {\n  width: math.div(10px, (1 + 2), );\n  @include pad(1px, );\n  color: darken($c, );\n}\n",
+	})
+
+	help := lsp.GetSignatureHelp(path, protocol.Position{Line: 3, Character: 33})
+	if help == nil || help.Signatures[0].Label != "math.div($number1, $number2)" || help.ActiveParameter != 1 {
+		t.Fatalf("unexpected signature help %+v", help)
+	}
+	help = lsp.GetSignatureHelp(path, protocol.Position{Line: 4, Character: 20})
+	if help == nil || help.Signatures[0].Label != "pad($x, $y: 0)" || help.ActiveParameter != 1 {
+		t.Fatalf("unexpected signature help %+v", help)
+	}
+	help = lsp.GetSignatureHelp(path, protocol.Position{Line: 5, Character: 20})
+	if help == nil || help.Signatures[0].Label != "darken($color, $amount)" {
+		t.Fatalf("unexpected signature help %+v", help)
+	}
+	if help := lsp.GetSignatureHelp(path, protocol.Position{Line: 2, Character: 3}); help != nil {
+		t.Fatalf("expected no signature help outside of a call, got %+v", help)
+	}
+}
+
+func TestBuiltinHover(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{path: "@use \"sass:math\";\n.a {\n  width: math.div(1, 2);\n  color: darken($c, 10%);\n}\n"})
+
+	hover := lsp.GetHoverInfo(path, sitter.Point{Row: 2, Column: 15})
+	if !strings.Contains(hover, "math.div($number1, $number2)") || !strings.Contains(hover, "sass:math") {
+		t.Fatalf("unexpected hover %q", hover)
+	}
+	hover = lsp.GetHoverInfo(path, sitter.Point{Row: 3, Column: 10})
+	if !strings.Contains(hover, "deprecated global function, use `color.adjust` from sass:color") {
+		t.Fatalf("unexpected hover %q", hover)
+	}
+}
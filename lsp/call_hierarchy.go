@@ -0,0 +1,165 @@
+package lsp
+
+import (
+	"path/filepath"
+	"sort"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func symbolKindForItemType(item_type string) protocol.SymbolKind {
+	switch item_type {
+	case itemTypeMixin:
+		return protocol.SymbolKindInterface
+	case itemTypeFunction:
+		return protocol.SymbolKindFunction
+	case itemTypeRuleSet:
+		return protocol.SymbolKindClass
+	}
+	return protocol.SymbolKindFile
+}
+
+func itemTypeForSymbolKind(kind protocol.SymbolKind) string {
+	switch kind {
+	case protocol.SymbolKindInterface:
+		return itemTypeMixin
+	case protocol.SymbolKindFunction:
+		return itemTypeFunction
+	case protocol.SymbolKindClass:
+		return itemTypeRuleSet
+	}
+	return ""
+}
+
+func callHierarchyItem(path string, entry *isDefined, item_type string) protocol.CallHierarchyItem {
+	entry_range := rangeFromPoints(entry.start_position, entry.end_position)
+	return protocol.CallHierarchyItem{
+		Name:           entry.name,
+		Kind:           symbolKindForItemType(item_type),
+		Detail:         entry.body,
+		URI:            uri.File(path),
+		Range:          entry_range,
+		SelectionRange: entry_range,
+	}
+}
+
+// calls at the top of a file have no caller, the file itself is the caller
+func fileCallHierarchyItem(path string) protocol.CallHierarchyItem {
+	return protocol.CallHierarchyItem{
+		Name:   filepath.Base(path),
+		Kind:   protocol.SymbolKindFile,
+		Detail: path,
+		URI:    uri.File(path),
+	}
+}
+
+func (lsp *Lsp) definitionsOfType(name string, item_type string) []isDefinedInfo {
+	switch item_type {
+	case itemTypeMixin:
+		return *lsp.findHoverableByNameInMap(&name, &lsp.Mixins, &item_type)
+	case itemTypeFunction:
+		return *lsp.findHoverableByNameInMap(&name, &lsp.Functions, &item_type)
+	}
+	return []isDefinedInfo{}
+}
+
+// sortedPaths keeps the results stable, map iteration order is random
+func sortedPaths[T any](paths map[string]T) []string {
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func (lsp *Lsp) PrepareCallHierarchy(path string, position sitter.Point) []protocol.CallHierarchyItem {
+	items := []protocol.CallHierarchyItem{}
+	target, ok := lsp.symbolAtPosition(path, position)
+	if !ok || (target.item_type != itemTypeMixin && target.item_type != itemTypeFunction) {
+		return items
+	}
+	for _, definition := range lsp.definitionsOfType(target.name, target.item_type) {
+		items = append(items, callHierarchyItem(definition.path, &definition.is_defined, target.item_type))
+	}
+	return items
+}
+
+func (lsp *Lsp) GetIncomingCalls(item protocol.CallHierarchyItem) []protocol.CallHierarchyIncomingCall {
+	incoming_calls := []protocol.CallHierarchyIncomingCall{}
+	item_type := itemTypeForSymbolKind(item.Kind)
+	if item_type != itemTypeMixin && item_type != itemTypeFunction {
+		return incoming_calls
+	}
+	for _, path := range sortedPaths(lsp.Calls) {
+		// calls are grouped by whoever makes them
+		by_caller := map[*isDefined]int{}
+		for _, entry := range lsp.Calls[path] {
+			if entry.item_type != item_type || entry.name != item.Name {
+				continue
+			}
+			call_range := rangeFromPoints(entry.start_position, entry.end_position)
+			if idx, ok := by_caller[entry.caller]; ok {
+				incoming_calls[idx].FromRanges = append(incoming_calls[idx].FromRanges, call_range)
+				continue
+			}
+			from := fileCallHierarchyItem(path)
+			if entry.caller != nil {
+				from = callHierarchyItem(path, entry.caller, entry.caller.item_type)
+			}
+			by_caller[entry.caller] = len(incoming_calls)
+			incoming_calls = append(incoming_calls, protocol.CallHierarchyIncomingCall{
+				From:       from,
+				FromRanges: []protocol.Range{call_range},
+			})
+		}
+	}
+	return incoming_calls
+}
+
+func (lsp *Lsp) GetOutgoingCalls(item protocol.CallHierarchyItem) []protocol.CallHierarchyOutgoingCall {
+	outgoing_calls := []protocol.CallHierarchyOutgoingCall{}
+	path := item.URI.Filename()
+	item_type := itemTypeForSymbolKind(item.Kind)
+
+	// the calls made by the item, grouped by what they call
+	calls := map[string][]isDefined{}
+	order := []string{}
+	for _, entry := range lsp.Calls[path] {
+		if entry.item_type != itemTypeMixin && entry.item_type != itemTypeFunction {
+			continue
+		}
+		if item.Kind == protocol.SymbolKindFile {
+			if entry.caller != nil {
+				continue
+			}
+		} else if entry.caller == nil || entry.caller.item_type != item_type ||
+			entry.caller.start_position.Row != item.Range.Start.Line ||
+			entry.caller.start_position.Column != item.Range.Start.Character {
+			continue
+		}
+		key := entry.item_type + entry.name
+		if _, ok := calls[key]; !ok {
+			order = append(order, key)
+		}
+		calls[key] = append(calls[key], entry)
+	}
+
+	for _, key := range order {
+		entries := calls[key]
+		from_ranges := []protocol.Range{}
+		for _, entry := range entries {
+			from_ranges = append(from_ranges, rangeFromPoints(entry.start_position, entry.end_position))
+		}
+		// undefined mixins and functions are not in the hierarchy
+		for _, definition := range lsp.definitionsOfType(entries[0].name, entries[0].item_type) {
+			outgoing_calls = append(outgoing_calls, protocol.CallHierarchyOutgoingCall{
+				To:         callHierarchyItem(definition.path, &definition.is_defined, entries[0].item_type),
+				FromRanges: from_ranges,
+			})
+		}
+	}
+	return outgoing_calls
+}
@@ -0,0 +1,84 @@
+package lsp
+
+import (
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+const callHierarchyMixins = `@function double($x) {
+  @return $x * 2;
+}
+
+@mixin spacing($x) {
+  margin: double($x);
+  padding: double($x);
+}
+
+@mixin button() {
+  @include spacing(1px);
+}
+`
+
+const callHierarchyStyles = `.btn {
+  @include button;
+  .icon {
+    @include spacing(2px);
+  }
+}
+@include button;
+`
+
+func TestCallHierarchy(t *testing.T) {
+	mixins := "/virtual/_mixins.scss"
+	styles := "/virtual/styles.scss"
+	lsp := lspWithSources(t, map[string]string{mixins: callHierarchyMixins, styles: callHierarchyStyles})
+
+	// on the spacing in `@include spacing(2px);`
+	items := lsp.PrepareCallHierarchy(styles, sitter.Point{Row: 3, Column: 14})
+	if len(items) != 1 || items[0].Name != "spacing" || items[0].Kind != protocol.SymbolKindInterface {
+		t.Fatalf("expected the spacing mixin, got %+v", items)
+	}
+	if items[0].URI.Filename() != mixins || items[0].Range.Start.Line != 4 {
+		t.Fatalf("expected the definition in %s, got %+v", mixins, items[0])
+	}
+
+	incoming := lsp.GetIncomingCalls(items[0])
+	expected := []string{"button", ".btn .icon"}
+	if len(incoming) != len(expected) {
+		t.Fatalf("expected %d incoming calls, got %d: %+v", len(expected), len(incoming), incoming)
+	}
+	for idx := range incoming {
+		if incoming[idx].From.Name != expected[idx] {
+			t.Fatalf("expected %s, got %s at %v", expected[idx], incoming[idx].From.Name, idx)
+		}
+	}
+
+	outgoing := lsp.GetOutgoingCalls(items[0])
+	if len(outgoing) != 1 || outgoing[0].To.Name != "double" || len(outgoing[0].FromRanges) != 2 {
+		t.Fatalf("expected 2 calls to double, got %+v", outgoing)
+	}
+}
+
+func TestCallHierarchyTopLevelCaller(t *testing.T) {
+	mixins := "/virtual/_mixins.scss"
+	styles := "/virtual/styles.scss"
+	lsp := lspWithSources(t, map[string]string{mixins: callHierarchyMixins, styles: callHierarchyStyles})
+
+	items := lsp.PrepareCallHierarchy(mixins, sitter.Point{Row: 9, Column: 8})
+	if len(items) != 1 || items[0].Name != "button" {
+		t.Fatalf("expected the button mixin, got %+v", items)
+	}
+	incoming := lsp.GetIncomingCalls(items[0])
+	if len(incoming) != 2 {
+		t.Fatalf("expected 2 incoming calls, got %+v", incoming)
+	}
+	if incoming[0].From.Name != ".btn" || incoming[1].From.Kind != protocol.SymbolKindFile {
+		t.Fatalf("expected .btn and the file, got %+v", incoming)
+	}
+	outgoing := lsp.GetOutgoingCalls(incoming[1].From)
+	if len(outgoing) != 1 || outgoing[0].To.Name != "button" {
+		t.Fatalf("expected the file to call button, got %+v", outgoing)
+	}
+}
@@ -0,0 +1,397 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// the code of the diagnostic for calls to things that are not defined
+const diagnosticUndefined = "undefined"
+
+// commands that code actions can run through workspace/executeCommand
+const commandAllowFunction = "scss-lsp.allowFunction"
+
+// a quickFixProvider makes the fixes for the diagnostics of one rule
+type quickFixProvider func(lsp *Lsp, path string, diagnostic protocol.Diagnostic) []protocol.CodeAction
+
+func diagnosticCode(diagnostic protocol.Diagnostic) string {
+	code, ok := diagnostic.Code.(string)
+	if !ok {
+		return ""
+	}
+	return code
+}
+
+// wantsKind checks the `only` filter of the client, an empty filter wants all
+func wantsKind(only []protocol.CodeActionKind, kind protocol.CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, wanted := range only {
+		if kind == wanted || strings.HasPrefix(string(kind), string(wanted)+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (lsp *Lsp) GetCodeActions(path string, context protocol.CodeActionContext) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	if lsp.Trees[path] == nil || !wantsKind(context.Only, protocol.QuickFix) {
+		return actions
+	}
+	for _, diagnostic := range context.Diagnostics {
+		if rule, ok := lsp.lookupRule(diagnosticCode(diagnostic)); ok {
+			actions = append(actions, rule.Fixes(lsp, path, diagnostic)...)
+		}
+		// every diagnostic of the server can be hidden with a comment
+		actions = append(actions, suppressionQuickFixes(lsp, path, diagnostic)...)
+	}
+	return actions
+}
+
+func quickFix(title string, diagnostic protocol.Diagnostic, path string, edits []protocol.TextEdit) protocol.CodeAction {
+	return protocol.CodeAction{
+		Title:       title,
+		Kind:        protocol.QuickFix,
+		Diagnostics: []protocol.Diagnostic{diagnostic},
+		Edit: &protocol.WorkspaceEdit{
+			Changes: map[uri.URI][]protocol.TextEdit{
+				uri.File(path): edits,
+			},
+		},
+	}
+}
+
+// callAtRange finds the call a diagnostic was made for
+func (lsp *Lsp) callAtRange(path string, diagnostic_range protocol.Range) (isDefined, bool) {
+	for _, entry := range lsp.Calls[path] {
+		if rangeFromPoints(entry.start_position, entry.end_position) == diagnostic_range {
+			return entry, true
+		}
+	}
+	return isDefined{}, false
+}
+
+func (lsp *Lsp) definitionMap(item_type string) map[string][]isDefined {
+	switch item_type {
+	case itemTypeMixin:
+		return lsp.Mixins
+	case itemTypeFunction:
+		return lsp.Functions
+	case itemTypeVariable:
+		return lsp.Variables
+	}
+	return map[string][]isDefined{}
+}
+
+func undefinedQuickFixes(lsp *Lsp, path string, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	call, ok := lsp.callAtRange(path, diagnostic.Range)
+	if !ok {
+		return actions
+	}
+	actions = append(actions, lsp.addUseFixes(path, call, diagnostic)...)
+	actions = append(actions, lsp.didYouMeanFixes(path, call, diagnostic)...)
+	if stub, ok := lsp.stubFix(path, call, diagnostic); ok {
+		actions = append(actions, stub)
+	}
+	if call.item_type == itemTypeFunction {
+		actions = append(actions, protocol.CodeAction{
+			Title:       fmt.Sprintf("Add %s to allowed functions", call.name),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diagnostic},
+			Command: &protocol.Command{
+				Title:     "Allow function",
+				Command:   commandAllowFunction,
+				Arguments: []interface{}{call.name},
+			},
+		})
+	}
+	return actions
+}
+
+// moduleUrl is how a file would be loaded from another file, without the
+// extension, the _ of partials and index files
+func moduleUrl(from_path string, to_path string) string {
+	relative, err := filepath.Rel(filepath.Dir(from_path), to_path)
+	if err != nil {
+		relative = to_path
+	}
+	dir, name := filepath.Split(filepath.ToSlash(relative))
+	name = strings.TrimPrefix(strings.TrimSuffix(name, filepath.Ext(name)), "_")
+	if name == "index" && dir != "" {
+		return strings.TrimSuffix(dir, "/")
+	}
+	return dir + name
+}
+
+// loadsModule checks if the file already has a @use, @forward or @import of
+// the other file
+func (lsp *Lsp) loadsModule(path string, module_path string) bool {
+	for _, statement := range lsp.Modules[path] {
+		if target, ok := lsp.resolveModule(path, statement.url); ok && target == module_path {
+			return true
+		}
+	}
+	return false
+}
+
+// useInsertPosition is after the last @use or @forward, @use has to come
+// before everything else
+func (lsp *Lsp) useInsertPosition(path string) protocol.Position {
+	position := protocol.Position{}
+	for _, statement := range lsp.Modules[path] {
+		if statement.kind == "@import" {
+			continue
+		}
+		if statement.statement_end.Row+1 > position.Line {
+			position.Line = statement.statement_end.Row + 1
+		}
+	}
+	return position
+}
+
+func (lsp *Lsp) addUseFixes(path string, call isDefined, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	definitions := lsp.definitionMap(call.item_type)
+	for _, defined_in := range sortedPaths(definitions) {
+		if defined_in == path || lsp.loadsModule(path, defined_in) {
+			continue
+		}
+		for _, entry := range definitions[defined_in] {
+			if entry.name != call.name {
+				continue
+			}
+			url := moduleUrl(path, defined_in)
+			position := lsp.useInsertPosition(path)
+			actions = append(actions, quickFix(
+				fmt.Sprintf("Add @use \"%s\" as *", url),
+				diagnostic,
+				path,
+				[]protocol.TextEdit{{
+					Range:   protocol.Range{Start: position, End: position},
+					NewText: fmt.Sprintf("@use \"%s\" as *;\n", url),
+				}},
+			))
+			break
+		}
+	}
+	return actions
+}
+
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// similarNames returns the names that are close enough to be a typo, closest
+// first
+func similarNames(name string, names []string, limit int) []string {
+	max_distance := max(1, len(name)/3)
+	type candidate struct {
+		name     string
+		distance int
+	}
+	candidates := []candidate{}
+	seen := map[string]bool{}
+	for _, other := range names {
+		if other == name || seen[other] {
+			continue
+		}
+		seen[other] = true
+		distance := levenshtein(strings.ToLower(name), strings.ToLower(other))
+		if distance <= max_distance {
+			candidates = append(candidates, candidate{other, distance})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	similar := []string{}
+	for idx := 0; idx < len(candidates) && idx < limit; idx++ {
+		similar = append(similar, candidates[idx].name)
+	}
+	return similar
+}
+
+func (lsp *Lsp) didYouMeanFixes(path string, call isDefined, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	names := []string{}
+	definitions := lsp.definitionMap(call.item_type)
+	for _, defined_in := range sortedPaths(definitions) {
+		for _, entry := range definitions[defined_in] {
+			names = append(names, entry.name)
+		}
+	}
+	for _, name := range similarNames(call.name, names, 3) {
+		actions = append(actions, quickFix(
+			fmt.Sprintf("Did you mean %s?", name),
+			diagnostic,
+			path,
+			[]protocol.TextEdit{{Range: diagnostic.Range, NewText: name}},
+		))
+	}
+	return actions
+}
+
+// topLevelStatement is the child of the stylesheet that has the node in it
+func topLevelStatement(node *sitter.Node) *sitter.Node {
+	for node.Parent() != nil && node.Parent().Type() != "stylesheet" {
+		node = node.Parent()
+	}
+	return node
+}
+
+// stubParameters makes up a parameter for every argument of the call
+func stubParameters(call_node *sitter.Node) string {
+	parameters := []string{}
+	for idx := 0; idx < int(call_node.NamedChildCount()); idx++ {
+		arguments := call_node.NamedChild(idx)
+		if arguments.Type() != "arguments" {
+			continue
+		}
+		for arg := 0; arg < int(arguments.NamedChildCount()); arg++ {
+			parameters = append(parameters, fmt.Sprintf("$arg%d", arg+1))
+		}
+	}
+	return "(" + strings.Join(parameters, ", ") + ")"
+}
+
+// stubFix creates the missing variable, mixin or function right before the
+// statement that uses it
+func (lsp *Lsp) stubFix(path string, call isDefined, diagnostic protocol.Diagnostic) (protocol.CodeAction, bool) {
+	node := lsp.Trees[path].RootNode().NamedDescendantForPointRange(call.start_position, call.end_position)
+	if node == nil || node.Type() == "stylesheet" {
+		return protocol.CodeAction{}, false
+	}
+	indent := newFormatter(nil, lsp.formatConfig(), protocol.FormattingOptions{TabSize: 2, InsertSpaces: true}).indent
+
+	var title, stub string
+	switch call.item_type {
+	case itemTypeVariable:
+		title = fmt.Sprintf("Create variable %s", call.name)
+		stub = call.name + ": null;\n\n"
+	case itemTypeMixin:
+		title = fmt.Sprintf("Create mixin %s", call.name)
+		stub = "@mixin " + call.name + stubParameters(node.Parent()) + " {\n" + indent + "// TODO\n}\n\n"
+	case itemTypeFunction:
+		title = fmt.Sprintf("Create function %s", call.name)
+		stub = "@function " + call.name + stubParameters(node.Parent()) + " {\n" + indent + "@return null;\n}\n\n"
+	default:
+		return protocol.CodeAction{}, false
+	}
+
+	statement_start := topLevelStatement(node).StartPoint()
+	position := protocol.Position{Line: statement_start.Row}
+	return quickFix(title, diagnostic, path, []protocol.TextEdit{{
+		Range:   protocol.Range{Start: position, End: position},
+		NewText: stub,
+	}}), true
+}
+
+// AllowFunction adds the function to the allowed functions and saves it in the
+// config file so it sticks around
+func (lsp *Lsp) AllowFunction(name string) error {
+	if lsp.isCallAllowed(name) {
+		return nil
+	}
+	lsp.Config.AllowedFunctions = append(lsp.Config.AllowedFunctions, name)
+
+	// go through a map so the rest of the file is left alone
+	config_path := filepath.Join(lsp.RootPath, configFileName)
+	file_config := map[string]interface{}{}
+	if data, err := os.ReadFile(config_path); err == nil {
+		if err := json.Unmarshal(data, &file_config); err != nil {
+			return err
+		}
+	}
+	allowed, _ := file_config["allowedFunctions"].([]interface{})
+	file_config["allowedFunctions"] = append(allowed, name)
+	data, err := json.MarshalIndent(file_config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(config_path, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	for _, path := range sortedPaths(lsp.Calls) {
+		for _, entry := range lsp.Calls[path] {
+			if entry.name == name {
+				lsp.reportDiagnostics(path)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// ExecuteCommand runs a command of workspace/executeCommand, the result goes
+// back to the client
+func (lsp *Lsp) ExecuteCommand(command string, arguments []interface{}) (interface{}, error) {
+	switch command {
+	case commandAllowFunction:
+		if len(arguments) != 1 {
+			return nil, fmt.Errorf("%s expects the name of the function", command)
+		}
+		name, ok := arguments[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s expects the name of the function", command)
+		}
+		return nil, lsp.AllowFunction(name)
+	case commandMigrateImports:
+		// the entry points, as uris or paths relative to the root
+		entries := []string{}
+		for _, argument := range arguments {
+			entry, ok := argument.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s expects the entry points", command)
+			}
+			if strings.HasPrefix(entry, "file://") {
+				entry = uri.URI(entry).Filename()
+			} else if !filepath.IsAbs(entry) {
+				entry = filepath.Join(lsp.RootPath, entry)
+			}
+			entries = append(entries, entry)
+		}
+		edit, warnings := lsp.MigrateImports(entries)
+		for _, warning := range warnings {
+			lsp.Log(warning, protocol.MessageTypeWarning)
+		}
+		return edit, nil
+	case commandRewrite:
+		query, replacement, paths, err := lsp.rewriteArguments(arguments)
+		if err != nil {
+			return nil, err
+		}
+		return lsp.Rewrite(query, replacement, paths)
+	}
+	return nil, fmt.Errorf("unknown command %s", command)
+}
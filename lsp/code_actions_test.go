@@ -0,0 +1,118 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func undefinedDiagnostic(start sitter.Point, end sitter.Point) protocol.Diagnostic {
+	return protocol.Diagnostic{
+		Range:   rangeFromPoints(start, end),
+		Code:    diagnosticUndefined,
+		Message: "undefined",
+	}
+}
+
+func actionTitles(actions []protocol.CodeAction) []string {
+	titles := []string{}
+	for _, action := range actions {
+		titles = append(titles, action.Title)
+	}
+	return titles
+}
+
+func TestUndefinedQuickFixes(t *testing.T) {
+	path := "/virtual/styles/main.scss"
+	colors := "/virtual/styles/abstracts/_colors.scss"
+	lsp := lspWithSources(t, map[string]string{
+		colors: "$primary: red;\n$secondary: blue;\n",
+		path:   "@use \"sass:math\";\n.a {\n  color: $primray;\n  @include shadow(1px, $primary);\n}\n",
+	})
+
+	diagnostic := undefinedDiagnostic(sitter.Point{Row: 2, Column: 9}, sitter.Point{Row: 2, Column: 17})
+	actions := lsp.GetCodeActions(path, protocol.CodeActionContext{Diagnostics: []protocol.Diagnostic{diagnostic}})
+	titles := strings.Join(actionTitles(actions), "|")
+	if titles != "Did you mean $primary?|Create variable $primray" {
+		t.Fatalf("unexpected actions %s", titles)
+	}
+	edit := actions[0].Edit.Changes[uri.File(path)][0]
+	if edit.NewText != "$primary" || edit.Range != diagnostic.Range {
+		t.Fatalf("unexpected edit %+v", edit)
+	}
+	stub := actions[1].Edit.Changes[uri.File(path)][0]
+	if stub.NewText != "$primray: null;\n\n" || stub.Range.Start != (protocol.Position{Line: 1}) {
+		t.Fatalf("unexpected stub %+v", stub)
+	}
+
+	// the mixin is only defined in a file that is not loaded
+	lsp.Cache[colors] = []byte("$primary: red;\n@mixin shadow($a, $b) {}\n")
+	input := lsp.Cache[colors]
+	lsp.UpdateTreeBytes(colors, &input)
+	diagnostic = undefinedDiagnostic(sitter.Point{Row: 3, Column: 11}, sitter.Point{Row: 3, Column: 17})
+	actions = lsp.GetCodeActions(path, protocol.CodeActionContext{Diagnostics: []protocol.Diagnostic{diagnostic}})
+	titles = strings.Join(actionTitles(actions), "|")
+	if titles != "Add @use \"abstracts/colors\" as *|Create mixin shadow" {
+		t.Fatalf("unexpected actions %s", titles)
+	}
+	use := actions[0].Edit.Changes[uri.File(path)][0]
+	if use.NewText != "@use \"abstracts/colors\" as *;\n" || use.Range.Start != (protocol.Position{Line: 1}) {
+		t.Fatalf("unexpected edit %+v", use)
+	}
+	stub = actions[1].Edit.Changes[uri.File(path)][0]
+	if stub.NewText != "@mixin shadow($arg1, $arg2) {\n  // TODO\n}\n\n" {
+		t.Fatalf("unexpected stub %q", stub.NewText)
+	}
+
+	// only asking for refactors gives no quick fixes
+	actions = lsp.GetCodeActions(path, protocol.CodeActionContext{
+		Diagnostics: []protocol.Diagnostic{diagnostic},
+		Only:        []protocol.CodeActionKind{protocol.Refactor},
+	})
+	if len(actions) != 0 {
+		t.Fatalf("expected no actions, got %+v", actions)
+	}
+}
+
+func TestAllowFunction(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "main.scss")
+	lsp := lspWithSources(t, map[string]string{path: ".a {\n  width: clamp(1px, 2px, 3px);\n}\n"})
+	lsp.RootPath = root
+	writeFiles(t, root, map[string]string{configFileName: `{"loadPaths": ["lib"]}`})
+	lsp.LoadConfig(nil)
+
+	diagnostic := undefinedDiagnostic(sitter.Point{Row: 1, Column: 9}, sitter.Point{Row: 1, Column: 14})
+	actions := lsp.GetCodeActions(path, protocol.CodeActionContext{Diagnostics: []protocol.Diagnostic{diagnostic}})
+	last := actions[len(actions)-1]
+	if last.Command == nil || last.Command.Command != commandAllowFunction {
+		t.Fatalf("expected the allow function action last, got %+v", actionTitles(actions))
+	}
+
+	if _, err := lsp.ExecuteCommand(last.Command.Command, last.Command.Arguments); err != nil {
+		t.Fatal(err)
+	}
+	if !lsp.isCallAllowed("clamp") {
+		t.Fatalf("expected clamp to be allowed")
+	}
+	data, err := os.ReadFile(filepath.Join(root, configFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lsp.LoadConfig(nil)
+	if len(lsp.Config.LoadPaths) != 1 || len(lsp.Config.AllowedFunctions) != 1 {
+		t.Fatalf("unexpected config file %s", data)
+	}
+}
+
+func TestSimilarNames(t *testing.T) {
+	similar := similarNames("$primray", []string{"$primary", "$prime", "$secondary", "$primary"}, 3)
+	if strings.Join(similar, ",") != "$primary" {
+		t.Fatalf("unexpected names %v", similar)
+	}
+}
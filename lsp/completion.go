@@ -0,0 +1,702 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+// a member that can be completed in a file, with the @use it needs if the
+// file doesnt load the module yet
+type memberCompletion struct {
+	entry      isDefined
+	defined_in string
+	// the name as it has to be written in the file, with the namespace
+	name string
+	// the @use to add, nil if the member is already visible
+	import_edit *protocol.TextEdit
+	import_url  string
+}
+
+func isWordChar(char byte) bool {
+	return char == '$' || char == '.' || char == '-' || char == '_' ||
+		(char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')
+}
+
+// typedWord is what is right before the cursor, it is replaced by the
+// completion
+func typedWord(input []byte, position protocol.Position) (string, protocol.Range) {
+	lines := strings.Split(string(input), "\n")
+	word_range := protocol.Range{Start: position, End: position}
+	if int(position.Line) >= len(lines) {
+		return "", word_range
+	}
+	line := lines[position.Line]
+	end := min(int(position.Character), len(line))
+	start := end
+	for start > 0 && isWordChar(line[start-1]) {
+		start--
+	}
+	word_range.Start.Character = uint32(start)
+	return line[start:end], word_range
+}
+
+// memberCompletions lists the mixins, functions or variables the file can
+// use, members of modules that are not loaded come with a @use to add
+func (lsp *Lsp) memberCompletions(path string, item_type string) []memberCompletion {
+	completions := []memberCompletion{}
+	definitions := lsp.definitionMap(item_type)
+	visible_paths := map[string]bool{}
+	seen := map[string]bool{}
+
+	for _, module := range lsp.VisibleModules(path) {
+		visible_paths[module.path] = true
+		for _, entry := range definitions[module.path] {
+			// local variables of the file come from localVariables, they
+			// depend on where the cursor is
+			if item_type == itemTypeVariable && !lsp.isTopLevelVariable(module.path, entry) {
+				continue
+			}
+			name, ok := module.memberName(entry.name)
+			if !ok {
+				continue
+			}
+			name = namespacedName(module.namespace, name)
+			if seen[module.path+"\x00"+name] {
+				continue
+			}
+			seen[module.path+"\x00"+name] = true
+			completions = append(completions, memberCompletion{entry: entry, defined_in: module.path, name: name})
+		}
+	}
+
+	insert_position := lsp.useInsertPosition(path)
+	for _, defined_in := range sortedPaths(definitions) {
+		if visible_paths[defined_in] {
+			continue
+		}
+		url := moduleUrl(path, defined_in)
+		namespace := lsp.freeNamespace(path, defaultNamespace(url))
+		statement := fmt.Sprintf("@use \"%s\";\n", url)
+		if namespace != defaultNamespace(url) {
+			statement = fmt.Sprintf("@use \"%s\" as %s;\n", url, namespace)
+		}
+		for _, entry := range definitions[defined_in] {
+			// @access private in the SassDoc is private by convention
+			if isPrivateMember(entry.name) || entry.doc.isPrivate() {
+				continue
+			}
+			if item_type == itemTypeVariable && !lsp.isTopLevelVariable(defined_in, entry) {
+				continue
+			}
+			completions = append(completions, memberCompletion{
+				entry:      entry,
+				defined_in: defined_in,
+				name:       namespacedName(namespace, entry.name),
+				import_edit: &protocol.TextEdit{
+					Range:   protocol.Range{Start: insert_position, End: insert_position},
+					NewText: statement,
+				},
+				import_url: url,
+			})
+		}
+	}
+	return completions
+}
+
+func (completion memberCompletion) item(kind protocol.CompletionItemKind, insert_text string) protocol.CompletionItem {
+	item := protocol.CompletionItem{
+		Label:         completion.name,
+		Kind:          kind,
+		Documentation: completion.entry.body + "\n\n" + completion.defined_in,
+		InsertText:    insert_text,
+	}
+	if completion.entry.doc != nil {
+		parameters := []string{}
+		if kind != protocol.CompletionItemKindVariable {
+			parameters = definitionParameters(completion.entry)
+		}
+		item.Documentation = markdown("```scss\n" + completion.entry.body + "\n```\n\n" + definitionDocumentation(completion.entry, parameters) + "\n\n" + completion.defined_in)
+	}
+	if completion.entry.doc.isDeprecated() {
+		item.Tags = []protocol.CompletionItemTag{protocol.CompletionItemTagDeprecated}
+	}
+	if completion.import_edit != nil {
+		item.Detail = fmt.Sprintf("auto import from \"%s\"", completion.import_url)
+		item.AdditionalTextEdits = []protocol.TextEdit{*completion.import_edit}
+	}
+	return item
+}
+
+// where the cursor is decides what is completed
+const (
+	completionAtRule    = "at-rule"
+	completionInclude   = "include"
+	completionExtend    = "extend"
+	completionNamespace = "namespace"
+	completionVariable  = "variable"
+	completionValue     = "value"
+	completionProperty  = "property"
+	completionSelector  = "selector"
+)
+
+type completionContext struct {
+	kind       string
+	word       string
+	word_range protocol.Range
+	// the namespace before the dot for completionNamespace and @include ns.
+	namespace string
+	// the property of the declaration for completionValue
+	property string
+	// the deepest node at the cursor, for the variables in scope
+	node *sitter.Node
+}
+
+var (
+	includeRegex   = regexp.MustCompile(`@include\s+(?:([\w-]+)\.)?([\w-]*)$`)
+	extendRegex    = regexp.MustCompile(`@extend\s+%?[\w-]*$`)
+	atRuleRegex    = regexp.MustCompile(`(?:^|[^\w-])@[\w-]*$`)
+	namespaceRegex = regexp.MustCompile(`(?:^|[^\w-])([\w-]+)\.(\$?[\w-]*)$`)
+	variableRegex  = regexp.MustCompile(`\$[\w-]*$`)
+	classRegex     = regexp.MustCompile(`\.(-?[_a-zA-Z][\w-]*)`)
+	valueLineRegex = regexp.MustCompile(`^\s*([\w-]+)\s*:[^{};]*$`)
+)
+
+var atRuleKeywords = []string{
+	"use", "forward", "import", "mixin", "include", "content", "function",
+	"return", "extend", "if", "else", "each", "for", "while", "at-root",
+	"debug", "warn", "error", "media", "supports", "keyframes", "font-face",
+	"charset", "page", "layer", "container",
+}
+
+// values every property accepts
+var cssWideKeywords = []string{"inherit", "initial", "unset", "revert", "revert-layer"}
+
+// blockDepth counts the braces that are open at the offset, for when the tree
+// is too broken to tell if the cursor is in a block
+func blockDepth(input []byte, offset int) int {
+	depth := 0
+	var quote byte
+	for idx := 0; idx < offset && idx < len(input); idx++ {
+		char := input[idx]
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '/' && idx+1 < len(input) && input[idx+1] == '/':
+			for idx < offset && idx < len(input) && input[idx] != '\n' {
+				idx++
+			}
+		case char == '{':
+			depth++
+		case char == '}' && depth > 0:
+			depth--
+		}
+	}
+	return depth
+}
+
+func offsetOfPosition(input []byte, position protocol.Position) int {
+	line := uint32(0)
+	for idx, char := range input {
+		if line == position.Line {
+			return min(idx+int(position.Character), len(input))
+		}
+		if char == '\n' {
+			line++
+		}
+	}
+	return len(input)
+}
+
+// getCompletionContext looks at the text before the cursor first, things
+// like `@include ` are usually in ERROR nodes, then at the syntax node
+func (lsp *Lsp) getCompletionContext(path string, input []byte, position protocol.Position) completionContext {
+	context := completionContext{}
+	context.word, context.word_range = typedWord(input, position)
+	line_start := offsetOfPosition(input, protocol.Position{Line: position.Line})
+	offset := offsetOfPosition(input, position)
+	line_prefix := string(input[line_start:offset])
+
+	point := sitter.Point{Row: position.Line, Column: position.Character}
+	if point.Column > 0 {
+		point.Column--
+	}
+	context.node = lsp.Trees[path].RootNode().NamedDescendantForPointRange(point, point)
+
+	switch {
+	case includeRegex.MatchString(line_prefix):
+		match := includeRegex.FindStringSubmatch(line_prefix)
+		context.kind = completionInclude
+		context.namespace = match[1]
+		// only the name after the namespace is replaced
+		context.word = match[2]
+		context.word_range.Start.Character = context.word_range.End.Character - uint32(len(match[2]))
+		return context
+	case extendRegex.MatchString(line_prefix):
+		context.kind = completionExtend
+		// % is not a word character, it is replaced too
+		if strings.HasSuffix(line_prefix, "%"+context.word) {
+			context.word_range.Start.Character--
+		}
+		return context
+	case atRuleRegex.MatchString(line_prefix):
+		context.kind = completionAtRule
+		// the @ is replaced too
+		if context.word_range.Start.Character > 0 {
+			context.word_range.Start.Character--
+		}
+		return context
+	case variableRegex.MatchString(line_prefix) && !strings.Contains(context.word, "."):
+		context.kind = completionVariable
+		return context
+	case namespaceRegex.MatchString(line_prefix):
+		match := namespaceRegex.FindStringSubmatch(line_prefix)
+		if _, ok := lsp.namespaceModule(path, match[1]); ok {
+			context.kind = completionNamespace
+			context.namespace = match[1]
+			return context
+		}
+	}
+
+	// the tree knows declarations and selectors, unless they are broken
+	in_block := false
+	for ancestor := context.node; ancestor != nil; ancestor = ancestor.Parent() {
+		switch ancestor.Type() {
+		case "declaration":
+			property := ancestor.NamedChild(0)
+			for idx := 0; idx < int(ancestor.ChildCount()); idx++ {
+				child := ancestor.Child(idx)
+				if child.Type() == ":" && child.EndByte() <= uint32(offset) {
+					if property != nil && property.Type() == "property_name" {
+						context.property = property.Content(input)
+					}
+					context.kind = completionValue
+					return context
+				}
+			}
+			context.kind = completionProperty
+			return context
+		case "selectors":
+			context.kind = completionSelector
+			return context
+		case "block":
+			in_block = true
+		case "ERROR":
+			in_block = blockDepth(input, offset) > 0
+		}
+		if in_block {
+			break
+		}
+	}
+	if !in_block && context.node != nil && context.node.Type() == "stylesheet" {
+		in_block = blockDepth(input, offset) > 0
+	}
+
+	if match := valueLineRegex.FindStringSubmatch(line_prefix); in_block && match != nil {
+		context.kind = completionValue
+		context.property = match[1]
+		return context
+	}
+	if strings.HasPrefix(context.word, ".") || strings.HasPrefix(context.word, "&") {
+		context.kind = completionSelector
+		return context
+	}
+	if in_block {
+		context.kind = completionProperty
+	}
+	return context
+}
+
+// namespaceModule is the module behind a namespace of a @use in the file
+func (lsp *Lsp) namespaceModule(path string, namespace string) (moduleStatement, bool) {
+	for _, statement := range lsp.Modules[path] {
+		if statement.kind == "@use" && statement.namespace == namespace {
+			return statement, true
+		}
+	}
+	return moduleStatement{}, false
+}
+
+// splitParameters splits the parameters of a mixin or function body like
+// `name($a, $b: 2px)`, commas inside parens dont count
+func splitParameters(body string) []string {
+	start := strings.Index(body, "(")
+	end := strings.LastIndex(body, ")")
+	if start == -1 || end <= start {
+		return []string{}
+	}
+	parameters := []string{}
+	depth := 0
+	current := strings.Builder{}
+	for _, char := range body[start+1 : end] {
+		switch {
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+		case char == ',' && depth == 0:
+			parameters = append(parameters, strings.TrimSpace(current.String()))
+			current.Reset()
+			continue
+		}
+		current.WriteRune(char)
+	}
+	if parameter := strings.TrimSpace(current.String()); parameter != "" {
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
+
+// callSnippet makes a snippet with a placeholder for every parameter without
+// a default value
+func callSnippet(name string, body string, always_parens bool) string {
+	placeholders := []string{}
+	for _, parameter := range splitParameters(body) {
+		if strings.Contains(parameter, ":") || strings.HasSuffix(parameter, "...") {
+			continue
+		}
+		placeholders = append(placeholders, fmt.Sprintf("${%d:%s}", len(placeholders)+1, strings.ReplaceAll(parameter, "$", `\$`)))
+	}
+	if len(placeholders) == 0 && !always_parens {
+		return name
+	}
+	if len(placeholders) == 0 {
+		return name + "($1)"
+	}
+	return name + "(" + strings.Join(placeholders, ", ") + ")"
+}
+
+// localVariables are the parameters, loop variables and local declarations
+// the cursor can see
+func localVariables(node *sitter.Node, input []byte, position sitter.Point) []isDefined {
+	variables := []isDefined{}
+	add := func(name_node *sitter.Node, body string) {
+		variables = append(variables, isDefined{name: name_node.Content(input), body: body, start_position: name_node.StartPoint(), end_position: name_node.EndPoint()})
+	}
+	for ancestor := node; ancestor != nil; ancestor = ancestor.Parent() {
+		switch ancestor.Type() {
+		case "mixin_statement", "function_statement":
+			for idx := 0; idx < int(ancestor.NamedChildCount()); idx++ {
+				parameters := ancestor.NamedChild(idx)
+				if parameters.Type() != "parameters" {
+					continue
+				}
+				for p := 0; p < int(parameters.NamedChildCount()); p++ {
+					parameter := parameters.NamedChild(p)
+					if parameter.NamedChildCount() > 0 {
+						add(parameter.NamedChild(0), parameter.Content(input))
+					}
+				}
+			}
+		case "each_statement", "for_statement":
+			for idx := 0; idx < int(ancestor.NamedChildCount()); idx++ {
+				child := ancestor.NamedChild(idx)
+				switch child.Type() {
+				case "key", "value", "variable":
+					add(child, ancestor.Content(input)[:strings.Index(ancestor.Content(input), "{")])
+				}
+			}
+		case "block":
+			// the top of the file is not local, memberCompletions has those
+			if ancestor.Parent() == nil || ancestor.Parent().Type() == "stylesheet" {
+				continue
+			}
+			for idx := 0; idx < int(ancestor.NamedChildCount()); idx++ {
+				declaration := ancestor.NamedChild(idx)
+				if declaration.Type() != "declaration" || declaration.NamedChildCount() == 0 {
+					continue
+				}
+				name := declaration.NamedChild(0)
+				if name.Type() == "variable_name" && comparePoints(name.StartPoint(), position) < 0 {
+					add(name, declaration.Content(input))
+				}
+			}
+		}
+	}
+	return variables
+}
+
+func comparePoints(a sitter.Point, b sitter.Point) int {
+	if a.Row != b.Row {
+		return int(a.Row) - int(b.Row)
+	}
+	return int(a.Column) - int(b.Column)
+}
+
+func (context completionContext) replace(item protocol.CompletionItem, new_text string) protocol.CompletionItem {
+	item.TextEdit = &protocol.TextEdit{Range: context.word_range, NewText: new_text}
+	if item.FilterText == "" {
+		item.FilterText = item.Label
+	}
+	return item
+}
+
+func (lsp *Lsp) variableItems(path string, input []byte, context completionContext, position protocol.Position) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	seen := map[string]bool{}
+	point := sitter.Point{Row: position.Line, Column: position.Character}
+	// the list is incomplete, so it is filtered here and not by the client
+	typed := ""
+	if idx := strings.LastIndex(context.word, "$"); idx != -1 {
+		typed = context.word[idx+1:]
+	}
+	for _, local := range localVariables(context.node, input, point) {
+		if seen[local.name] || !strings.Contains(local.name, typed) {
+			continue
+		}
+		seen[local.name] = true
+		items = append(items, context.replace(protocol.CompletionItem{
+			Label:         local.name,
+			Kind:          protocol.CompletionItemKindVariable,
+			Detail:        "local",
+			Documentation: local.body,
+		}, local.name))
+	}
+	for _, completion := range lsp.memberCompletions(path, itemTypeVariable) {
+		if seen[completion.name] || !strings.Contains(completion.entry.name, typed) {
+			continue
+		}
+		item := completion.item(protocol.CompletionItemKindVariable, "")
+		// typing $pri should find colors.$primary too
+		item.FilterText = completion.entry.name
+		if strings.Contains(context.word, ".") {
+			item.FilterText = completion.name
+		}
+		items = append(items, context.replace(item, completion.name))
+	}
+	return items
+}
+
+func (lsp *Lsp) functionItems(path string, context completionContext) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	for _, completion := range lsp.memberCompletions(path, itemTypeFunction) {
+		item := completion.item(protocol.CompletionItemKindFunction, "")
+		item.InsertTextFormat = protocol.InsertTextFormatSnippet
+		items = append(items, context.replace(item, callSnippet(completion.name, completion.entry.body, true)))
+	}
+	return items
+}
+
+func (lsp *Lsp) mixinItems(path string, context completionContext) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	for _, completion := range lsp.memberCompletions(path, itemTypeMixin) {
+		if context.namespace != "" && !strings.HasPrefix(completion.name, context.namespace+".") {
+			continue
+		}
+		item := completion.item(protocol.CompletionItemKindInterface, "")
+		item.InsertTextFormat = protocol.InsertTextFormatSnippet
+		name := completion.name
+		if context.namespace != "" {
+			// the namespace is not part of the word
+			name = strings.TrimPrefix(name, context.namespace+".")
+			item.FilterText = name
+		}
+		items = append(items, context.replace(item, callSnippet(name, completion.entry.body, false)))
+	}
+	return items
+}
+
+// builtinItems completes the members of a built-in module, the word has the
+// namespace in it
+func (lsp *Lsp) builtinItems(path string, context completionContext, item_type string) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	statement, ok := lsp.namespaceModule(path, context.namespace)
+	if !ok {
+		return items
+	}
+	module, ok := lookupBuiltinModule(statement.url)
+	if !ok {
+		return items
+	}
+	add := func(member builtinMember, kind protocol.CompletionItemKind, text string) {
+		item := protocol.CompletionItem{
+			Label:         context.namespace + "." + member.Name,
+			Kind:          kind,
+			Detail:        member.signature(context.namespace),
+			Documentation: markdown(member.documentation(context.namespace)),
+		}
+		if text != item.Label {
+			item.InsertTextFormat = protocol.InsertTextFormatSnippet
+		}
+		items = append(items, context.replace(item, text))
+	}
+	if item_type == itemTypeMixin {
+		for _, member := range module.Mixins {
+			// after @include the namespace is not part of the word
+			add(member, protocol.CompletionItemKindInterface, callSnippet(member.Name, member.signature(""), false))
+			items[len(items)-1].FilterText = member.Name
+		}
+		return items
+	}
+	for _, member := range module.Variables {
+		add(member, protocol.CompletionItemKindVariable, context.namespace+"."+member.Name)
+	}
+	for _, member := range module.Functions {
+		add(member, protocol.CompletionItemKindFunction, callSnippet(context.namespace+"."+member.Name, member.signature(""), true))
+	}
+	return items
+}
+
+func (lsp *Lsp) placeholderItems(context completionContext) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	seen := map[string]bool{}
+	for _, path := range sortedPaths(lsp.Placeholders) {
+		for _, entry := range lsp.Placeholders[path] {
+			if seen[entry.name] {
+				continue
+			}
+			seen[entry.name] = true
+			items = append(items, context.replace(protocol.CompletionItem{
+				Label:         entry.name,
+				Kind:          protocol.CompletionItemKindClass,
+				Documentation: path,
+			}, entry.name))
+		}
+	}
+	return items
+}
+
+func (lsp *Lsp) classItems(context completionContext) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	seen := map[string]bool{}
+	// only the class after the last dot is replaced, .a.b completes b
+	word_range := context.word_range
+	if idx := strings.LastIndex(context.word, "."); idx != -1 {
+		word_range.Start.Character += uint32(idx)
+	}
+	for _, path := range sortedPaths(lsp.SelectorEntries) {
+		for _, entry := range lsp.SelectorEntries[path] {
+			for _, match := range classRegex.FindAllStringSubmatch(entry.name, -1) {
+				if seen[match[1]] {
+					continue
+				}
+				seen[match[1]] = true
+				items = append(items, protocol.CompletionItem{
+					Label:      "." + match[1],
+					Kind:       protocol.CompletionItemKindClass,
+					FilterText: "." + match[1],
+					TextEdit:   &protocol.TextEdit{Range: word_range, NewText: "." + match[1]},
+				})
+			}
+		}
+	}
+	return items
+}
+
+func markdown(value string) protocol.MarkupContent {
+	return protocol.MarkupContent{Kind: protocol.Markdown, Value: value}
+}
+
+func (lsp *Lsp) propertyItems(context completionContext) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	seen := map[string]bool{}
+	for _, property := range getCssData().Properties {
+		seen[property.Name] = true
+		items = append(items, context.replace(protocol.CompletionItem{
+			Label:         property.Name,
+			Kind:          protocol.CompletionItemKindProperty,
+			Documentation: markdown(property.documentation()),
+		}, property.Name+": "))
+	}
+	// properties that are not in the data, vendor prefixed ones and such
+	for _, path := range sortedPaths(lsp.Properties) {
+		for _, entry := range lsp.Properties[path] {
+			// custom properties and interpolation are not worth suggesting
+			if seen[entry.name] || strings.HasPrefix(entry.name, "--") || strings.Contains(entry.name, "#{") {
+				continue
+			}
+			seen[entry.name] = true
+			items = append(items, context.replace(protocol.CompletionItem{
+				Label: entry.name,
+				Kind:  protocol.CompletionItemKindProperty,
+			}, entry.name+": "))
+		}
+	}
+	return items
+}
+
+var numberRegex = regexp.MustCompile(`^-?\d*\.?\d+$`)
+
+func (lsp *Lsp) valueItems(path string, input []byte, context completionContext, position protocol.Position) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	// a number is followed by a unit, nothing else makes sense there
+	if numberRegex.MatchString(context.word) {
+		for _, unit := range getCssData().Units {
+			items = append(items, context.replace(protocol.CompletionItem{
+				Label:         context.word + unit.Name,
+				Kind:          protocol.CompletionItemKindUnit,
+				Documentation: unit.Description,
+			}, context.word+unit.Name))
+		}
+		return items
+	}
+
+	// the keywords of the property come first
+	keywords := cssWideKeywords
+	if property, ok := lookupCssProperty(context.property); ok {
+		keywords = append(property.Values[:len(property.Values):len(property.Values)], cssWideKeywords...)
+	}
+	for idx, keyword := range keywords {
+		items = append(items, context.replace(protocol.CompletionItem{
+			Label:    keyword,
+			Kind:     protocol.CompletionItemKindValue,
+			SortText: fmt.Sprintf("0%03d", idx),
+		}, keyword))
+	}
+	items = append(items, lsp.variableItems(path, input, context, position)...)
+	items = append(items, lsp.functionItems(path, context)...)
+	return items
+}
+
+func (lsp *Lsp) GetCompletions(path string, position protocol.Position) protocol.CompletionList {
+	list := protocol.CompletionList{Items: []protocol.CompletionItem{}}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil || lsp.Trees[path] == nil {
+		return list
+	}
+	context := lsp.getCompletionContext(path, *input, position)
+
+	switch context.kind {
+	case completionAtRule:
+		for _, keyword := range atRuleKeywords {
+			list.Items = append(list.Items, context.replace(protocol.CompletionItem{
+				Label: "@" + keyword,
+				Kind:  protocol.CompletionItemKindKeyword,
+			}, "@"+keyword))
+		}
+	case completionInclude:
+		list.Items = lsp.mixinItems(path, context)
+		if context.namespace != "" {
+			list.Items = append(list.Items, lsp.builtinItems(path, context, itemTypeMixin)...)
+		}
+	case completionExtend:
+		list.Items = lsp.placeholderItems(context)
+	case completionNamespace:
+		prefix := context.namespace + "."
+		items := append(lsp.variableItems(path, *input, context, position), lsp.functionItems(path, context)...)
+		for _, item := range items {
+			if strings.HasPrefix(item.Label, prefix) {
+				list.Items = append(list.Items, item)
+			}
+		}
+		list.Items = append(list.Items, lsp.builtinItems(path, context, itemTypeFunction)...)
+	case completionVariable:
+		// the list changes with every letter, so the client has to ask again
+		list.IsIncomplete = true
+		list.Items = lsp.variableItems(path, *input, context, position)
+	case completionValue:
+		list.Items = lsp.valueItems(path, *input, context, position)
+	case completionProperty:
+		list.Items = lsp.propertyItems(context)
+	case completionSelector:
+		list.Items = lsp.classItems(context)
+	}
+	return list
+}
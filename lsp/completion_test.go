@@ -0,0 +1,163 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func completionLabels(list protocol.CompletionList) map[string]protocol.CompletionItem {
+	labels := map[string]protocol.CompletionItem{}
+	for _, item := range list.Items {
+		labels[item.Label] = item
+	}
+	return labels
+}
+
+func TestVariableCompletionAutoImport(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/lib/_index.scss":   "@forward \"colors\" as c-*;\n$-private: 1;\n$lib: 1;\n",
+		"/virtual/lib/_colors.scss":  "$primary: red;\n",
+		"/virtual/_legacy.scss":      "$old: 1;\n",
+		"/virtual/other/_sizes.scss": "$gap: 1px;\n@mixin pad { $local: 1; }\n",
+		path:                         "@use \"lib\" as l;\n@import \"legacy\";\n.a { color: $ }\n",
+	})
+
+	list := lsp.GetCompletions(path, protocol.Position{Line: 2, Character: 13})
+	labels := completionLabels(list)
+	for _, expected := range []string{"l.$lib", "l.$c-primary", "$old", "sizes.$gap"} {
+		if _, ok := labels[expected]; !ok {
+			t.Fatalf("expected %s in %v", expected, list.Items)
+		}
+	}
+	for _, unexpected := range []string{"l.$-private", "$-private", "$local", "sizes.$local"} {
+		if _, ok := labels[unexpected]; ok {
+			t.Fatalf("did not expect %s", unexpected)
+		}
+	}
+
+	if len(labels["l.$lib"].AdditionalTextEdits) != 0 {
+		t.Fatalf("expected no @use for a loaded module")
+	}
+	gap := labels["sizes.$gap"]
+	if len(gap.AdditionalTextEdits) != 1 || gap.AdditionalTextEdits[0].NewText != "@use \"other/sizes\";\n" {
+		t.Fatalf("unexpected edits %+v", gap.AdditionalTextEdits)
+	}
+	if gap.AdditionalTextEdits[0].Range.Start.Line != 1 {
+		t.Fatalf("expected the @use after the other @use, got %+v", gap.AdditionalTextEdits[0].Range)
+	}
+	if gap.TextEdit.NewText != "sizes.$gap" || gap.TextEdit.Range.Start.Character != 12 {
+		t.Fatalf("unexpected text edit %+v", gap.TextEdit)
+	}
+}
+
+func TestAutoImportNamespaceClash(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/a/_colors.scss": "$red: red;\n",
+		"/virtual/b/_colors.scss": "$blue: blue;\n",
+		path:                      "@use \"a/colors\";\n.a { color: $bl }\n",
+	})
+
+	labels := completionLabels(lsp.GetCompletions(path, protocol.Position{Line: 1, Character: 15}))
+	blue, ok := labels["colors2.$blue"]
+	if !ok {
+		t.Fatalf("expected colors2.$blue, got %v", labels)
+	}
+	if blue.AdditionalTextEdits[0].NewText != "@use \"b/colors\" as colors2;\n" {
+		t.Fatalf("unexpected edit %q", blue.AdditionalTextEdits[0].NewText)
+	}
+	if _, ok := labels["colors.$red"]; ok {
+		t.Fatalf("did not expect $red when typing $bl")
+	}
+}
+
+func TestMixinCompletionAutoImport(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/_mixins.scss": "@mixin pad($a, $b: 2px) {}\n@mixin clear {}\n",
+		path:                    ".a {\n  @include \n}\n",
+	})
+
+	labels := completionLabels(lsp.GetCompletions(path, protocol.Position{Line: 1, Character: 11}))
+	pad := labels["mixins.pad"]
+	if pad.TextEdit == nil || pad.TextEdit.NewText != `mixins.pad(${1:\$a})` || !strings.HasPrefix(pad.Detail, "auto import") {
+		t.Fatalf("unexpected item %+v", pad)
+	}
+	if pad.InsertTextFormat != protocol.InsertTextFormatSnippet {
+		t.Fatalf("expected a snippet")
+	}
+	if clear := labels["mixins.clear"]; clear.TextEdit == nil || clear.TextEdit.NewText != "mixins.clear" {
+		t.Fatalf("unexpected item %+v", clear)
+	}
+}
+
+const contextSource = `@use "lib" as l;
+%base { top: 0; }
+.card { &__title { top: 0; } }
+@mixin m($size) {
+  $inner: 1;
+  width: $
+}
+.a {
+  color: re;
+  @extend %;
+  marg
+}
+.c
+@inc
+.d { top: l. }
+`
+
+func TestCompletionContexts(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/_lib.scss":  "$gap: 1px;\n@function double($n) { @return $n * 2; }\n",
+		"/virtual/other.scss": ".x { margin: 0; }\n",
+		path:                  contextSource,
+	})
+
+	tests := []struct {
+		position protocol.Position
+		kind     string
+		expected []string
+		missing  []string
+	}{
+		{protocol.Position{Line: 5, Character: 10}, completionVariable, []string{"$size", "$inner", "l.$gap"}, []string{"double"}},
+		{protocol.Position{Line: 8, Character: 11}, completionValue, []string{"inherit", "l.$gap", "l.double"}, []string{"margin"}},
+		{protocol.Position{Line: 9, Character: 11}, completionExtend, []string{"%base"}, nil},
+		{protocol.Position{Line: 10, Character: 6}, completionProperty, []string{"margin", "top"}, []string{"inherit"}},
+		{protocol.Position{Line: 12, Character: 2}, completionSelector, []string{".card", ".card__title", ".x"}, nil},
+		{protocol.Position{Line: 13, Character: 4}, completionAtRule, []string{"@include", "@use"}, nil},
+		{protocol.Position{Line: 14, Character: 12}, completionNamespace, []string{"l.$gap", "l.double"}, []string{"$size"}},
+	}
+	input := lsp.Cache[path]
+	for _, test := range tests {
+		context := lsp.getCompletionContext(path, input, test.position)
+		if context.kind != test.kind {
+			t.Fatalf("expected %s at %+v, got %s", test.kind, test.position, context.kind)
+		}
+		labels := completionLabels(lsp.GetCompletions(path, test.position))
+		for _, expected := range test.expected {
+			if _, ok := labels[expected]; !ok {
+				t.Fatalf("expected %s at %+v, got %v", expected, test.position, labels)
+			}
+		}
+		for _, missing := range test.missing {
+			if _, ok := labels[missing]; ok {
+				t.Fatalf("did not expect %s at %+v", missing, test.position)
+			}
+		}
+	}
+
+	labels := completionLabels(lsp.GetCompletions(path, protocol.Position{Line: 14, Character: 12}))
+	if labels["l.double"].TextEdit.NewText != `l.double(${1:\$n})` || labels["l.double"].TextEdit.Range.Start.Character != 10 {
+		t.Fatalf("unexpected edit %+v", labels["l.double"].TextEdit)
+	}
+	labels = completionLabels(lsp.GetCompletions(path, protocol.Position{Line: 9, Character: 11}))
+	if labels["%base"].TextEdit.Range.Start.Character != 10 {
+		t.Fatalf("expected the %% to be replaced, got %+v", labels["%base"].TextEdit)
+	}
+}
@@ -0,0 +1,108 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"go.lsp.dev/protocol"
+)
+
+// the config file is looked up in the root of the workspace
+const configFileName = ".scss-lsp.json"
+
+type Config struct {
+	// extra directories to look in when resolving @use, @forward and @import,
+	// relative paths are relative to the root
+	LoadPaths []string `json:"loadPaths"`
+	// directories to look in when resolving url(...), for urls like
+	// "/images/foo.png" that are relative to the site and not the file
+	AssetRoots []string `json:"assetRoots"`
+	// functions that are not reported as undefined, on top of the ones the
+	// server knows about, like functions of plain css
+	AllowedFunctions []string `json:"allowedFunctions"`
+	// options of the formatter, see FormatConfig
+	Format FormatConfig `json:"format"`
+	// the file with the known problems that are not reported, relative to
+	// the root, .scss-lsp-baseline.json when empty
+	Baseline string `json:"baseline"`
+	// the severity and options of rules by their id, "off" turns one off
+	Rules map[string]RuleConfig `json:"rules"`
+	// rules for the files in some directories, later ones win
+	Overrides []RuleOverride `json:"overrides"`
+	// where the docs of the rules are, diagnostics link to it with the id
+	// of their rule as the fragment
+	RuleDocsUrl string `json:"ruleDocsUrl"`
+	// rules written as tree-sitter queries, see CustomRuleConfig
+	CustomRules []CustomRuleConfig `json:"customRules"`
+	// the files, or directories, whose members other projects use, what they
+	// forward is never reported as unused, relative to the root
+	PublicEntryPoints []string `json:"publicEntryPoints"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		LoadPaths:         []string{},
+		AssetRoots:        []string{},
+		AllowedFunctions:  []string{},
+		Format:            DefaultFormatConfig(),
+		Rules:             map[string]RuleConfig{},
+		Overrides:         []RuleOverride{},
+		CustomRules:       []CustomRuleConfig{},
+		PublicEntryPoints: []string{},
+	}
+}
+
+// LoadConfig reads the config file from the root, then applies the
+// initializationOptions of the client on top of it, and loads the baseline
+// and the custom rules
+func (lsp *Lsp) LoadConfig(initialization_options interface{}) {
+	lsp.ConfigErrors = []string{}
+	config := DefaultConfig()
+	config_path := filepath.Join(lsp.RootPath, configFileName)
+	if data, err := os.ReadFile(config_path); err == nil {
+		if err := json.Unmarshal(data, config); err != nil {
+			lsp.configError(config_path + ": " + err.Error())
+		}
+	}
+	if initialization_options != nil {
+		// it comes in as whatever encoding/json decided, easiest to go around
+		data, err := json.Marshal(initialization_options)
+		if err == nil {
+			err = json.Unmarshal(data, config)
+		}
+		if err != nil {
+			lsp.configError("initializationOptions: " + err.Error())
+		}
+	}
+	lsp.Config = config
+	lsp.forgetWorkspace()
+	if err := lsp.LoadBaseline(); err != nil {
+		lsp.configError(lsp.baselinePath() + ": " + err.Error())
+	}
+	rules, errors := compileCustomRules(config.CustomRules)
+	lsp.customRules = rules
+	for _, message := range errors {
+		lsp.configError(message)
+	}
+	for _, message := range lsp.ruleConfigErrors() {
+		lsp.configError(message)
+	}
+}
+
+func (lsp *Lsp) configError(message string) {
+	lsp.ConfigErrors = append(lsp.ConfigErrors, message)
+	lsp.Log(message, protocol.MessageTypeError)
+}
+
+// rootRelative makes config paths absolute
+func (lsp *Lsp) rootRelative(paths []string) []string {
+	absolute := []string{}
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(lsp.RootPath, path)
+		}
+		absolute = append(absolute, path)
+	}
+	return absolute
+}
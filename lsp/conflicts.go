@@ -0,0 +1,348 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// the codes of the diagnostics for definitions that get in each others way
+const (
+	diagnosticDuplicateDefinition = "duplicate-definition"
+	diagnosticShadowedVariable    = "shadowed-variable"
+	diagnosticIneffectiveDefault  = "ineffective-default"
+)
+
+// the statements whose blocks dont get their own variables at the top of a
+// file, an assignment in them changes the global variable
+var flowControlTypes = map[string]bool{
+	"block":           true,
+	"if_statement":    true,
+	"if_clause":       true,
+	"else_if_clause":  true,
+	"else_clause":     true,
+	"each_statement":  true,
+	"for_statement":   true,
+	"while_statement": true,
+}
+
+// a definition as one file sees it
+type scopeDefinition struct {
+	path      string
+	entry     isDefined
+	item_type string
+	// loaded with @use or @forward, where two modules with the same member
+	// are an error
+	is_module bool
+}
+
+func (lsp *Lsp) definitionLocation(path string, entry isDefined, item_type string) protocol.Location {
+	start, end := entry.start_position, entry.end_position
+	if tree := lsp.Trees[path]; tree != nil {
+		start, end = definitionNameRange(tree, entry, item_type)
+	}
+	return protocol.Location{URI: uri.File(path), Range: rangeFromPoints(start, end)}
+}
+
+func (lsp *Lsp) definitionPlace(path string, entry isDefined) string {
+	return fmt.Sprintf("%s:%d", lsp.relativePath(path), entry.start_position.Row+1)
+}
+
+// scopeDefinitions are the mixins, functions and global variables the file
+// sees, by the item type and the name they are called with there, the first
+// definition of each file only. Every file a file loads asks for them, so they
+// are kept like the loaders.
+func (lsp *Lsp) scopeDefinitions(path string) map[string][]scopeDefinition {
+	if definitions, ok := lsp.scopes[path]; ok {
+		return definitions
+	}
+	if lsp.scopes == nil {
+		lsp.scopes = map[string]map[string][]scopeDefinition{}
+	}
+	definitions := map[string][]scopeDefinition{}
+	lsp.scopes[path] = definitions
+	seen := map[string]bool{}
+	for _, module := range lsp.VisibleModules(path) {
+		if seen[module.namespace+" "+module.path] {
+			continue
+		}
+		seen[module.namespace+" "+module.path] = true
+		for _, item_type := range []string{itemTypeMixin, itemTypeFunction, itemTypeVariable} {
+			names := map[string]bool{}
+			for _, entry := range lsp.definitionMap(item_type)[module.path] {
+				if item_type == itemTypeVariable && (!lsp.isTopLevelVariable(module.path, entry) || strings.Contains(entry.body, "!default")) {
+					continue
+				}
+				name, ok := module.memberName(entry.name)
+				if !ok || names[name] {
+					continue
+				}
+				names[name] = true
+				key := item_type + " " + namespacedName(module.namespace, name)
+				definitions[key] = append(definitions[key], scopeDefinition{path: module.path, entry: entry, item_type: item_type, is_module: module.is_module})
+			}
+		}
+	}
+	return definitions
+}
+
+// loadersOf are the files that see the file, with itself, the index is built
+// for the whole workspace once and kept until a tree or the config changes
+func (lsp *Lsp) loadersOf(path string) []string {
+	if lsp.loaders == nil {
+		lsp.loaders = map[string][]string{}
+		for _, loader := range sortedPaths(lsp.Trees) {
+			seen := map[string]bool{}
+			for _, module := range lsp.VisibleModules(loader) {
+				if !seen[module.path] {
+					seen[module.path] = true
+					lsp.loaders[module.path] = append(lsp.loaders[module.path], loader)
+				}
+			}
+		}
+	}
+	return lsp.loaders[path]
+}
+
+// duplicateDefinitionDiagnostics finds the mixins and functions that are
+// defined twice, in the file or in two files that one file loads, and the
+// variables of two modules that one file loads without a namespace
+func duplicateDefinitionDiagnostics(context RuleContext) []protocol.Diagnostic {
+	lsp := context.Lsp
+	diagnostics := []protocol.Diagnostic{}
+	tree := context.Tree
+	for _, item_type := range []string{itemTypeMixin, itemTypeFunction} {
+		first := map[string]isDefined{}
+		for _, entry := range lsp.definitionMap(item_type)[context.Path] {
+			earlier, ok := first[entry.name]
+			if !ok {
+				first[entry.name] = entry
+				continue
+			}
+			start, end := definitionNameRange(tree, entry, item_type)
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range:   rangeFromPoints(start, end),
+				Message: fmt.Sprintf("%s %s is already defined on line %d", strings.TrimLeft(item_type, "@$%"), entry.name, earlier.start_position.Row+1),
+				RelatedInformation: []protocol.DiagnosticRelatedInformation{{
+					Location: lsp.definitionLocation(context.Path, earlier, item_type),
+					Message:  "the first definition",
+				}},
+			})
+		}
+	}
+
+	reported := map[sitter.Point]bool{}
+	for _, path := range lsp.loadersOf(context.Path) {
+		definitions := lsp.scopeDefinitions(path)
+		for _, key := range sortedPaths(definitions) {
+			for _, definition := range definitions[key] {
+				if definition.path != context.Path || reported[definition.entry.start_position] {
+					continue
+				}
+				others := []scopeDefinition{}
+				for _, other := range definitions[key] {
+					is_variable := definition.item_type == itemTypeVariable
+					if other.path != definition.path && (!is_variable || other.is_module && definition.is_module) {
+						others = append(others, other)
+					}
+				}
+				if len(others) == 0 {
+					continue
+				}
+				reported[definition.entry.start_position] = true
+				places := []string{}
+				related := []protocol.DiagnosticRelatedInformation{}
+				for _, other := range others {
+					places = append(places, lsp.definitionPlace(other.path, other.entry))
+					related = append(related, protocol.DiagnosticRelatedInformation{
+						Location: lsp.definitionLocation(other.path, other.entry, other.item_type),
+						Message:  "the other definition",
+					})
+				}
+				start, end := definitionNameRange(tree, definition.entry, definition.item_type)
+				diagnostics = append(diagnostics, protocol.Diagnostic{
+					Range:              rangeFromPoints(start, end),
+					Message:            fmt.Sprintf("%s %s is also defined in %s, %s loads both", strings.TrimLeft(definition.item_type, "@$%"), definition.entry.name, strings.Join(places, ", "), lsp.relativePath(path)),
+					RelatedInformation: related,
+				})
+			}
+		}
+	}
+	return diagnostics
+}
+
+// isSemiGlobal checks if a declaration is only in flow control at the top of
+// the file, sass assigns the global variable there instead of making a new one
+func isSemiGlobal(declaration *sitter.Node) bool {
+	for ancestor := declaration.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		if ancestor.Type() == "stylesheet" {
+			return true
+		}
+		if !flowControlTypes[ancestor.Type()] {
+			return false
+		}
+	}
+	return true
+}
+
+// isDeclaredAround checks if a local scope the declaration is in already has
+// the variable, then the declaration assigns it and doesnt shadow anything
+func isDeclaredAround(declaration *sitter.Node, name string, input *[]byte) bool {
+	block := declaration.Parent()
+	for idx := 0; idx < int(block.NamedChildCount()); idx++ {
+		child := block.NamedChild(idx)
+		if child.StartByte() >= declaration.StartByte() {
+			break
+		}
+		if child.Type() == "declaration" && child.NamedChild(0) != nil && child.NamedChild(0).Content(*input) == name {
+			return true
+		}
+	}
+	for ancestor := block.Parent(); ancestor != nil && ancestor.Type() != "stylesheet"; ancestor = ancestor.Parent() {
+		if declaresVariable(ancestor, name, declaration, input) {
+			return true
+		}
+	}
+	return false
+}
+
+// globalVariables are the variables the file sees without a namespace
+func (lsp *Lsp) globalVariables(path string) map[string]scopeDefinition {
+	globals := map[string]scopeDefinition{}
+	for _, module := range lsp.VisibleModules(path) {
+		if module.namespace != "" {
+			continue
+		}
+		for _, entry := range lsp.Variables[module.path] {
+			name, ok := module.memberName(entry.name)
+			if _, seen := globals[name]; !ok || seen || !lsp.isTopLevelVariable(module.path, entry) {
+				continue
+			}
+			globals[name] = scopeDefinition{path: module.path, entry: entry, item_type: itemTypeVariable, is_module: module.is_module}
+		}
+	}
+	return globals
+}
+
+// shadowedVariableDiagnostics finds local variables with the name of a global
+// one, sass makes a new variable for them and leaves the global one alone
+func shadowedVariableDiagnostics(context RuleContext) []protocol.Diagnostic {
+	lsp := context.Lsp
+	diagnostics := []protocol.Diagnostic{}
+	globals := lsp.globalVariables(context.Path)
+	for _, entry := range lsp.Variables[context.Path] {
+		global, ok := globals[entry.name]
+		declaration := declarationOf(context.Tree, entry)
+		if !ok || declaration == nil || declaration.Parent() == nil || declaration.Parent().Type() != "block" {
+			continue
+		}
+		if strings.Contains(entry.body, "!global") || isSemiGlobal(declaration) || isDeclaredAround(declaration, entry.name, &context.Input) {
+			continue
+		}
+		name := declaration.NamedChild(0)
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:   rangeFromPoints(name.StartPoint(), name.EndPoint()),
+			Message: fmt.Sprintf("local %s shadows the global variable from %s, add !global to assign that one", entry.name, lsp.definitionPlace(global.path, global.entry)),
+			RelatedInformation: []protocol.DiagnosticRelatedInformation{{
+				Location: lsp.definitionLocation(global.path, global.entry, itemTypeVariable),
+				Message:  "the global variable",
+			}},
+		})
+	}
+	return diagnostics
+}
+
+// a !default variable of a file another one loads, and where the load is
+type loadedDefault struct {
+	path  string
+	entry isDefined
+	// the end of the statement that loads the file
+	loaded_at sitter.Point
+	// how to set it before the file is loaded
+	hint string
+}
+
+// loadedDefaults are the !default variables of the files the file @imports
+// or uses without a namespace, by the name they have in the file
+func (lsp *Lsp) loadedDefaults(path string) map[string]loadedDefault {
+	defaults := map[string]loadedDefault{}
+	for _, statement := range lsp.Modules[path] {
+		target, ok := lsp.resolveModule(path, statement.url)
+		if !ok {
+			continue
+		}
+		modules := []visibleModule{}
+		hint := "set it before the @import"
+		switch {
+		case statement.kind == "@import":
+			modules = lsp.importedModules(target, true, modules, map[string]bool{})
+		case statement.kind == "@use" && statement.namespace == "*":
+			modules = lsp.forwardedModules(visibleModule{path: target}, modules, map[string]bool{})
+			hint = "configure it with @use ... with (...)"
+		}
+		for _, module := range modules {
+			for name_in_module := range lsp.defaultVariables(module.path) {
+				name, ok := module.memberName(name_in_module)
+				if _, seen := defaults[name]; !ok || seen {
+					continue
+				}
+				for _, entry := range lsp.Variables[module.path] {
+					if entry.name == name_in_module {
+						defaults[name] = loadedDefault{path: module.path, entry: entry, loaded_at: statement.statement_end, hint: hint}
+						break
+					}
+				}
+			}
+		}
+	}
+	return defaults
+}
+
+func isAfter(a sitter.Point, b sitter.Point) bool {
+	return a.Row > b.Row || a.Row == b.Row && a.Column >= b.Column
+}
+
+// ineffectiveDefaultDiagnostics finds !default declarations that come after
+// the variable already has a value, and the values given to !default
+// variables of other files after those are loaded, when it is too late for
+// the other file to see them
+func ineffectiveDefaultDiagnostics(context RuleContext) []protocol.Diagnostic {
+	lsp := context.Lsp
+	diagnostics := []protocol.Diagnostic{}
+	assigned := map[string]isDefined{}
+	defaults := lsp.loadedDefaults(context.Path)
+	for _, entry := range lsp.Variables[context.Path] {
+		declaration := declarationOf(context.Tree, entry)
+		if declaration == nil || declaration.Parent() == nil || declaration.Parent().Type() != "stylesheet" {
+			continue
+		}
+		name := declaration.NamedChild(0)
+		is_default := strings.Contains(entry.body, "!default")
+		if earlier, ok := assigned[entry.name]; ok && is_default {
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range:   rangeFromPoints(name.StartPoint(), name.EndPoint()),
+				Message: fmt.Sprintf("%s already has a value from line %d, this !default never takes effect", entry.name, earlier.start_position.Row+1),
+			})
+			continue
+		}
+		if _, ok := assigned[entry.name]; !ok {
+			assigned[entry.name] = entry
+		}
+		loaded, ok := defaults[entry.name]
+		if is_default || !ok || !isAfter(entry.start_position, loaded.loaded_at) {
+			continue
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:   rangeFromPoints(name.StartPoint(), name.EndPoint()),
+			Message: fmt.Sprintf("%s is a !default of %s, which is already loaded and never sees this value, %s", entry.name, lsp.definitionPlace(loaded.path, loaded.entry), loaded.hint),
+			RelatedInformation: []protocol.DiagnosticRelatedInformation{{
+				Location: lsp.definitionLocation(loaded.path, loaded.entry, itemTypeVariable),
+				Message:  "the !default variable",
+			}},
+		})
+	}
+	return diagnostics
+}
@@ -0,0 +1,93 @@
+package lsp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticsWithCode keeps the diagnostics of one rule
+func diagnosticsWithCode(diagnostics []protocol.Diagnostic, code string) []protocol.Diagnostic {
+	found := []protocol.Diagnostic{}
+	for _, diagnostic := range diagnostics {
+		if diagnosticCode(diagnostic) == code {
+			found = append(found, diagnostic)
+		}
+	}
+	return found
+}
+
+func TestConflictingDefinitions(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"_vars.scss":  "$primary: red !default;\n$gap: 4px;\n",
+		"_other.scss": "@mixin button { a: b; }\n$gap: 8px;\n",
+		"_theme.scss": "@mixin button { c: d; }\n@function f() { @return 1; }\n@function f() { @return 2; }\n",
+		"main.scss": `@import "vars";
+@import "other";
+@import "theme";
+$primary: blue;
+.a { $gap: 1px; @if true { $gap: 3px; } }
+@mixin m($gap) { $gap: 2px; }
+@if true { $primary: green; }
+$local: 1;
+$local: 2 !default;
+`,
+		"modules/_a.scss":    "$x: 1;\n$size: 1 !default;\n",
+		"modules/_b.scss":    "$x: 2;\n",
+		"modules/entry.scss": "@use \"a\" as *;\n@use \"b\" as *;\n$size: 2;\n",
+	})
+	lsp := LoadWorkspace(root)
+
+	duplicates := diagnosticsWithCode(lsp.getDiagnostics(filepath.Join(root, "_theme.scss")), diagnosticDuplicateDefinition)
+	if len(duplicates) != 2 {
+		t.Fatalf("unexpected diagnostics %+v", duplicates)
+	}
+	if duplicates[0].Message != "function f is already defined on line 2" || duplicates[0].Range.Start != (protocol.Position{Line: 2, Character: 10}) {
+		t.Fatalf("unexpected diagnostic %+v", duplicates[0])
+	}
+	if duplicates[1].Message != "mixin button is also defined in _other.scss:1, main.scss loads both" || len(duplicates[1].RelatedInformation) != 1 {
+		t.Fatalf("unexpected diagnostic %+v", duplicates[1])
+	}
+	// @import lets a later variable replace an earlier one, @use doesnt
+	if duplicates := diagnosticsWithCode(lsp.getDiagnostics(filepath.Join(root, "_other.scss")), diagnosticDuplicateDefinition); len(duplicates) != 1 {
+		t.Fatalf("expected only the mixin, got %+v", duplicates)
+	}
+	duplicates = diagnosticsWithCode(lsp.getDiagnostics(filepath.Join(root, "modules/_a.scss")), diagnosticDuplicateDefinition)
+	if len(duplicates) != 1 || duplicates[0].Message != "variable $x is also defined in modules/_b.scss:1, modules/entry.scss loads both" {
+		t.Fatalf("unexpected diagnostics %+v", duplicates)
+	}
+	// the loaders are kept between files, a new tree has to drop them
+	writeFiles(t, root, map[string]string{"_other.scss": "@mixin link { a: b; }\n$gap: 8px;\n"})
+	if _, err := lsp.ParseAndSaveTree(filepath.Join(root, "_other.scss")); err != nil {
+		t.Fatal(err)
+	}
+	if duplicates := diagnosticsWithCode(lsp.getDiagnostics(filepath.Join(root, "_theme.scss")), diagnosticDuplicateDefinition); len(duplicates) != 1 {
+		t.Fatalf("expected only the function, got %+v", duplicates)
+	}
+
+	diagnostics := lsp.getDiagnostics(filepath.Join(root, "main.scss"))
+	shadowed := diagnosticsWithCode(diagnostics, diagnosticShadowedVariable)
+	if len(shadowed) != 1 || shadowed[0].Range.Start != (protocol.Position{Line: 4, Character: 5}) {
+		t.Fatalf("expected only the variable of .a, got %+v", shadowed)
+	}
+	if shadowed[0].Message != "local $gap shadows the global variable from _vars.scss:2, add !global to assign that one" {
+		t.Fatalf("unexpected message %q", shadowed[0].Message)
+	}
+
+	ineffective := diagnosticsWithCode(diagnostics, diagnosticIneffectiveDefault)
+	if len(ineffective) != 2 {
+		t.Fatalf("unexpected diagnostics %+v", ineffective)
+	}
+	if ineffective[0].Message != "$primary is a !default of _vars.scss:1, which is already loaded and never sees this value, set it before the @import" {
+		t.Fatalf("unexpected message %q", ineffective[0].Message)
+	}
+	if ineffective[1].Message != "$local already has a value from line 8, this !default never takes effect" || ineffective[1].Range.Start.Line != 8 {
+		t.Fatalf("unexpected diagnostic %+v", ineffective[1])
+	}
+	ineffective = diagnosticsWithCode(lsp.getDiagnostics(filepath.Join(root, "modules/entry.scss")), diagnosticIneffectiveDefault)
+	if len(ineffective) != 1 || ineffective[0].Message != "$size is a !default of modules/_a.scss:2, which is already loaded and never sees this value, configure it with @use ... with (...)" {
+		t.Fatalf("unexpected diagnostics %+v", ineffective)
+	}
+}
@@ -0,0 +1,89 @@
+package lsp
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// the properties and units of plain css, bundled in the binary so nothing
+// is fetched at runtime. data/generate.go makes the file from mdn-data, the
+// descriptions are short summaries of MDN that it keeps
+//
+//go:generate go run data/generate.go
+//go:embed data/css.json
+var cssDataJson []byte
+
+type cssProperty struct {
+	Name        string   `json:"name"`
+	Syntax      string   `json:"syntax"`
+	Values      []string `json:"values"`
+	Description string   `json:"description"`
+	Reference   string   `json:"reference"`
+}
+
+type cssUnit struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type cssData struct {
+	Properties []cssProperty `json:"properties"`
+	Units      []cssUnit     `json:"units"`
+}
+
+var (
+	loadCssData   sync.Once
+	cssDataLoaded cssData
+	cssProperties map[string]cssProperty
+)
+
+// getCssData parses the data the first time it is needed
+func getCssData() cssData {
+	loadCssData.Do(func() {
+		if err := json.Unmarshal(cssDataJson, &cssDataLoaded); err != nil {
+			// the file is part of the binary, this can only happen while
+			// working on it
+			panic(err)
+		}
+		cssProperties = make(map[string]cssProperty, len(cssDataLoaded.Properties))
+		for _, property := range cssDataLoaded.Properties {
+			cssProperties[property.Name] = property
+		}
+	})
+	return cssDataLoaded
+}
+
+// vendor prefixed properties get the data of the standard one
+func unprefixedProperty(name string) string {
+	for _, prefix := range []string{"-webkit-", "-moz-", "-ms-", "-o-"} {
+		if strings.HasPrefix(name, prefix) {
+			return strings.TrimPrefix(name, prefix)
+		}
+	}
+	return name
+}
+
+func lookupCssProperty(name string) (cssProperty, bool) {
+	getCssData()
+	property, ok := cssProperties[unprefixedProperty(strings.ToLower(name))]
+	return property, ok
+}
+
+// documentation is the markdown shown in hover and completion
+func (property cssProperty) documentation() string {
+	var sb strings.Builder
+	sb.WriteString(property.Description)
+	sb.WriteString("\n\n```css\n")
+	sb.WriteString(property.Name)
+	sb.WriteString(": ")
+	sb.WriteString(property.Syntax)
+	sb.WriteString("\n```")
+	if property.Reference != "" {
+		sb.WriteString("\n\n[MDN Reference](")
+		sb.WriteString(property.Reference)
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
@@ -0,0 +1,132 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+func TestLookupCssProperty(t *testing.T) {
+	property, ok := lookupCssProperty("-webkit-Transform")
+	if !ok || property.Name != "transform" {
+		t.Fatalf("expected transform, got %+v", property)
+	}
+	if _, ok := lookupCssProperty("not-a-property"); ok {
+		t.Fatalf("did not expect a property")
+	}
+	for _, name := range []string{"translate", "scale", "rotate", "touch-action", "content-visibility", "quotes", "scrollbar-width", "accent-color", "text-wrap", "inset-inline-start", "margin-block-start", "font-variant-numeric", "text-underline-offset"} {
+		if _, ok := lookupCssProperty(name); !ok {
+			t.Fatalf("expected %s in the data", name)
+		}
+	}
+	for _, property := range getCssData().Properties {
+		if property.Description == "" || property.Syntax == "" {
+			t.Fatalf("%s is missing its description or syntax", property.Name)
+		}
+	}
+}
+
+func TestCssValueCompletion(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{path: ".a {\n  display: f;\n  width: 10;\n  dis\n}\n"})
+
+	list := lsp.GetCompletions(path, protocol.Position{Line: 1, Character: 12})
+	labels := completionLabels(list)
+	for _, expected := range []string{"flex", "grid", "none", "inherit"} {
+		if _, ok := labels[expected]; !ok {
+			t.Fatalf("expected %s in %v", expected, labels)
+		}
+	}
+	if list.Items[0].Label != "block" {
+		t.Fatalf("expected the keywords of display first, got %s", list.Items[0].Label)
+	}
+
+	labels = completionLabels(lsp.GetCompletions(path, protocol.Position{Line: 2, Character: 11}))
+	if item, ok := labels["10px"]; !ok || item.TextEdit.Range.Start.Character != 9 {
+		t.Fatalf("expected 10px, got %v", labels)
+	}
+	if _, ok := labels["inherit"]; ok {
+		t.Fatalf("did not expect keywords after a number")
+	}
+
+	labels = completionLabels(lsp.GetCompletions(path, protocol.Position{Line: 3, Character: 5}))
+	display, ok := labels["display"]
+	if !ok || display.TextEdit.NewText != "display: " {
+		t.Fatalf("expected display, got %+v", display)
+	}
+}
+
+func TestCssPropertyHover(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{path: ".a {\n  box-sizing: border-box;\n}\n"})
+
+	hover := lsp.GetHoverInfo(path, sitter.Point{Row: 1, Column: 4})
+	if !strings.HasPrefix(hover, "Whether width and height include padding and border.") ||
+		!strings.Contains(hover, "box-sizing: content-box | border-box") {
+		t.Fatalf("unexpected hover %q", hover)
+	}
+}
+
+func TestGenerateCssData(t *testing.T) {
+	// a css.json from before, whose descriptions and unit order are kept
+	out := filepath.Join(t.TempDir(), "css.json")
+	existing := `{"properties": [{"name": "top", "description": "From before."}, {"name": "gone", "description": "Not in mdn-data."}],
+		"units": [{"name": "dvh", "description": "Dynamic."}, {"name": "%", "description": "Percent."}, {"name": "px", "description": "Pixels."}, {"name": "gone", "description": "Not in mdn-data."}]}`
+	if err := os.WriteFile(out, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	command := exec.Command("go", "run", "data/generate.go", "-mdn-data", "testdata/mdn-data", "-out", out)
+	if output, err := command.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, output)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated := cssData{}
+	if err := json.Unmarshal(data, &generated); err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	properties := map[string]cssProperty{}
+	for _, property := range generated.Properties {
+		names = append(names, property.Name)
+		properties[property.Name] = property
+	}
+	// prefixed, nonstandard and obsolete ones are left out
+	if strings.Join(names, " ") != "align-content inset-inline-start quotes top translate width" {
+		t.Fatalf("unexpected properties %v", names)
+	}
+	if properties["top"].Description != "From before." || properties["top"].Reference != "https://developer.mozilla.org/docs/Web/CSS/top" {
+		t.Fatalf("expected the old description, got %+v", properties["top"])
+	}
+	if properties["quotes"].Description != "Part of CSS Generated Content, the initial value is depends on user agent." {
+		t.Fatalf("unexpected description %q", properties["quotes"].Description)
+	}
+	// the keywords of the types and properties the syntax refers to
+	if values := strings.Join(properties["align-content"].Values, " "); values != "normal first last baseline space-between space-around space-evenly stretch unsafe safe center start end flex-start flex-end" {
+		t.Fatalf("unexpected values %q", values)
+	}
+	if values := strings.Join(properties["inset-inline-start"].Values, " "); values != "auto" {
+		t.Fatalf("unexpected values %q", values)
+	}
+	if values := strings.Join(properties["width"].Values, " "); values != "auto min-content max-content fit-content" {
+		t.Fatalf("unexpected values %q", values)
+	}
+
+	units := []string{}
+	for _, unit := range generated.Units {
+		units = append(units, unit.Name+" "+unit.Description)
+	}
+	expected := "dvh Dynamic.|% Percent.|px Pixels.|cap One of the CSS Lengths.|Hz One of the CSS Frequencies."
+	if strings.Join(units, "|") != expected {
+		t.Fatalf("unexpected units %q", units)
+	}
+}
@@ -0,0 +1,223 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	binding "scss-lsp/scss_binding"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+// {{name}} in the message of a custom rule or in the replacement of a
+// rewrite is the text of the capture
+var captureTemplateRegex = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+// a rule of the config, a tree-sitter query with checks on what it captures
+type CustomRuleConfig struct {
+	Id          string `json:"id"`
+	Description string `json:"description"`
+	Query       string `json:"query"`
+	// the capture the diagnostic is on, the first one of the match when
+	// empty
+	Capture    string                `json:"capture"`
+	Predicates []CustomRulePredicate `json:"predicates"`
+	// {{name}} is replaced with the text of the capture name
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+	// globs for the path relative to the root or the name of the file, the
+	// rule only checks the files that match Files, if any, and not Exclude
+	Files   []string `json:"files"`
+	Exclude []string `json:"exclude"`
+}
+
+// the text of a capture has to match Match and not NotMatch, when they are
+// set
+type CustomRulePredicate struct {
+	Capture  string `json:"capture"`
+	Match    string `json:"match"`
+	NotMatch string `json:"notMatch"`
+}
+
+type capturePredicate struct {
+	capture string
+	regex   *regexp.Regexp
+	negate  bool
+}
+
+type queryRule struct {
+	config     CustomRuleConfig
+	severity   protocol.DiagnosticSeverity
+	query      *sitter.Query
+	predicates []capturePredicate
+}
+
+func (rule queryRule) Id() string {
+	return rule.config.Id
+}
+
+func (rule queryRule) Description() string {
+	if rule.config.Description == "" {
+		return rule.config.Query
+	}
+	return rule.config.Description
+}
+
+func (rule queryRule) DefaultSeverity() protocol.DiagnosticSeverity {
+	return rule.severity
+}
+
+func (rule queryRule) Fixes(lsp *Lsp, path string, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+	return []protocol.CodeAction{}
+}
+
+// matchesGlobs checks the path relative to the root and the file name
+func matchesGlobs(relative string, globs []string) bool {
+	for _, glob := range globs {
+		if matched, _ := filepath.Match(glob, relative); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(glob, filepath.Base(relative)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule queryRule) Check(context RuleContext) []protocol.Diagnostic {
+	relative := filepath.ToSlash(context.Lsp.relativePath(context.Path))
+	if len(rule.config.Files) > 0 && !matchesGlobs(relative, rule.config.Files) || matchesGlobs(relative, rule.config.Exclude) {
+		return []protocol.Diagnostic{}
+	}
+	return queryMatches(rule.query, func(context RuleContext, match *sitter.QueryMatch) []protocol.Diagnostic {
+		captures := map[string]*sitter.Node{}
+		var target *sitter.Node
+		for _, capture := range match.Captures {
+			name := rule.query.CaptureNameForId(capture.Index)
+			captures[name] = capture.Node
+			if target == nil && (rule.config.Capture == "" || rule.config.Capture == name) {
+				target = capture.Node
+			}
+		}
+		if target == nil {
+			return nil
+		}
+		for _, predicate := range rule.predicates {
+			node, ok := captures[predicate.capture]
+			if !ok || predicate.regex.MatchString(node.Content(context.Input)) == predicate.negate {
+				return nil
+			}
+		}
+		texts := map[string]string{}
+		for name, node := range captures {
+			texts[name] = node.Content(context.Input)
+		}
+		return []protocol.Diagnostic{{
+			Range:   rangeFromPoints(target.StartPoint(), target.EndPoint()),
+			Message: fillTemplate(rule.config.Message, texts),
+		}}
+	})(context)
+}
+
+// compileQuery compiles a query that comes from the user
+func compileQuery(text string) (*sitter.Query, error) {
+	query, err := sitter.NewQuery([]byte(text), binding.GetLanguage())
+	if err != nil {
+		return nil, err
+	}
+	// the #match? predicates of the query itself are compiled while matching,
+	// and that panics on a bad regex
+	for pattern := uint32(0); pattern < query.PatternCount(); pattern++ {
+		for _, steps := range query.PredicatesForPattern(pattern) {
+			operator := query.StringValueForId(steps[0].ValueId)
+			if (operator == "match?" || operator == "not-match?") && len(steps) > 2 {
+				if _, err := regexp.Compile(query.StringValueForId(steps[2].ValueId)); err != nil {
+					return nil, fmt.Errorf("#%s %v", operator, err)
+				}
+			}
+		}
+	}
+	return query, nil
+}
+
+// compileCustomRule checks a rule of the config and compiles its query and
+// regexes
+func compileCustomRule(config CustomRuleConfig) (Rule, error) {
+	if config.Id == "" {
+		return nil, fmt.Errorf("a custom rule needs an id")
+	}
+	if _, ok := lookupRule(config.Id); ok {
+		return nil, fmt.Errorf("custom rule %s: there already is a rule %s", config.Id, config.Id)
+	}
+	rule := queryRule{config: config, severity: protocol.DiagnosticSeverityWarning}
+	if config.Severity != "" {
+		severity, ok := ParseSeverity(config.Severity)
+		if !ok {
+			return nil, fmt.Errorf("custom rule %s: unknown severity %s", config.Id, config.Severity)
+		}
+		rule.severity = severity
+	}
+	if rule.config.Message == "" {
+		rule.config.Message = config.Id
+	}
+
+	query, err := compileQuery(config.Query)
+	if err != nil {
+		return nil, fmt.Errorf("custom rule %s: %v", config.Id, err)
+	}
+	names := map[string]bool{}
+	for id := uint32(0); id < query.CaptureCount(); id++ {
+		names[query.CaptureNameForId(id)] = true
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("custom rule %s: the query captures nothing", config.Id)
+	}
+	if config.Capture != "" && !names[config.Capture] {
+		return nil, fmt.Errorf("custom rule %s: the query has no capture @%s", config.Id, config.Capture)
+	}
+	rule.query = query
+
+	for _, predicate := range config.Predicates {
+		if !names[predicate.Capture] {
+			return nil, fmt.Errorf("custom rule %s: the query has no capture @%s", config.Id, predicate.Capture)
+		}
+		for _, check := range []struct {
+			pattern string
+			negate  bool
+		}{{predicate.Match, false}, {predicate.NotMatch, true}} {
+			if check.pattern == "" {
+				continue
+			}
+			regex, err := regexp.Compile(check.pattern)
+			if err != nil {
+				return nil, fmt.Errorf("custom rule %s: %v", config.Id, err)
+			}
+			rule.predicates = append(rule.predicates, capturePredicate{capture: predicate.Capture, regex: regex, negate: check.negate})
+		}
+	}
+	return rule, nil
+}
+
+// compileCustomRules compiles the custom rules of the config, the ones with
+// mistakes are left out
+func compileCustomRules(configs []CustomRuleConfig) ([]Rule, []string) {
+	rules := []Rule{}
+	errors := []string{}
+	seen := map[string]bool{}
+	for _, config := range configs {
+		if seen[config.Id] {
+			errors = append(errors, fmt.Sprintf("custom rule %s is there twice", config.Id))
+			continue
+		}
+		seen[config.Id] = true
+		rule, err := compileCustomRule(config)
+		if err != nil {
+			errors = append(errors, err.Error())
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, errors
+}
@@ -0,0 +1,70 @@
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestCustomRules(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".scss-lsp.json": `{
+			"customRules": [
+				{
+					"id": "no-raw-colors",
+					"query": "(color_value) @color",
+					"message": "use a palette variable instead of {{color}}",
+					"severity": "error",
+					"exclude": ["_palette.scss"]
+				},
+				{
+					"id": "z-index-token",
+					"query": "(declaration (property_name) @property (_) @value (#eq? @property \"z-index\"))",
+					"capture": "value",
+					"predicates": [{"capture": "value", "notMatch": "^\\$"}],
+					"message": "z-index {{value}} is not a token"
+				},
+				{"id": "broken", "query": "(declaration @missing"},
+				{"id": "bad-regex", "query": "(color_value) @color (#match? @color \"[\")"},
+				{"id": "undefined", "query": "(color_value) @color"}
+			],
+			"overrides": [{"directory": "vendor", "rules": {"z-index-token": "off"}}]
+		}`,
+		"_palette.scss":   "$primary: #ff0000;\n",
+		"main.scss":       "$z: 3;\n.a { color: #fff; z-index: 10; }\n.b { z-index: $z; color: $primary; }\n",
+		"vendor/lib.scss": ".c { z-index: 99; }\n",
+	})
+	lsp := LoadWorkspace(root)
+	if len(lsp.ConfigErrors) != 3 {
+		t.Fatalf("expected the 3 broken rules to be reported, got %q", lsp.ConfigErrors)
+	}
+	if !strings.Contains(lsp.RuleDocs(), "no-raw-colors (error)") {
+		t.Fatalf("expected the custom rules in the docs")
+	}
+
+	if diagnostics := lsp.getDiagnostics(filepath.Join(root, "_palette.scss")); len(diagnostics) != 0 {
+		t.Fatalf("expected the palette to be excluded, got %+v", diagnostics)
+	}
+	diagnostics := lsp.getDiagnostics(filepath.Join(root, "main.scss"))
+	if len(diagnostics) != 2 {
+		t.Fatalf("unexpected diagnostics %+v", diagnostics)
+	}
+	if diagnostics[0].Code != "no-raw-colors" || diagnostics[0].Message != "use a palette variable instead of #fff" || diagnostics[0].Severity != protocol.DiagnosticSeverityError {
+		t.Fatalf("unexpected diagnostic %+v", diagnostics[0])
+	}
+	if diagnostics[1].Code != "z-index-token" || diagnostics[1].Message != "z-index 10 is not a token" || diagnostics[1].Range.Start != (protocol.Position{Line: 1, Character: 27}) {
+		t.Fatalf("unexpected diagnostic %+v", diagnostics[1])
+	}
+	if diagnostics := lsp.getDiagnostics(filepath.Join(root, "vendor/lib.scss")); len(diagnostics) != 0 {
+		t.Fatalf("expected the override to turn the rule off, got %+v", diagnostics)
+	}
+
+	// custom rules can be hidden like the others
+	actions := lsp.GetCodeActions(filepath.Join(root, "main.scss"), protocol.CodeActionContext{Diagnostics: diagnostics[1:]})
+	if titles := actionTitles(actions); len(titles) != 2 || titles[0] != "Disable z-index-token for this line" {
+		t.Fatalf("unexpected actions %q", titles)
+	}
+}
@@ -0,0 +1,318 @@
+//go:build ignore
+
+// generate.go writes css.json from the mdn-data release of mdnDataVersion,
+// run it with
+//
+//	go generate ./lsp
+//
+// which downloads the package from the npm registry, or with -mdn-data (or
+// MDN_DATA) set to an unpacked package to work offline.
+//
+// mdn-data has no descriptions, the ones already in css.json are kept and the
+// new properties and units get one from their groups and initial value. The
+// order of the units is the order of the completion after a number, the ones
+// already in css.json stay in front.
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// the release css.json is made from, bump it and run go generate to update
+const mdnDataVersion = "2.12.2"
+
+// the files of mdn-data that are used, by their path in the package
+var mdnDataFiles = []string{"css/properties.json", "css/syntaxes.json", "css/units.json", "l10n/css.json"}
+
+type cssProperty struct {
+	Name        string   `json:"name"`
+	Syntax      string   `json:"syntax"`
+	Values      []string `json:"values"`
+	Description string   `json:"description"`
+	Reference   string   `json:"reference"`
+}
+
+type cssUnit struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type cssData struct {
+	Properties []cssProperty `json:"properties"`
+	Units      []cssUnit     `json:"units"`
+}
+
+// the parts of css/properties.json that are used
+type mdnProperty struct {
+	Syntax  string      `json:"syntax"`
+	Initial interface{} `json:"initial"`
+	Groups  []string    `json:"groups"`
+	Status  string      `json:"status"`
+	MdnUrl  string      `json:"mdn_url"`
+}
+
+type mdnSyntax struct {
+	Syntax string `json:"syntax"`
+}
+
+type mdnUnit struct {
+	Groups []string `json:"groups"`
+	Status string   `json:"status"`
+}
+
+// the words of l10n/css.json, for the initial values
+type mdnText map[string]map[string]string
+
+// a reference to another property, a type or a keyword, functions are left
+// out since they cant be completed like a keyword
+var syntaxTokenRegex = regexp.MustCompile(`<'([^'>]+)'>|<([a-zA-Z0-9-]+)(?:\(\))?(?: \[[^\]]*\])?>|([a-zA-Z][a-zA-Z0-9-]*)(\()?`)
+
+// css-wide keywords are added to every property by the completion
+var cssWideKeywords = map[string]bool{"inherit": true, "initial": true, "unset": true, "revert": true, "revert-layer": true}
+
+// how deep the types of the syntax are followed for keywords, further down
+// there are mostly the keywords of other properties
+const keywordDepth = 3
+
+type generator struct {
+	properties map[string]mdnProperty
+	syntaxes   map[string]mdnSyntax
+}
+
+// keywords are the keywords of the syntax, in order, with the ones of the
+// types and properties it refers to
+func (gen *generator) keywords(syntax string, depth int, seen map[string]bool, keywords []string) []string {
+	for _, match := range syntaxTokenRegex.FindAllStringSubmatch(syntax, -1) {
+		switch {
+		case match[1] != "":
+			if property, ok := gen.properties[match[1]]; ok && depth > 0 && !seen["'"+match[1]] {
+				seen["'"+match[1]] = true
+				keywords = gen.keywords(property.Syntax, depth-1, seen, keywords)
+			}
+		case match[2] != "":
+			if definition, ok := gen.syntaxes[match[2]]; ok && depth > 0 && !seen[match[2]] {
+				seen[match[2]] = true
+				keywords = gen.keywords(definition.Syntax, depth-1, seen, keywords)
+			}
+		case match[4] == "" && !seen[match[3]] && !cssWideKeywords[match[3]]:
+			seen[match[3]] = true
+			keywords = append(keywords, match[3])
+		}
+	}
+	return keywords
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func readJson(path string, into interface{}) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, into)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+// unpackedFiles reads the files from an unpacked package
+func unpackedFiles(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	for _, name := range mdnDataFiles {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
+// downloadedFiles reads the files from the tarball of the release on the npm
+// registry, everything in it is under package/
+func downloadedFiles(version string) (map[string][]byte, error) {
+	url := fmt.Sprintf("https://registry.npmjs.org/mdn-data/-/mdn-data-%s.tgz", version)
+	response, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, response.Status)
+	}
+	unzipped, err := gzip.NewReader(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, name := range mdnDataFiles {
+		wanted[name] = true
+	}
+	files := map[string][]byte{}
+	archive := tar.NewReader(unzipped)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(header.Name, "package/")
+		if !wanted[name] {
+			continue
+		}
+		if files[name], err = io.ReadAll(archive); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range mdnDataFiles {
+		if files[name] == nil {
+			return nil, fmt.Errorf("%s has no %s", url, name)
+		}
+	}
+	return files, nil
+}
+
+// describe is the text of an l10n key, or the value itself when it is not one
+func (text mdnText) describe(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		if words, ok := text[value]; ok {
+			return stripTags(words["en-US"])
+		}
+		return value
+	case []interface{}:
+		parts := []string{}
+		for _, part := range value {
+			parts = append(parts, text.describe(part))
+		}
+		return strings.Join(parts, ", ")
+	}
+	return ""
+}
+
+var tagRegex = regexp.MustCompile(`<[^>]+>`)
+
+func stripTags(text string) string {
+	return tagRegex.ReplaceAllString(text, "")
+}
+
+func main() {
+	mdn_data := flag.String("mdn-data", os.Getenv("MDN_DATA"), "an unpacked mdn-data package, the release of mdnDataVersion is downloaded without one")
+	out := flag.String("out", "data/css.json", "the file to write, its descriptions are kept")
+	flag.Parse()
+
+	var files map[string][]byte
+	var err error
+	if *mdn_data != "" {
+		files, err = unpackedFiles(*mdn_data)
+	} else {
+		files, err = downloadedFiles(mdnDataVersion)
+	}
+	if err != nil {
+		fail(err)
+	}
+	gen := &generator{}
+	units := map[string]mdnUnit{}
+	text := mdnText{}
+	for name, into := range map[string]interface{}{
+		"css/properties.json": &gen.properties,
+		"css/syntaxes.json":   &gen.syntaxes,
+		"css/units.json":      &units,
+		"l10n/css.json":       &text,
+	} {
+		if err := json.Unmarshal(files[name], into); err != nil {
+			fail(fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	existing := cssData{}
+	if _, err := os.Stat(*out); err == nil {
+		readJson(*out, &existing)
+	}
+	descriptions := map[string]string{}
+	for _, property := range existing.Properties {
+		descriptions[property.Name] = property.Description
+	}
+
+	data := cssData{Properties: []cssProperty{}, Units: []cssUnit{}}
+	names := []string{}
+	for name := range gen.properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property := gen.properties[name]
+		// vendor prefixed properties get the data of the standard one
+		if strings.HasPrefix(name, "-") || property.Status == "obsolete" || property.Status == "nonstandard" {
+			continue
+		}
+		description := descriptions[name]
+		if description == "" {
+			description = fmt.Sprintf("Part of %s, the initial value is %s.", strings.Join(property.Groups, " and "), text.describe(property.Initial))
+		}
+		values := gen.keywords(property.Syntax, keywordDepth, map[string]bool{}, []string{})
+		data.Properties = append(data.Properties, cssProperty{
+			Name:        name,
+			Syntax:      property.Syntax,
+			Values:      values,
+			Description: description,
+			Reference:   property.MdnUrl,
+		})
+	}
+
+	seen := map[string]bool{}
+	for _, unit := range existing.Units {
+		if _, ok := units[unit.Name]; ok || unit.Name == "%" {
+			data.Units = append(data.Units, unit)
+			seen[unit.Name] = true
+		}
+	}
+	unit_names := []string{}
+	for name := range units {
+		unit_names = append(unit_names, name)
+	}
+	sort.Slice(unit_names, func(i, j int) bool {
+		return strings.ToLower(unit_names[i]) < strings.ToLower(unit_names[j])
+	})
+	for _, name := range unit_names {
+		unit := units[name]
+		if seen[name] || unit.Status == "obsolete" || unit.Status == "nonstandard" {
+			continue
+		}
+		group := "CSS Units"
+		for _, unit_group := range unit.Groups {
+			if unit_group != "CSS Units" {
+				group = unit_group
+			}
+		}
+		data.Units = append(data.Units, cssUnit{Name: name, Description: "One of the " + group + "."})
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", " ")
+	if err := encoder.Encode(data); err != nil {
+		fail(err)
+	}
+	if err := os.WriteFile(*out, buffer.Bytes(), 0644); err != nil {
+		fail(err)
+	}
+	fmt.Printf("%d properties and %d units\n", len(data.Properties), len(data.Units))
+}
@@ -0,0 +1,569 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+// the codes of the warnings for things dart sass is going to remove, every
+// one of them has a quick fix
+const (
+	diagnosticDeprecatedImport    = "deprecated-import"
+	diagnosticDeprecatedGlobal    = "deprecated-global-function"
+	diagnosticDeprecatedDivision  = "deprecated-slash-division"
+	diagnosticDeprecatedNewGlobal = "deprecated-new-global-variable"
+)
+
+// what the fix all action of every kind of deprecation migrates
+var deprecationLabels = map[string]string{
+	diagnosticDeprecatedImport:    "@import rules",
+	diagnosticDeprecatedGlobal:    "global functions",
+	diagnosticDeprecatedDivision:  "divisions",
+	diagnosticDeprecatedNewGlobal: "new !global variables",
+}
+
+// global functions that are also plain css functions, sass leaves them to
+// the browser when the arguments are css
+var cssGlobalFunctions = map[string]bool{
+	"min":   true,
+	"max":   true,
+	"round": true,
+	"abs":   true,
+}
+
+// css filter functions that have the same name as a color function
+var cssFilterFunctions = map[string]bool{
+	"grayscale": true,
+	"invert":    true,
+	"opacity":   true,
+	"saturate":  true,
+}
+
+// in these the / is a css division or separator, not a sass one
+var cssSlashFunctions = map[string]bool{
+	"calc":  true,
+	"clamp": true,
+	"min":   true,
+	"max":   true,
+	"url":   true,
+}
+
+var keywordArgumentRegex = regexp.MustCompile(`^\$[\w-]+\s*:`)
+
+// a deprecated construct in a file, fix makes the edits that migrate it
+type deprecation struct {
+	code           string
+	message        string
+	start_position sitter.Point
+	end_position   sitter.Point
+	fix            func() (title string, edits []protocol.TextEdit, ok bool)
+}
+
+func (lsp *Lsp) findDeprecations(path string) []deprecation {
+	deprecations := []deprecation{}
+	tree := lsp.Trees[path]
+	input, err := lsp.bytesFromFilePath(path)
+	if tree == nil || err != nil {
+		return deprecations
+	}
+	deprecations = append(deprecations, lsp.importDeprecations(path, tree, *input)...)
+	deprecations = append(deprecations, lsp.globalFunctionDeprecations(path, tree, *input)...)
+	deprecations = append(deprecations, lsp.divisionDeprecations(path, tree, *input)...)
+	deprecations = append(deprecations, lsp.newGlobalDeprecations(path, tree, *input)...)
+	return deprecations
+}
+
+// deprecationRule is the rule for one kind of deprecation, find is one of
+// the functions findDeprecations runs
+func deprecationRule(code string, description string, find func(lsp *Lsp, path string, tree *sitter.Tree, input []byte) []deprecation) Rule {
+	return lintRule{
+		id:          code,
+		description: description,
+		severity:    protocol.DiagnosticSeverityWarning,
+		check: func(context RuleContext) []protocol.Diagnostic {
+			diagnostics := []protocol.Diagnostic{}
+			for _, found := range find(context.Lsp, context.Path, context.Tree, context.Input) {
+				diagnostics = append(diagnostics, protocol.Diagnostic{
+					Range:   rangeFromPoints(found.start_position, found.end_position),
+					Message: found.message,
+					Tags:    []protocol.DiagnosticTag{protocol.DiagnosticTagDeprecated},
+				})
+			}
+			return diagnostics
+		},
+		fixes: deprecationQuickFixes,
+	}
+}
+
+func rangesOverlap(a protocol.Range, b protocol.Range) bool {
+	before := func(x protocol.Position, y protocol.Position) bool {
+		return x.Line < y.Line || x.Line == y.Line && x.Character <= y.Character
+	}
+	return !before(a.End, b.Start) && !before(b.End, a.Start)
+}
+
+// mergeEdits puts the edits of many fixes together, the same @use added by
+// two fixes is added once and a fix that overlaps an earlier one is left
+// for the next run
+func mergeEdits(fixes [][]protocol.TextEdit) []protocol.TextEdit {
+	merged := []protocol.TextEdit{}
+	for _, edits := range fixes {
+		fresh := []protocol.TextEdit{}
+		overlaps := false
+		for _, edit := range edits {
+			duplicate := false
+			for _, other := range merged {
+				if edit == other {
+					duplicate = true
+					break
+				}
+				if edit.Range.Start != edit.Range.End && other.Range.Start != other.Range.End && rangesOverlap(edit.Range, other.Range) {
+					overlaps = true
+				}
+			}
+			if !duplicate {
+				fresh = append(fresh, edit)
+			}
+		}
+		if !overlaps {
+			merged = append(merged, fresh...)
+		}
+	}
+	return merged
+}
+
+func deprecationQuickFixes(lsp *Lsp, path string, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	code := diagnosticCode(diagnostic)
+	fixes := [][]protocol.TextEdit{}
+	for _, found := range lsp.findDeprecations(path) {
+		if found.code != code {
+			continue
+		}
+		title, edits, ok := found.fix()
+		if !ok {
+			continue
+		}
+		fixes = append(fixes, edits)
+		if rangeFromPoints(found.start_position, found.end_position) == diagnostic.Range {
+			actions = append(actions, quickFix(title, diagnostic, path, edits))
+		}
+	}
+	if len(actions) > 0 && len(fixes) > 1 {
+		actions = append(actions, quickFix(
+			fmt.Sprintf("Migrate all %s in the file", deprecationLabels[code]),
+			diagnostic,
+			path,
+			mergeEdits(fixes),
+		))
+	}
+	return actions
+}
+
+// useBuiltinModule is the prefix members of the built-in module get in the
+// file, with the @use to add when the file doesnt load the module yet
+func (lsp *Lsp) useBuiltinModule(path string, module string) (string, []protocol.TextEdit) {
+	url := "sass:" + module
+	for _, statement := range lsp.Modules[path] {
+		if statement.kind == "@use" && statement.url == url {
+			if statement.namespace == "*" {
+				return "", nil
+			}
+			return statement.namespace + ".", nil
+		}
+	}
+	namespace := lsp.freeNamespace(path, module)
+	use := fmt.Sprintf("@use \"%s\";\n", url)
+	if namespace != module {
+		use = fmt.Sprintf("@use \"%s\" as %s;\n", url, namespace)
+	}
+	position := lsp.useInsertPosition(path)
+	return namespace + ".", []protocol.TextEdit{{Range: protocol.Range{Start: position, End: position}, NewText: use}}
+}
+
+// lineIndent is the whitespace the line of the point starts with
+func lineIndent(input []byte, point sitter.Point) string {
+	lines := strings.Split(string(input), "\n")
+	if int(point.Row) >= len(lines) {
+		return ""
+	}
+	line := lines[point.Row]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// isPlainCssImport checks for the imports sass leaves to the browser, they
+// are not deprecated
+func isPlainCssImport(input []byte, statement moduleStatement) bool {
+	if strings.HasSuffix(statement.url, ".css") || strings.Contains(statement.url, "://") || strings.HasPrefix(statement.url, "//") {
+		return true
+	}
+	lines := strings.Split(string(input), "\n")
+	if int(statement.start_position.Row) >= len(lines) {
+		return false
+	}
+	before := lines[statement.start_position.Row][:max(int(statement.start_position.Column)-1, 0)]
+	return strings.HasSuffix(strings.TrimRight(before, " \t"), "url(")
+}
+
+// isModuleHeader checks that only @use, @forward, @import, comments and
+// variables come before the node, which is where @use has to be
+func isModuleHeader(node *sitter.Node) bool {
+	for sibling := node.PrevNamedSibling(); sibling != nil; sibling = sibling.PrevNamedSibling() {
+		switch sibling.Type() {
+		case "use_statement", "forward_statement", "import_statement", "comment", "single_line_comment":
+		case "declaration":
+			if sibling.NamedChildCount() == 0 || sibling.NamedChild(0).Type() != "variable_name" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (lsp *Lsp) importDeprecations(path string, tree *sitter.Tree, input []byte) []deprecation {
+	deprecations := []deprecation{}
+	imports := map[sitter.Point][]moduleStatement{}
+	for _, statement := range lsp.Modules[path] {
+		if statement.kind == "@import" {
+			imports[statement.statement_start] = append(imports[statement.statement_start], statement)
+		}
+	}
+	for _, statement := range lsp.Modules[path] {
+		if statement.kind != "@import" || isPlainCssImport(input, statement) {
+			continue
+		}
+		statement := statement
+		deprecations = append(deprecations, deprecation{
+			code:           diagnosticDeprecatedImport,
+			message:        fmt.Sprintf("@import is deprecated, load \"%s\" with @use", statement.url),
+			start_position: sitter.Point{Row: statement.start_position.Row, Column: statement.start_position.Column - 1},
+			end_position:   sitter.Point{Row: statement.end_position.Row, Column: statement.end_position.Column + 1},
+			fix: func() (string, []protocol.TextEdit, bool) {
+				node := tree.RootNode().NamedDescendantForPointRange(statement.statement_start, statement.statement_end)
+				for node != nil && node.Type() != "import_statement" {
+					node = node.Parent()
+				}
+				if node == nil {
+					return "", nil, false
+				}
+				return lsp.importFix(path, input, node, imports[statement.statement_start])
+			},
+		})
+	}
+	return deprecations
+}
+
+// importFix rewrites the whole @import statement, a nested @import can only
+// become meta.load-css
+func (lsp *Lsp) importFix(path string, input []byte, node *sitter.Node, statements []moduleStatement) (string, []protocol.TextEdit, bool) {
+	statement_range := rangeFromPoints(node.StartPoint(), node.EndPoint())
+	indent := lineIndent(input, node.StartPoint())
+	lines := []string{}
+
+	if node.Parent() == nil || node.Parent().Type() != "stylesheet" {
+		prefix, edits := lsp.useBuiltinModule(path, "meta")
+		for _, statement := range statements {
+			if isPlainCssImport(input, statement) {
+				return "", nil, false
+			}
+			lines = append(lines, fmt.Sprintf("@include %sload-css(\"%s\");", prefix, statement.url))
+		}
+		edits = append(edits, protocol.TextEdit{Range: statement_range, NewText: strings.Join(lines, "\n"+indent)})
+		return "Replace with meta.load-css", edits, true
+	}
+
+	for _, statement := range statements {
+		if isPlainCssImport(input, statement) {
+			lines = append(lines, fmt.Sprintf("@import \"%s\";", statement.url))
+		} else {
+			lines = append(lines, fmt.Sprintf("@use \"%s\" as *;", statement.url))
+		}
+	}
+	if isModuleHeader(node) {
+		return "Replace with @use", []protocol.TextEdit{{Range: statement_range, NewText: strings.Join(lines, "\n")}}, true
+	}
+	// @use can not come after other rules, it moves up with the others
+	position := lsp.useInsertPosition(path)
+	return "Replace with @use", []protocol.TextEdit{
+		{Range: protocol.Range{Start: position, End: position}, NewText: strings.Join(lines, "\n") + "\n"},
+		{Range: statementRange(input, node), NewText: ""},
+	}, true
+}
+
+// negateArgument puts a minus in front of an argument
+func negateArgument(argument string) string {
+	switch {
+	case strings.HasPrefix(argument, "-"):
+		return strings.TrimPrefix(argument, "-")
+	case strings.ContainsAny(argument, " +*/"):
+		return "-(" + argument + ")"
+	}
+	return "-" + argument
+}
+
+// migrate fills the replacement of the global function with the arguments
+// of a call, false when the call can not be rewritten like that
+func (global builtinGlobal) migrate(prefix string, arguments []string) (string, bool) {
+	replacement := prefix + strings.TrimPrefix(global.replacement, global.module+".")
+	if strings.Contains(replacement, "{args}") {
+		return strings.Replace(replacement, "{args}", strings.Join(arguments, ", "), 1), true
+	}
+	if strings.Contains(replacement, fmt.Sprintf("{%d}", len(arguments)+1)) {
+		return "", false
+	}
+	for idx, argument := range arguments {
+		placeholder := fmt.Sprintf("{%d}", idx+1)
+		if !strings.Contains(replacement, placeholder) || keywordArgumentRegex.MatchString(argument) || strings.HasSuffix(argument, "...") {
+			return "", false
+		}
+		replacement = strings.Replace(replacement, "-"+placeholder, negateArgument(argument), 1)
+		replacement = strings.Replace(replacement, placeholder, argument, 1)
+	}
+	return replacement, true
+}
+
+// isCssFilterCall checks for grayscale(50%) and the like, they are css and
+// not the color functions
+func isCssFilterCall(name string, arguments []string, call_node *sitter.Node, input []byte) bool {
+	if !cssFilterFunctions[name] {
+		return false
+	}
+	if len(arguments) == 1 && arguments[0] != "" && strings.ContainsRune("0123456789.", rune(arguments[0][0])) {
+		return true
+	}
+	for ancestor := call_node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		if ancestor.Type() == "declaration" {
+			property := ancestor.NamedChild(0)
+			return property != nil && property.Type() == "property_name" &&
+				strings.HasSuffix(property.Content(input), "filter")
+		}
+	}
+	return false
+}
+
+func (lsp *Lsp) globalFunctionDeprecations(path string, tree *sitter.Tree, input []byte) []deprecation {
+	deprecations := []deprecation{}
+	for _, entry := range lsp.Calls[path] {
+		if entry.item_type != itemTypeFunction || cssGlobalFunctions[entry.name] {
+			continue
+		}
+		global, ok := lookupBuiltinGlobal(entry.name)
+		// a function of the workspace with the same name wins
+		if !ok || len(lsp.definitionsOfType(entry.name, itemTypeFunction)) > 0 {
+			continue
+		}
+		node := tree.RootNode().NamedDescendantForPointRange(entry.start_position, entry.end_position)
+		if node == nil || node.Parent() == nil || node.Parent().Type() != "call_expression" {
+			continue
+		}
+		call_node := node.Parent()
+		arguments := splitParameters(call_node.Content(input))
+		if isCssFilterCall(entry.name, arguments, call_node, input) {
+			continue
+		}
+		// calls like saturate($color) that sass doesnt know either
+		if strings.Contains(global.replacement, fmt.Sprintf("{%d}", len(arguments)+1)) {
+			continue
+		}
+		deprecations = append(deprecations, deprecation{
+			code:           diagnosticDeprecatedGlobal,
+			message:        fmt.Sprintf("%s() is deprecated, use %s from sass:%s", entry.name, global.replacementName(), global.module),
+			start_position: entry.start_position,
+			end_position:   entry.end_position,
+			fix: func() (string, []protocol.TextEdit, bool) {
+				prefix, edits := lsp.useBuiltinModule(path, global.module)
+				replacement, ok := global.migrate(prefix, arguments)
+				if !ok {
+					return "", nil, false
+				}
+				edits = append(edits, protocol.TextEdit{
+					Range:   rangeFromPoints(call_node.StartPoint(), call_node.EndPoint()),
+					NewText: replacement,
+				})
+				return fmt.Sprintf("Replace with %s", prefix+strings.TrimPrefix(global.replacementName(), global.module+".")), edits, true
+			},
+		})
+	}
+	return deprecations
+}
+
+func isSlashExpression(node *sitter.Node) bool {
+	return node != nil && node.Type() == "binary_expression" && node.ChildCount() == 3 && node.Child(1).Type() == "/"
+}
+
+// isSassDivision follows the rules of sass for when a / divides, with only
+// numbers outside of parentheses it separates like in css
+func isSassDivision(node *sitter.Node, input []byte) bool {
+	for ancestor := node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		if ancestor.Type() == "call_expression" {
+			if name := ancestor.NamedChild(0); name != nil && name.Type() == "function_name" && cssSlashFunctions[name.Content(input)] {
+				return false
+			}
+		}
+	}
+	for _, operand := range []*sitter.Node{node.Child(0), node.Child(2)} {
+		switch operand.Type() {
+		case "integer_value", "float_value":
+		default:
+			return true
+		}
+	}
+	for ancestor := node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		switch ancestor.Type() {
+		case "binary_expression", "parenthesized_value", "return_statement":
+			return true
+		case "declaration":
+			name := ancestor.NamedChild(0)
+			return name != nil && name.Type() == "variable_name"
+		}
+	}
+	return false
+}
+
+// divisionCall turns the divisions of the expression into math.div, lead
+// goes in front of the leftmost operand
+func divisionCall(node *sitter.Node, input []byte, lead string, prefix string) string {
+	if !isSlashExpression(node) {
+		return lead + node.Content(input)
+	}
+	left := divisionCall(node.Child(0), input, lead, prefix)
+	right := divisionCall(node.Child(2), input, "", prefix)
+	return prefix + "div(" + left + ", " + right + ")"
+}
+
+// gluedCall is the name of a namespaced call the grammar split off from its
+// arguments, math.percentage(...) becomes a plain_value and the parens
+func gluedCall(node *sitter.Node) *sitter.Node {
+	previous := node.PrevSibling()
+	if previous != nil && previous.EndByte() == node.StartByte() && previous.Type() == "plain_value" {
+		return previous
+	}
+	return nil
+}
+
+func (lsp *Lsp) divisionDeprecations(path string, tree *sitter.Tree, input []byte) []deprecation {
+	deprecations := []deprecation{}
+	var walk func(node *sitter.Node)
+	walk = func(node *sitter.Node) {
+		for idx := 0; idx < int(node.NamedChildCount()); idx++ {
+			walk(node.NamedChild(idx))
+		}
+		// a division in a division is fixed with the outer one
+		if !isSlashExpression(node) || isSlashExpression(node.Parent()) || !isSassDivision(node, input) {
+			return
+		}
+		deprecations = append(deprecations, deprecation{
+			code:           diagnosticDeprecatedDivision,
+			message:        "using / for division is deprecated, use math.div",
+			start_position: node.StartPoint(),
+			end_position:   node.EndPoint(),
+			fix: func() (string, []protocol.TextEdit, bool) {
+				prefix, edits := lsp.useBuiltinModule(path, "math")
+				replaced := node
+				lead := ""
+				if glued := gluedCall(node); glued != nil {
+					lead = glued.Content(input)
+					replaced = glued
+				}
+				end := node.EndPoint()
+				// (a / b) doesnt need the parentheses anymore
+				if parent := node.Parent(); lead == "" && parent.Type() == "parenthesized_value" && parent.NamedChildCount() == 1 && gluedCall(parent) == nil {
+					replaced = parent
+					end = parent.EndPoint()
+				}
+				edits = append(edits, protocol.TextEdit{
+					Range:   rangeFromPoints(replaced.StartPoint(), end),
+					NewText: divisionCall(node, input, lead, prefix),
+				})
+				return fmt.Sprintf("Replace with %sdiv", prefix), edits, true
+			},
+		})
+	}
+	walk(tree.RootNode())
+	return deprecations
+}
+
+// globalScopeFiles are the files that share the global variables with the
+// file, through @import in either direction
+func (lsp *Lsp) globalScopeFiles(path string) map[string]bool {
+	files := map[string]bool{}
+	add := func(from string) {
+		for _, module := range lsp.VisibleModules(from) {
+			if !module.is_module {
+				files[module.path] = true
+			}
+		}
+	}
+	add(path)
+	for _, other := range sortedPaths(lsp.Modules) {
+		if other == path || files[other] {
+			continue
+		}
+		for _, module := range lsp.VisibleModules(other) {
+			if module.path == path && !module.is_module {
+				add(other)
+				break
+			}
+		}
+	}
+	return files
+}
+
+func (lsp *Lsp) newGlobalDeprecations(path string, tree *sitter.Tree, input []byte) []deprecation {
+	deprecations := []deprecation{}
+	// the first !global of every variable, the fix declares it before that
+	first := map[string]*sitter.Node{}
+	assignments := []isDefined{}
+	for _, entry := range lsp.Variables[path] {
+		node := declarationOf(tree, entry)
+		if node == nil || node.Parent() == nil || node.Parent().Type() == "stylesheet" || !strings.Contains(node.Content(input), "!global") {
+			continue
+		}
+		if first[entry.name] == nil {
+			first[entry.name] = node
+		}
+		assignments = append(assignments, entry)
+	}
+	if len(assignments) == 0 {
+		return deprecations
+	}
+
+	declared := map[string]bool{}
+	for file := range lsp.globalScopeFiles(path) {
+		if lsp.Trees[file] == nil {
+			continue
+		}
+		for _, entry := range lsp.Variables[file] {
+			if node := declarationOf(lsp.Trees[file], entry); node != nil && node.Parent() != nil && node.Parent().Type() == "stylesheet" {
+				declared[entry.name] = true
+			}
+		}
+	}
+	for _, entry := range assignments {
+		if declared[entry.name] {
+			continue
+		}
+		name := entry.name
+		statement := topLevelStatement(first[name])
+		deprecations = append(deprecations, deprecation{
+			code:           diagnosticDeprecatedNewGlobal,
+			message:        fmt.Sprintf("declaring the new variable %s with !global is deprecated, declare it at the top level first", name),
+			start_position: entry.start_position,
+			end_position:   entry.end_position,
+			fix: func() (string, []protocol.TextEdit, bool) {
+				position := protocol.Position{Line: statement.StartPoint().Row}
+				return fmt.Sprintf("Declare %s at the top level", name), []protocol.TextEdit{{
+					Range:   protocol.Range{Start: position, End: position},
+					NewText: name + ": null;\n",
+				}}, true
+			},
+		})
+	}
+	return deprecations
+}
@@ -0,0 +1,175 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+const deprecationSource = `@use "sass:math";
+@import "colors", "print.css";
+.a {
+  $local: 1 !global;
+  $primary: blue !global;
+  color: darken($primary, 10%);
+  filter: grayscale(50%);
+  width: $size / 2 / 3;
+  height: (10px / 2);
+  grid-row: 1 / 3;
+  top: calc(100% / 3);
+  @import "nested";
+}
+`
+
+func deprecationsOf(lsp *Lsp, path string) map[string][]protocol.Diagnostic {
+	found := map[string][]protocol.Diagnostic{}
+	for _, diagnostic := range lsp.getDiagnostics(path) {
+		code := diagnosticCode(diagnostic)
+		if _, ok := deprecationLabels[code]; ok {
+			found[code] = append(found[code], diagnostic)
+		}
+	}
+	return found
+}
+
+func fixFor(t *testing.T, lsp *Lsp, path string, diagnostic protocol.Diagnostic) protocol.CodeAction {
+	t.Helper()
+	actions := lsp.GetCodeActions(path, protocol.CodeActionContext{Diagnostics: []protocol.Diagnostic{diagnostic}})
+	if len(actions) == 0 {
+		t.Fatalf("no fix for %s at %+v", diagnostic.Message, diagnostic.Range)
+	}
+	return actions[0]
+}
+
+func editTexts(action protocol.CodeAction, path string) []string {
+	texts := []string{}
+	for _, edit := range action.Edit.Changes[uri.File(path)] {
+		texts = append(texts, edit.NewText)
+	}
+	return texts
+}
+
+func TestDeprecationDiagnostics(t *testing.T) {
+	path := "/virtual/styles/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		path:                           deprecationSource,
+		"/virtual/styles/_colors.scss": "$primary: red;\n$size: 10px;\n",
+	})
+	found := deprecationsOf(lsp, path)
+
+	imports := found[diagnosticDeprecatedImport]
+	if len(imports) != 2 || imports[0].Range.Start != (protocol.Position{Line: 1, Character: 8}) || imports[1].Range.Start.Line != 11 {
+		t.Fatalf("unexpected @import warnings %+v", imports)
+	}
+	if imports[0].Severity != protocol.DiagnosticSeverityWarning || len(imports[0].Tags) != 1 {
+		t.Fatalf("deprecations should be tagged warnings %+v", imports[0])
+	}
+	globals := found[diagnosticDeprecatedGlobal]
+	if len(globals) != 1 || globals[0].Range.Start.Line != 5 || !strings.Contains(globals[0].Message, "color.adjust") {
+		t.Fatalf("unexpected global function warnings %+v", globals)
+	}
+	divisions := found[diagnosticDeprecatedDivision]
+	if len(divisions) != 2 || divisions[0].Range.Start.Line != 7 || divisions[1].Range.Start.Line != 8 {
+		t.Fatalf("unexpected division warnings %+v", divisions)
+	}
+	// $primary already exists in the imported file
+	new_globals := found[diagnosticDeprecatedNewGlobal]
+	if len(new_globals) != 1 || new_globals[0].Range.Start != (protocol.Position{Line: 3, Character: 2}) {
+		t.Fatalf("unexpected !global warnings %+v", new_globals)
+	}
+}
+
+func TestDeprecationQuickFixes(t *testing.T) {
+	path := "/virtual/styles/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		path:                           deprecationSource,
+		"/virtual/styles/_colors.scss": "$primary: red;\n$size: 10px;\n",
+	})
+	found := deprecationsOf(lsp, path)
+
+	imports := found[diagnosticDeprecatedImport]
+	top := fixFor(t, lsp, path, imports[0])
+	if texts := editTexts(top, path); len(texts) != 1 || texts[0] != "@use \"colors\" as *;\n@import \"print.css\";" {
+		t.Fatalf("unexpected @import fix %q", texts)
+	}
+	nested := fixFor(t, lsp, path, imports[1])
+	if texts := editTexts(nested, path); len(texts) != 2 || texts[0] != "@use \"sass:meta\";\n" || texts[1] != "@include meta.load-css(\"nested\");" {
+		t.Fatalf("unexpected nested @import fix %q", texts)
+	}
+
+	global := fixFor(t, lsp, path, found[diagnosticDeprecatedGlobal][0])
+	if texts := editTexts(global, path); len(texts) != 2 || texts[0] != "@use \"sass:color\";\n" || texts[1] != "color.adjust($primary, $lightness: -10%)" {
+		t.Fatalf("unexpected global function fix %q", texts)
+	}
+	if edit := global.Edit.Changes[uri.File(path)][0]; edit.Range.Start != (protocol.Position{Line: 1}) {
+		t.Fatalf("the @use should go after the other @use %+v", edit)
+	}
+
+	// sass:math is already there
+	division := fixFor(t, lsp, path, found[diagnosticDeprecatedDivision][0])
+	if texts := editTexts(division, path); len(texts) != 1 || texts[0] != "math.div(math.div($size, 2), 3)" {
+		t.Fatalf("unexpected division fix %q", texts)
+	}
+	parenthesized := fixFor(t, lsp, path, found[diagnosticDeprecatedDivision][1])
+	edit := parenthesized.Edit.Changes[uri.File(path)][0]
+	if edit.NewText != "math.div(10px, 2)" || edit.Range.Start.Character != 10 || edit.Range.End.Character != 20 {
+		t.Fatalf("unexpected division fix %+v", edit)
+	}
+
+	new_global := fixFor(t, lsp, path, found[diagnosticDeprecatedNewGlobal][0])
+	edit = new_global.Edit.Changes[uri.File(path)][0]
+	if edit.NewText != "$local: null;\n" || edit.Range.Start != (protocol.Position{Line: 2}) {
+		t.Fatalf("unexpected !global fix %+v", edit)
+	}
+}
+
+func TestMigrateAllDeprecations(t *testing.T) {
+	path := "/virtual/legacy.scss"
+	lsp := lspWithSources(t, map[string]string{
+		path: "@use \"theme\" as color;\n.a {\n  color: lighten($c, 5%);\n  background: darken(lighten($c, 5%), $amount);\n  opacity: opacity($c);\n}\n",
+	})
+	globals := deprecationsOf(lsp, path)[diagnosticDeprecatedGlobal]
+	if len(globals) != 4 {
+		t.Fatalf("expected 4 global function warnings, got %+v", globals)
+	}
+	actions := lsp.GetCodeActions(path, protocol.CodeActionContext{Diagnostics: globals[:1]})
+	titles := actionTitles(actions)
+	if strings.Join(titles, "|") != "Replace with color2.adjust|Migrate all global functions in the file|Disable deprecated-global-function for this line|Disable deprecated-global-function for the file" {
+		t.Fatalf("unexpected actions %q", titles)
+	}
+	// the @use is added once and the nested lighten waits for the next run
+	texts := editTexts(actions[1], path)
+	expected := []string{
+		"@use \"sass:color\" as color2;\n",
+		"color2.adjust($c, $lightness: 5%)",
+		"color2.adjust(lighten($c, 5%), $lightness: -$amount)",
+		"color2.alpha($c)",
+	}
+	if strings.Join(texts, "|") != strings.Join(expected, "|") {
+		t.Fatalf("unexpected edits %q", texts)
+	}
+}
+
+func TestMigrateGlobalFunction(t *testing.T) {
+	getBuiltins()
+	cases := []struct {
+		name      string
+		prefix    string
+		arguments []string
+		expected  string
+	}{
+		{"darken", "color.", []string{"$c", "-5%"}, "color.adjust($c, $lightness: 5%)"},
+		{"darken", "", []string{"$c", "$a * 2"}, "adjust($c, $lightness: -($a * 2))"},
+		{"map-get", "map.", []string{"$map", "a", "b"}, "map.get($map, a, b)"},
+		{"darken", "color.", []string{"$color: red", "$amount: 5%"}, ""},
+		{"darken", "color.", []string{"$c"}, ""},
+	}
+	for _, c := range cases {
+		migrated, ok := builtinGlobals[c.name].migrate(c.prefix, c.arguments)
+		if ok != (c.expected != "") || migrated != c.expected {
+			t.Errorf("%s%v: expected %q, got %q", c.name, c.arguments, c.expected, migrated)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// ApplyEdits makes the edits to the text, edits at the same position go in
+// the order they are in and an edit that overlaps an earlier one is dropped
+func ApplyEdits(input []byte, edits []protocol.TextEdit) []byte {
+	type offsetEdit struct {
+		start int
+		end   int
+		text  string
+	}
+	offset_edits := []offsetEdit{}
+	for _, edit := range edits {
+		offset_edits = append(offset_edits, offsetEdit{
+			start: offsetOfPosition(input, edit.Range.Start),
+			end:   offsetOfPosition(input, edit.Range.End),
+			text:  edit.NewText,
+		})
+	}
+	sort.SliceStable(offset_edits, func(i, j int) bool {
+		return offset_edits[i].start < offset_edits[j].start
+	})
+	var sb strings.Builder
+	last := 0
+	for _, edit := range offset_edits {
+		if edit.start < last {
+			continue
+		}
+		sb.Write(input[last:edit.start])
+		sb.WriteString(edit.text)
+		last = edit.end
+	}
+	sb.Write(input[last:])
+	return []byte(sb.String())
+}
+
+// EditDiff shows the edits as a unified diff without context, patch can
+// apply it
+func EditDiff(path string, input []byte, edits []protocol.TextEdit) string {
+	lines := strings.SplitAfter(string(input), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	sorted := append([]protocol.TextEdit{}, edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Range.Start.Line < sorted[j].Range.Start.Line
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", path, path)
+	delta := 0
+	for idx := 0; idx < len(sorted); {
+		// the edits that touch the same lines make one hunk
+		first := int(sorted[idx].Range.Start.Line)
+		last := int(sorted[idx].Range.End.Line)
+		hunk := []protocol.TextEdit{}
+		for ; idx < len(sorted) && int(sorted[idx].Range.Start.Line) <= last; idx++ {
+			last = max(last, int(sorted[idx].Range.End.Line))
+			hunk = append(hunk, sorted[idx])
+		}
+		old_lines := lines[min(first, len(lines)):min(last+1, len(lines))]
+		for edit := range hunk {
+			hunk[edit].Range.Start.Line -= uint32(first)
+			hunk[edit].Range.End.Line -= uint32(first)
+		}
+		new_text := string(ApplyEdits([]byte(strings.Join(old_lines, "")), hunk))
+		new_lines := strings.SplitAfter(new_text, "\n")
+		if new_lines[len(new_lines)-1] == "" {
+			new_lines = new_lines[:len(new_lines)-1]
+		}
+
+		old_start, new_start := first+1, first+1+delta
+		if len(old_lines) == 0 {
+			old_start--
+		}
+		if len(new_lines) == 0 {
+			new_start--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", old_start, len(old_lines), new_start, len(new_lines))
+		for _, line := range old_lines {
+			sb.WriteString("-" + strings.TrimSuffix(line, "\n") + "\n")
+		}
+		for _, line := range new_lines {
+			sb.WriteString("+" + strings.TrimSuffix(line, "\n") + "\n")
+		}
+		delta += len(new_lines) - len(old_lines)
+	}
+	return sb.String()
+}
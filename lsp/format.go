@@ -0,0 +1,606 @@
+package lsp
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+type FormatConfig struct {
+	// when set these win over the tab size and spaces sent by the client
+	IndentSize int   `json:"indentSize"`
+	UseTabs    *bool `json:"useTabs"`
+	// "double", "single", anything else leaves the quotes alone
+	Quote                 string `json:"quote"`
+	BlankLineBetweenRules bool   `json:"blankLineBetweenRules"`
+	SelectorPerLine       bool   `json:"selectorPerLine"`
+	SpaceBeforeColon      bool   `json:"spaceBeforeColon"`
+	SpaceAfterColon       bool   `json:"spaceAfterColon"`
+	TrailingSemicolon     bool   `json:"trailingSemicolon"`
+}
+
+func DefaultFormatConfig() FormatConfig {
+	return FormatConfig{
+		Quote:                 "double",
+		BlankLineBetweenRules: true,
+		SelectorPerLine:       true,
+		SpaceBeforeColon:      false,
+		SpaceAfterColon:       true,
+		TrailingSemicolon:     true,
+	}
+}
+
+// statements that get a blank line around them
+var blockStatementTypes = []string{
+	"rule_set",
+	"placeholder",
+	"mixin_statement",
+	"function_statement",
+	"media_statement",
+	"supports_statement",
+	"keyframes_statement",
+	"if_statement",
+	"each_statement",
+	"for_statement",
+	"while_statement",
+	"at_root_statement",
+}
+
+type formatter struct {
+	input  []byte
+	config FormatConfig
+	indent string
+}
+
+func newFormatter(input []byte, config FormatConfig, options protocol.FormattingOptions) *formatter {
+	indent_size := int(options.TabSize)
+	use_tabs := !options.InsertSpaces
+	if config.IndentSize > 0 {
+		indent_size = config.IndentSize
+	}
+	if config.UseTabs != nil {
+		use_tabs = *config.UseTabs
+	}
+	if indent_size <= 0 {
+		indent_size = 2
+	}
+	indent := strings.Repeat(" ", indent_size)
+	if use_tabs {
+		indent = "\t"
+	}
+	return &formatter{input: input, config: config, indent: indent}
+}
+
+func isComment(node *sitter.Node) bool {
+	return node.Type() == "comment" || node.Type() == "single_line_comment"
+}
+
+func hasChildOfType(node *sitter.Node, child_type string) bool {
+	for i := 0; i < int(node.ChildCount()); i++ {
+		if node.Child(i).Type() == child_type {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlockStatement(node *sitter.Node) bool {
+	for _, block_type := range blockStatementTypes {
+		if node.Type() == block_type {
+			return true
+		}
+	}
+	// @include with @content and unknown at-rules with a body
+	return (node.Type() == "include_statement" || node.Type() == "at_rule") && hasChildOfType(node, "block")
+}
+
+func (f *formatter) content(node *sitter.Node) string {
+	return node.Content(f.input)
+}
+
+func (f *formatter) text(first *sitter.Node, last *sitter.Node) string {
+	return string(f.input[first.StartByte():last.EndByte()])
+}
+
+// convertQuotes swaps the quotes of a string literal if that doesnt need any
+// escaping
+func (f *formatter) convertQuotes(literal string) string {
+	quote := byte(0)
+	switch f.config.Quote {
+	case "double":
+		quote = '"'
+	case "single":
+		quote = '\''
+	}
+	if quote == 0 || len(literal) < 2 || literal[0] == quote {
+		return literal
+	}
+	inner := literal[1 : len(literal)-1]
+	if strings.IndexByte(inner, quote) != -1 || strings.IndexByte(inner, '\\') != -1 {
+		return literal
+	}
+	return string(quote) + inner + string(quote)
+}
+
+// unquotedUrlEnd is the index of the ) that closes the url( at the start of
+// text, -1 if the url is quoted or not closed. Everything in between is the
+// url, like data:image/png;base64,AAAA, and has to be kept as it is.
+func unquotedUrlEnd(text string) int {
+	if len(text) < 4 || !strings.EqualFold(text[:4], "url(") {
+		return -1
+	}
+	if inner := strings.TrimLeft(text[4:], " \t\n\r"); strings.HasPrefix(inner, "\"") || strings.HasPrefix(inner, "'") {
+		return -1
+	}
+	depth := 0
+	for i := 3; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// normalizeInline collapses the whitespace of something that goes on a single
+// line, strings, unquoted urls and comments are left alone. With colons the spacing around
+// the colons of keyword arguments, maps and media features is fixed too
+func (f *formatter) normalizeInline(text string, colons bool) string {
+	text = strings.TrimSpace(text)
+	// a line comment in the middle would comment out the rest of the line
+	if idx := strings.Index(text, "//"); idx != -1 && strings.Contains(text[idx:], "\n") {
+		return text
+	}
+	var sb strings.Builder
+	pending_space := false
+	// right after a colon that already got its space
+	skip_space := false
+	write_space := func() {
+		if pending_space && sb.Len() > 0 {
+			last := sb.String()[sb.Len()-1]
+			if last != '(' && last != '[' {
+				sb.WriteByte(' ')
+			}
+		}
+		pending_space = false
+	}
+	for i := 0; i < len(text); i++ {
+		char := text[i]
+		if char != ' ' && char != '\t' && char != '\n' && char != '\r' {
+			skip_space = false
+		}
+		switch {
+		case char == '"' || char == '\'':
+			end := i + 1
+			for end < len(text) && text[end] != char {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				end = len(text) - 1
+			}
+			write_space()
+			sb.WriteString(f.convertQuotes(text[i : end+1]))
+			i = end
+		case (i == 0 || !isWordChar(text[i-1])) && unquotedUrlEnd(text[i:]) != -1:
+			end := i + unquotedUrlEnd(text[i:])
+			write_space()
+			sb.WriteString(text[i : end+1])
+			i = end
+		case strings.HasPrefix(text[i:], "/*"):
+			end := strings.Index(text[i+2:], "*/")
+			if end == -1 {
+				end = len(text)
+			} else {
+				end = i + 2 + end + 2
+			}
+			write_space()
+			sb.WriteString(text[i:end])
+			i = end - 1
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			pending_space = !skip_space
+		case char == ':' && colons && i+1 < len(text) && text[i+1] != '/' && text[i+1] != ':' &&
+			!(pending_space && text[i+1] != ' '):
+			// the second part leaves things like `@page :first` alone
+			pending_space = false
+			if f.config.SpaceBeforeColon {
+				sb.WriteByte(' ')
+			}
+			sb.WriteByte(':')
+			if f.config.SpaceAfterColon {
+				sb.WriteByte(' ')
+			}
+			skip_space = true
+			continue
+		case char == ',':
+			sb.WriteByte(',')
+			pending_space = true
+		case char == ')' || char == ']' || char == ';':
+			pending_space = false
+			sb.WriteByte(char)
+		default:
+			write_space()
+			sb.WriteByte(char)
+		}
+	}
+	return sb.String()
+}
+
+// normalizeSelector puts spaces around combinators on top of normalizeInline
+func (f *formatter) normalizeSelector(text string) string {
+	text = f.normalizeInline(text, false)
+	var sb strings.Builder
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		char := text[i]
+		switch char {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case '"', '\'':
+			end := strings.IndexByte(text[i+1:], char)
+			if end != -1 {
+				sb.WriteString(text[i : i+end+2])
+				i += end + 1
+				continue
+			}
+		case '>', '+', '~':
+			if depth == 0 {
+				result := strings.TrimRight(sb.String(), " ")
+				sb.Reset()
+				sb.WriteString(result)
+				if sb.Len() > 0 {
+					sb.WriteByte(' ')
+				}
+				sb.WriteByte(char)
+				sb.WriteByte(' ')
+				for i+1 < len(text) && text[i+1] == ' ' {
+					i++
+				}
+				continue
+			}
+		}
+		sb.WriteByte(char)
+	}
+	return sb.String()
+}
+
+func (f *formatter) indentation(depth int) string {
+	return strings.Repeat(f.indent, depth)
+}
+
+func (f *formatter) semicolon(text string) string {
+	if strings.HasSuffix(text, ";") || !f.config.TrailingSemicolon {
+		return text
+	}
+	return text + ";"
+}
+
+func (f *formatter) selectors(node *sitter.Node, depth int) string {
+	items := []string{}
+	for _, item := range listItems(node) {
+		items = append(items, f.normalizeSelector(f.content(item)))
+	}
+	separator := ", "
+	if f.config.SelectorPerLine {
+		separator = ",\n" + f.indentation(depth)
+	}
+	return strings.Join(items, separator)
+}
+
+func (f *formatter) block(node *sitter.Node, depth int) string {
+	statements := []*sitter.Node{}
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		statements = append(statements, node.NamedChild(i))
+	}
+	if len(statements) == 0 {
+		return "{}"
+	}
+	inner := f.indentation(depth+1) + f.statementList(statements, depth+1)
+	return "{\n" + inner + "\n" + f.indentation(depth) + "}"
+}
+
+func (f *formatter) declaration(node *sitter.Node, depth int) string {
+	name := f.content(node.NamedChild(0))
+	values := []*sitter.Node{}
+	var nested *sitter.Node
+	has_semicolon := false
+	after_colon := false
+	for i := 0; i < int(node.ChildCount()); i++ {
+		child := node.Child(i)
+		switch {
+		case child.Type() == ":" && !after_colon:
+			after_colon = true
+		case !after_colon:
+		case child.Type() == ";":
+			has_semicolon = true
+		case child.Type() == "block":
+			nested = child
+		default:
+			values = append(values, child)
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString(name)
+	if f.config.SpaceBeforeColon {
+		sb.WriteByte(' ')
+	}
+	sb.WriteByte(':')
+	if len(values) > 0 {
+		if f.config.SpaceAfterColon {
+			sb.WriteByte(' ')
+		}
+		value := f.text(values[0], values[len(values)-1])
+		has_map := false
+		for _, value_node := range values {
+			walkNamed(value_node, func(node *sitter.Node) {
+				has_map = has_map || node.Type() == "map"
+			})
+		}
+		// maps are usually written one entry per line on purpose
+		if has_map {
+			sb.WriteString(strings.TrimSpace(value))
+		} else {
+			sb.WriteString(f.normalizeInline(value, true))
+		}
+	}
+	if nested != nil {
+		sb.WriteString(" ")
+		sb.WriteString(f.block(nested, depth))
+		return sb.String()
+	}
+	if has_semicolon {
+		sb.WriteByte(';')
+		return sb.String()
+	}
+	return f.semicolon(sb.String())
+}
+
+// generic prints everything before a block on one line, then the block, this
+// covers most at-rules
+func (f *formatter) generic(node *sitter.Node, depth int) string {
+	var sb strings.Builder
+	header := []*sitter.Node{}
+	flush := func() {
+		if len(header) == 0 {
+			return
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(f.normalizeInline(f.text(header[0], header[len(header)-1]), true))
+		header = header[:0]
+	}
+	for i := 0; i < int(node.ChildCount()); i++ {
+		child := node.Child(i)
+		switch child.Type() {
+		case "block":
+			flush()
+			sb.WriteByte(' ')
+			sb.WriteString(f.block(child, depth))
+		case "keyframe_block_list":
+			flush()
+			sb.WriteByte(' ')
+			sb.WriteString(f.block(child, depth))
+		case "if_clause", "else_if_clause", "else_clause", "keyframe_block":
+			flush()
+			if sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(f.generic(child, depth))
+		case ";":
+			flush()
+			sb.WriteByte(';')
+		default:
+			header = append(header, child)
+		}
+	}
+	flush()
+	return sb.String()
+}
+
+func (f *formatter) statement(node *sitter.Node, depth int) string {
+	if node.IsError() || node.HasError() {
+		// dont touch what the parser didnt understand
+		return strings.TrimSpace(f.content(node))
+	}
+	switch node.Type() {
+	case "comment", "single_line_comment":
+		return strings.TrimRight(f.content(node), " \t\r\n")
+	case "declaration":
+		return f.declaration(node, depth)
+	case "rule_set":
+		return f.selectors(node.NamedChild(0), depth) + " " + f.block(node.NamedChild(1), depth)
+	case "placeholder":
+		return "%" + f.content(node.NamedChild(0)) + " " + f.block(node.NamedChild(1), depth)
+	}
+	text := f.generic(node, depth)
+	if isBlockStatement(node) || strings.HasSuffix(text, "}") {
+		return text
+	}
+	return f.semicolon(text)
+}
+
+// isBareAtKeyword checks for an at-rule the parser only got the keyword of,
+// like @at-root .x {} and @page :first {}, the rule set after it is its
+// prelude and block
+func (f *formatter) isBareAtKeyword(node *sitter.Node) bool {
+	if node == nil || !node.IsError() {
+		return false
+	}
+	text := strings.TrimSpace(f.content(node))
+	return strings.HasPrefix(text, "@") && !strings.ContainsAny(text, " \t\n\r;{}")
+}
+
+// statementList prints the statements at the same depth, the first one is not
+// indented so it can be used to replace a range that starts at the statement
+func (f *formatter) statementList(statements []*sitter.Node, depth int) string {
+	var sb strings.Builder
+	var previous *sitter.Node
+	// the statement before the trailing comments of previous
+	var previous_statement *sitter.Node
+	for _, node := range statements {
+		if previous != nil {
+			if f.isBareAtKeyword(previous) && node.Type() == "rule_set" {
+				// the prelude stays on the line of the keyword
+				sb.WriteByte(' ')
+				sb.WriteString(f.statement(node, depth))
+				previous = node
+				previous_statement = node
+				continue
+			}
+			if isComment(node) && node.StartPoint().Row == previous.EndPoint().Row {
+				// trailing comment, stays on the line
+				sb.WriteByte(' ')
+				sb.WriteString(f.statement(node, depth))
+				previous = node
+				continue
+			}
+			blank_line := node.StartPoint().Row > previous.EndPoint().Row+1
+			if f.config.BlankLineBetweenRules && (isBlockStatement(node) || isBlockStatement(previous_statement)) {
+				// comments right above a rule belong to it
+				attached := isComment(previous_statement) && previous.EndPoint().Row+1 == node.StartPoint().Row
+				blank_line = blank_line || !attached
+			}
+			sb.WriteByte('\n')
+			if blank_line {
+				sb.WriteByte('\n')
+			}
+			sb.WriteString(f.indentation(depth))
+		}
+		sb.WriteString(f.statement(node, depth))
+		previous = node
+		previous_statement = node
+	}
+	return sb.String()
+}
+
+func (lsp *Lsp) formatConfig() FormatConfig {
+	if lsp.Config == nil {
+		return DefaultFormatConfig()
+	}
+	return lsp.Config.Format
+}
+
+func endOfInput(input []byte) sitter.Point {
+	return pointAtOffset(sitter.Point{}, string(input), len(input))
+}
+
+func (lsp *Lsp) FormatDocument(path string, options protocol.FormattingOptions) []protocol.TextEdit {
+	edits := []protocol.TextEdit{}
+	tree := lsp.Trees[path]
+	if tree == nil {
+		return edits
+	}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return edits
+	}
+	f := newFormatter(*input, lsp.formatConfig(), options)
+	root := tree.RootNode()
+	statements := []*sitter.Node{}
+	for i := 0; i < int(root.NamedChildCount()); i++ {
+		statements = append(statements, root.NamedChild(i))
+	}
+	formatted := f.statementList(statements, 0)
+	if formatted != "" {
+		formatted += "\n"
+	}
+	if formatted == string(*input) {
+		return edits
+	}
+	return append(edits, protocol.TextEdit{
+		Range:   rangeFromPoints(sitter.Point{}, endOfInput(*input)),
+		NewText: formatted,
+	})
+}
+
+// statementDepth counts the blocks around a statement
+func statementDepth(node *sitter.Node) int {
+	depth := 0
+	for ancestor := node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		if ancestor.Type() == "block" || ancestor.Type() == "keyframe_block_list" {
+			depth++
+		}
+	}
+	return depth
+}
+
+func pointBefore(a sitter.Point, b sitter.Point) bool {
+	return a.Row < b.Row || (a.Row == b.Row && a.Column < b.Column)
+}
+
+func (lsp *Lsp) FormatRange(path string, start sitter.Point, end sitter.Point, options protocol.FormattingOptions) []protocol.TextEdit {
+	edits := []protocol.TextEdit{}
+	tree := lsp.Trees[path]
+	if tree == nil {
+		return edits
+	}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return edits
+	}
+
+	// the statements of the innermost block that contains the whole range
+	container := tree.RootNode().NamedDescendantForPointRange(start, end)
+	for container != nil && container.Type() != "block" && container.Type() != "stylesheet" {
+		container = container.Parent()
+	}
+	if container == nil {
+		return edits
+	}
+	statements := []*sitter.Node{}
+	for i := 0; i < int(container.NamedChildCount()); i++ {
+		child := container.NamedChild(i)
+		if pointBefore(child.EndPoint(), start) || pointBefore(end, child.StartPoint()) {
+			continue
+		}
+		statements = append(statements, child)
+	}
+	if len(statements) == 0 {
+		return edits
+	}
+
+	f := newFormatter(*input, lsp.formatConfig(), options)
+	first := statements[0]
+	last := statements[len(statements)-1]
+	formatted := f.statementList(statements, statementDepth(first))
+	if formatted == f.text(first, last) {
+		return edits
+	}
+	return append(edits, protocol.TextEdit{
+		Range:   rangeFromPoints(first.StartPoint(), last.EndPoint()),
+		NewText: formatted,
+	})
+}
+
+// FormatOnType formats the statement that was just finished with a ; or }
+func (lsp *Lsp) FormatOnType(path string, position sitter.Point, options protocol.FormattingOptions) []protocol.TextEdit {
+	tree := lsp.Trees[path]
+	if tree == nil || position.Column == 0 {
+		return []protocol.TextEdit{}
+	}
+	typed := sitter.Point{Row: position.Row, Column: position.Column - 1}
+	node := tree.RootNode().NamedDescendantForPointRange(typed, typed)
+	// go up to the statement the character belongs to
+	for node != nil && node.Parent() != nil {
+		parent_type := node.Parent().Type()
+		if (parent_type == "block" || parent_type == "stylesheet") && node.Type() != "block" {
+			break
+		}
+		node = node.Parent()
+	}
+	if node == nil || node.Type() == "stylesheet" {
+		return []protocol.TextEdit{}
+	}
+	return lsp.FormatRange(path, node.StartPoint(), node.EndPoint(), options)
+}
@@ -0,0 +1,145 @@
+package lsp
+
+import (
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+const unformattedSource = `// header
+@use 'sass:math';
+$a : 1px ;
+.a,.b>.c{color:red;margin:0   auto;
+  &:hover{color:blue} // trailing
+  /* about .d */
+  .d { font-family: 'Helvetica', sans-serif }
+}
+@mixin foo( $a,$b : 2px ){ width:$a; @content; }
+@media (min-width:10px){ .f{ top:0 } }
+`
+
+const formattedSource = `// header
+@use "sass:math";
+$a: 1px;
+
+.a,
+.b > .c {
+  color: red;
+  margin: 0 auto;
+
+  &:hover {
+    color: blue;
+  } // trailing
+
+  /* about .d */
+  .d {
+    font-family: "Helvetica", sans-serif;
+  }
+}
+
+@mixin foo($a, $b: 2px) {
+  width: $a;
+  @content;
+}
+
+@media (min-width: 10px) {
+  .f {
+    top: 0;
+  }
+}
+`
+
+var spaces = protocol.FormattingOptions{TabSize: 2, InsertSpaces: true}
+
+func TestFormatDocument(t *testing.T) {
+	path := "/virtual/format.scss"
+	lsp := lspWithSources(t, map[string]string{path: unformattedSource})
+
+	edits := lsp.FormatDocument(path, spaces)
+	if len(edits) != 1 {
+		t.Fatalf("expected 1 edit, got %d", len(edits))
+	}
+	if edits[0].NewText != formattedSource {
+		t.Fatalf("expected:\n%s\ngot:\n%s", formattedSource, edits[0].NewText)
+	}
+	if edits[0].Range.End.Line != 10 {
+		t.Fatalf("expected the edit to cover the whole file, got %+v", edits[0].Range)
+	}
+
+	// formatting twice doesnt change anything
+	lsp = lspWithSources(t, map[string]string{path: formattedSource})
+	if edits := lsp.FormatDocument(path, spaces); len(edits) != 0 {
+		t.Fatalf("expected no edits, got %+v", edits)
+	}
+}
+
+func TestFormatOptions(t *testing.T) {
+	path := "/virtual/format.scss"
+	lsp := lspWithSources(t, map[string]string{path: ".a,.b{content:\"x\";top:0}\n.c{top:0}\n"})
+	use_tabs := true
+	lsp.Config.Format = FormatConfig{
+		UseTabs:          &use_tabs,
+		Quote:            "single",
+		SpaceBeforeColon: true,
+	}
+
+	edits := lsp.FormatDocument(path, spaces)
+	expected := ".a, .b {\n\tcontent :'x';\n\ttop :0\n}\n.c {\n\ttop :0\n}\n"
+	if len(edits) != 1 || edits[0].NewText != expected {
+		t.Fatalf("expected:\n%q\ngot:\n%+v", expected, edits)
+	}
+}
+
+func TestFormatKeepsErrors(t *testing.T) {
+	path := "/virtual/format.scss"
+	source := "$primary:   #fff   !default;\n.a {   top:0; }\n"
+	lsp := lspWithSources(t, map[string]string{path: source})
+
+	edits := lsp.FormatDocument(path, spaces)
+	expected := "$primary:   #fff   !default;\n\n.a {\n  top: 0;\n}\n"
+	if len(edits) != 1 || edits[0].NewText != expected {
+		t.Fatalf("expected:\n%q\ngot:\n%+v", expected, edits)
+	}
+}
+
+func TestFormatRange(t *testing.T) {
+	path := "/virtual/format.scss"
+	source := ".a {\n  top:0;\n  left:0;\n  right:0;\n}\n.b{top:0}\n"
+	lsp := lspWithSources(t, map[string]string{path: source})
+
+	edits := lsp.FormatRange(path, sitter.Point{Row: 1, Column: 0}, sitter.Point{Row: 2, Column: 3}, spaces)
+	if len(edits) != 1 {
+		t.Fatalf("expected 1 edit, got %+v", edits)
+	}
+	if edits[0].NewText != "top: 0;\n  left: 0;" {
+		t.Fatalf("unexpected edit %q", edits[0].NewText)
+	}
+	if edits[0].Range != rangeFromPoints(sitter.Point{Row: 1, Column: 2}, sitter.Point{Row: 2, Column: 9}) {
+		t.Fatalf("unexpected range %+v", edits[0].Range)
+	}
+}
+
+func TestFormatOnType(t *testing.T) {
+	path := "/virtual/format.scss"
+	source := ".a {\n  .b{top:0}\n}\n"
+	lsp := lspWithSources(t, map[string]string{path: source})
+
+	// right after the } of .b
+	edits := lsp.FormatOnType(path, sitter.Point{Row: 1, Column: 11}, spaces)
+	if len(edits) != 1 || edits[0].NewText != ".b {\n    top: 0;\n  }" {
+		t.Fatalf("unexpected edits %+v", edits)
+	}
+}
+
+func TestFormatUrlsAndPreludes(t *testing.T) {
+	path := "/virtual/format.scss"
+	source := ".a{background:url(data:image/png;base64,AAAA);content:\"a:b,c\"}\n@at-root .x{top:0}\n@page :first{margin:0}\n"
+	lsp := lspWithSources(t, map[string]string{path: source})
+
+	edits := lsp.FormatDocument(path, spaces)
+	expected := ".a {\n  background: url(data:image/png;base64,AAAA);\n  content: \"a:b,c\";\n}\n\n@at-root .x {\n  top: 0;\n}\n\n@page :first {\n  margin: 0;\n}\n"
+	if len(edits) != 1 || edits[0].NewText != expected {
+		t.Fatalf("expected:\n%q\ngot:\n%+v", expected, edits)
+	}
+}
@@ -0,0 +1,247 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// references to variables in a value, namespaced ones included
+var variableReferenceRegex = regexp.MustCompile(`(?:[A-Za-z_][\w-]*\.)?\$[A-Za-z_][\w-]*`)
+
+// how far resolveValue follows variables that are set to other variables
+const maxResolveDepth = 8
+
+// relativePath is the path as shown to the user, relative to the root
+func (lsp *Lsp) relativePath(path string) string {
+	if lsp.RootPath == "" {
+		return path
+	}
+	relative, err := filepath.Rel(lsp.RootPath, path)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return path
+	}
+	return filepath.ToSlash(relative)
+}
+
+// wordAt is the word at the position, namespace and $ included, and the
+// text of the line before it
+func wordAt(input []byte, position sitter.Point) (string, string) {
+	lines := strings.Split(string(input), "\n")
+	if int(position.Row) >= len(lines) {
+		return "", ""
+	}
+	line := lines[position.Row]
+	start := min(int(position.Column), len(line))
+	end := start
+	for start > 0 && isWordChar(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isWordChar(line[end]) {
+		end++
+	}
+	return line[start:end], line[:start]
+}
+
+// declarationValue is the value of a variable declaration without the flags
+func declarationValue(body string) string {
+	_, value, _ := strings.Cut(body, ":")
+	value = strings.TrimSuffix(strings.TrimSpace(value), ";")
+	for _, flag := range []string{"!default", "!global"} {
+		value = strings.ReplaceAll(value, flag, "")
+	}
+	return strings.TrimSpace(value)
+}
+
+// definitionParameters are the parameters of a mixin or function as they
+// are written, defaults included
+func definitionParameters(entry isDefined) []string {
+	rest := strings.TrimSpace(strings.TrimPrefix(entry.body, entry.name))
+	if !strings.HasPrefix(rest, "(") {
+		return []string{}
+	}
+	return splitParameters(rest)
+}
+
+// definitionSignature is how a definition is shown, the signature of mixins
+// and functions and the declaration of variables
+func definitionSignature(item_type string, entry isDefined) string {
+	switch item_type {
+	case itemTypeMixin, itemTypeFunction:
+		return item_type + " " + entry.name + "(" + strings.Join(definitionParameters(entry), ", ") + ")"
+	}
+	return strings.TrimSpace(entry.body)
+}
+
+// scopedDefinitions finds the definitions of a member the file can see, its
+// own first. When the module graph shows none the member comes from
+// somewhere it doesnt know about, like a file that @imports this one, and
+// every definition in the workspace is a candidate.
+func (lsp *Lsp) scopedDefinitions(path string, name string, item_type string) []isDefinedInfo {
+	if namespace, member, found := strings.Cut(name, "."); found {
+		entry, ok := lsp.memberDefinition(path, namespace, member, item_type)
+		if !ok {
+			return []isDefinedInfo{}
+		}
+		for _, module := range lsp.VisibleModules(path) {
+			for _, other := range lsp.definitionMap(item_type)[module.path] {
+				if other == entry {
+					return []isDefinedInfo{{path: module.path, item_type: item_type, is_defined: entry}}
+				}
+			}
+		}
+		return []isDefinedInfo{}
+	}
+
+	definitions := lsp.definitionMap(item_type)
+	if item_type == itemTypePlaceholder {
+		definitions = lsp.Placeholders
+	}
+	visible := []isDefinedInfo{}
+	seen := map[string]bool{}
+	for idx, module := range lsp.VisibleModules(path) {
+		if module.namespace != "" || seen[module.path] {
+			continue
+		}
+		seen[module.path] = true
+		for _, entry := range definitions[module.path] {
+			if member, ok := module.memberName(entry.name); !ok || member != name {
+				continue
+			}
+			if item_type == itemTypeVariable && !lsp.isTopLevelVariable(module.path, entry) {
+				continue
+			}
+			visible = append(visible, isDefinedInfo{path: module.path, item_type: item_type, is_defined: entry})
+			// the file itself shadows everything else
+			if idx == 0 {
+				return visible
+			}
+			break
+		}
+	}
+	if len(visible) > 0 {
+		return visible
+	}
+	for _, defined_in := range sortedPaths(definitions) {
+		for _, entry := range definitions[defined_in] {
+			if entry.name != name || item_type == itemTypeVariable && !lsp.isTopLevelVariable(defined_in, entry) {
+				continue
+			}
+			visible = append(visible, isDefinedInfo{path: defined_in, item_type: item_type, is_defined: entry})
+		}
+	}
+	return visible
+}
+
+// resolveValue replaces the variables in a value with their values, as far
+// as they can be followed
+func (lsp *Lsp) resolveValue(path string, value string, depth int) string {
+	if depth >= maxResolveDepth {
+		return value
+	}
+	return variableReferenceRegex.ReplaceAllStringFunc(value, func(reference string) string {
+		definitions := lsp.scopedDefinitions(path, reference, itemTypeVariable)
+		if len(definitions) != 1 {
+			return reference
+		}
+		definition := definitions[0]
+		return lsp.resolveValue(definition.path, declarationValue(definition.is_defined.body), depth+1)
+	})
+}
+
+// definitionHover is the markdown for one definition
+func (lsp *Lsp) definitionHover(info isDefinedInfo) string {
+	var sb strings.Builder
+	entry := info.is_defined
+	sb.WriteString("```scss\n" + definitionSignature(info.item_type, entry) + "\n```")
+	parameters := []string{}
+	if info.item_type == itemTypeMixin || info.item_type == itemTypeFunction {
+		parameters = definitionParameters(entry)
+	}
+
+	if documentation := definitionDocumentation(entry, parameters); documentation != "" {
+		sb.WriteString("\n\n" + documentation)
+	}
+	if info.item_type == itemTypeVariable {
+		value := declarationValue(entry.body)
+		if resolved := lsp.resolveValue(info.path, value, 0); resolved != value {
+			sb.WriteString("\n\nValue: `" + resolved + "`")
+		}
+	}
+	sb.WriteString(fmt.Sprintf("\n\ndefined in %s:%d", lsp.relativePath(info.path), entry.start_position.Row+1))
+	return sb.String()
+}
+
+// localHover shows parameters, loop variables and variables of a block,
+// they never come from other files
+func (lsp *Lsp) localHover(path string, target symbolOccurrence, input []byte) (string, bool) {
+	scope := variableScope(target, &input)
+	if scope == nil || scope.Type() == "stylesheet" {
+		return "", false
+	}
+	for _, occurrence := range lsp.findOccurrences(path, target) {
+		if !occurrence.is_write {
+			continue
+		}
+		parent := occurrence.node.Parent()
+		kind := "loop variable"
+		text := occurrence.name
+		switch parent.Type() {
+		case "declaration":
+			kind = "local variable"
+			text = parent.Content(input)
+		case "parameter":
+			kind = "parameter"
+			text = parent.Content(input)
+		}
+		return fmt.Sprintf("```scss\n%s\n```\n\n%s, line %d", text, kind, occurrence.node.StartPoint().Row+1), true
+	}
+	return "", false
+}
+
+// symbolHover shows the definitions of the symbol under the cursor, the one
+// in scope or all of them when that can not be told
+func (lsp *Lsp) symbolHover(path string, input []byte, node *sitter.Node, position sitter.Point) string {
+	name := node.Content(input)
+	item_type := ""
+	if target, ok := lsp.symbolAtPosition(path, position); ok {
+		name, item_type = target.name, target.item_type
+		if item_type == itemTypeVariable && target.node != nil {
+			if hover, ok := lsp.localHover(path, target, input); ok {
+				return hover
+			}
+		}
+	}
+
+	// namespaced mixins and functions end up in ERROR nodes
+	if word, before := wordAt(input, position); strings.Contains(word, ".") {
+		name = word
+		_, member, _ := strings.Cut(word, ".")
+		switch {
+		case strings.HasPrefix(member, "$"):
+			item_type = itemTypeVariable
+		case strings.HasSuffix(strings.TrimRight(before, " \t"), "@include"):
+			item_type = itemTypeMixin
+		default:
+			item_type = itemTypeFunction
+		}
+	}
+	if item_type == "" {
+		return ""
+	}
+	definitions := lsp.scopedDefinitions(path, name, item_type)
+	switch len(definitions) {
+	case 0:
+		return ""
+	case 1:
+		return lsp.definitionHover(definitions[0])
+	}
+	hovers := []string{fmt.Sprintf("%s is defined in %d places", name, len(definitions))}
+	for idx, definition := range definitions {
+		hovers = append(hovers, fmt.Sprintf("**%d.** %s", idx+1, lsp.definitionHover(definition)))
+	}
+	return strings.Join(hovers, "\n\n---\n\n")
+}
@@ -0,0 +1,78 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+func TestHoverInScope(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/_colors.scss":   "$brand: #f00;\n/// The main color\n/// of the site\n$primary: $brand !default;\n$gap: 2px;\n",
+		"/virtual/a/_shadow.scss": "@mixin shadow($size: 1px, $color) {}\n",
+		"/virtual/b/_shadow.scss": "@mixin shadow($size) {}\n",
+		path: `@use "colors";
+$gap: 1px;
+@mixin m($size: 2px) {
+  width: $size + $gap;
+}
+.a {
+  color: colors.$primary;
+  @include shadow;
+}
+`,
+	})
+	lsp.RootPath = "/virtual"
+
+	hover := lsp.GetHoverInfo(path, sitter.Point{Row: 6, Column: 18})
+	for _, expected := range []string{
+		"```scss\n$primary: $brand !default;\n```",
+		"The main color\nof the site",
+		"Value: `#f00`",
+		"defined in _colors.scss:4",
+	} {
+		if !strings.Contains(hover, expected) {
+			t.Fatalf("expected %q in hover %q", expected, hover)
+		}
+	}
+
+	// the file's own $gap, not the one of colors
+	hover = lsp.GetHoverInfo(path, sitter.Point{Row: 3, Column: 18})
+	if !strings.Contains(hover, "$gap: 1px;") || strings.Contains(hover, "2px") {
+		t.Fatalf("unexpected hover %q", hover)
+	}
+
+	hover = lsp.GetHoverInfo(path, sitter.Point{Row: 3, Column: 10})
+	if hover != "```scss\n$size: 2px\n```\n\nparameter, line 3" {
+		t.Fatalf("unexpected hover %q", hover)
+	}
+
+	// neither shadow is loaded, both are candidates
+	hover = lsp.GetHoverInfo(path, sitter.Point{Row: 7, Column: 13})
+	for _, expected := range []string{
+		"shadow is defined in 2 places",
+		"**1.** ```scss\n@mixin shadow($size: 1px, $color)\n```",
+		"- `$size` defaults to `1px`\n- `$color`",
+		"defined in a/_shadow.scss:1",
+		"**2.** ```scss\n@mixin shadow($size)\n```",
+		"defined in b/_shadow.scss:1",
+	} {
+		if !strings.Contains(hover, expected) {
+			t.Fatalf("expected %q in hover %q", expected, hover)
+		}
+	}
+}
+
+func TestHoverNamespacedMixin(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/_mixins.scss": "/// Centers things\n@mixin center {\n  margin: auto;\n}\n",
+		path:                    "@use \"mixins\" as mx;\n.a {\n  @include mx.center;\n}\n",
+	})
+	hover := lsp.GetHoverInfo(path, sitter.Point{Row: 2, Column: 16})
+	if !strings.Contains(hover, "@mixin center()") || !strings.Contains(hover, "Centers things") {
+		t.Fatalf("unexpected hover %q", hover)
+	}
+}
@@ -0,0 +1,513 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// the command that migrates the @import graph of entry points to modules,
+// the result is the WorkspaceEdit to review and apply
+const commandMigrateImports = "scss-lsp.migrateImports"
+
+// a @use or @forward the migration adds to a file
+type migratedUse struct {
+	url       string
+	namespace string
+	// the file behind the url, empty for built-in modules and files that
+	// can not be found
+	path string
+	// the variables set before the @import that become `with (...)`
+	with []string
+}
+
+func (use migratedUse) statement(forward bool) string {
+	statement := "@use \"" + use.url + "\""
+	if forward {
+		statement = "@forward \"" + use.url + "\""
+	} else if use.namespace != defaultNamespace(use.url) {
+		statement += " as " + use.namespace
+	}
+	if len(use.with) > 0 {
+		statement += " with (" + strings.Join(use.with, ", ") + ")"
+	}
+	return statement + ";"
+}
+
+// importGraph lists the files the entry points load with @import, in the
+// order sass loads them
+func (lsp *Lsp) importGraph(entries []string) []string {
+	graph := []string{}
+	seen := map[string]bool{}
+	var visit func(path string)
+	visit = func(path string) {
+		if seen[path] || lsp.Trees[path] == nil {
+			return
+		}
+		seen[path] = true
+		graph = append(graph, path)
+		for _, statement := range lsp.Modules[path] {
+			if statement.kind != "@import" {
+				continue
+			}
+			if target, ok := lsp.resolveModule(path, statement.url); ok {
+				visit(target)
+			}
+		}
+	}
+	for _, entry := range entries {
+		visit(entry)
+	}
+	return graph
+}
+
+// entryPoints are the files that no other file loads, partials are only
+// ever loaded
+func (lsp *Lsp) entryPoints() []string {
+	loaded := map[string]bool{}
+	for _, path := range sortedPaths(lsp.Modules) {
+		for _, statement := range lsp.Modules[path] {
+			if target, ok := lsp.resolveModule(path, statement.url); ok {
+				loaded[target] = true
+			}
+		}
+	}
+	entries := []string{}
+	for _, path := range sortedPaths(lsp.Trees) {
+		if !loaded[path] && !strings.HasPrefix(filepath.Base(path), "_") {
+			entries = append(entries, path)
+		}
+	}
+	return entries
+}
+
+// isForwardingOnly checks for files like _index.scss that only @import other
+// files, they become @forward
+func (lsp *Lsp) isForwardingOnly(path string) bool {
+	tree := lsp.Trees[path]
+	if tree == nil {
+		return false
+	}
+	root := tree.RootNode()
+	imports := 0
+	for idx := 0; idx < int(root.NamedChildCount()); idx++ {
+		switch root.NamedChild(idx).Type() {
+		case "import_statement":
+			imports++
+		case "forward_statement", "comment", "single_line_comment":
+		default:
+			return false
+		}
+	}
+	return imports > 0
+}
+
+// forwardedFiles are the files a forwarding only file makes visible
+func (lsp *Lsp) forwardedFiles(path string, files map[string]bool) map[string]bool {
+	for _, statement := range lsp.Modules[path] {
+		target, ok := lsp.resolveModule(path, statement.url)
+		if statement.kind != "@import" || !ok || files[target] {
+			continue
+		}
+		files[target] = true
+		if lsp.isForwardingOnly(target) {
+			lsp.forwardedFiles(target, files)
+		}
+	}
+	return files
+}
+
+// topLevelDefinition finds the file of the graph that defines the member at
+// its top level, the first one sass loads wins
+func (lsp *Lsp) topLevelDefinition(graph []string, exclude map[string]bool, name string, item_type string) (string, bool) {
+	definitions := lsp.definitionMap(item_type)
+	for _, path := range graph {
+		if exclude[path] {
+			continue
+		}
+		for _, entry := range definitions[path] {
+			if entry.name != name {
+				continue
+			}
+			if item_type == itemTypeVariable && !lsp.isTopLevelVariable(path, entry) {
+				continue
+			}
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// defaultVariables are the top level variables of the file with !default,
+// the ones a @use can configure
+func (lsp *Lsp) defaultVariables(path string) map[string]bool {
+	defaults := map[string]bool{}
+	tree := lsp.Trees[path]
+	input, err := lsp.bytesFromFilePath(path)
+	if tree == nil || err != nil {
+		return defaults
+	}
+	for _, entry := range lsp.Variables[path] {
+		node := declarationOf(tree, entry)
+		if node != nil && node.Parent() != nil && node.Parent().Type() == "stylesheet" && strings.Contains(node.Content(*input), "!default") {
+			defaults[entry.name] = true
+		}
+	}
+	return defaults
+}
+
+// configurableVariables are the !default variables a `@use ... with (...)` of
+// the file can set once it is migrated, its own and the ones of the files it
+// forwards, forwarding only files forward what they @import
+func (lsp *Lsp) configurableVariables(path string) map[string]bool {
+	modules := lsp.forwardedModules(visibleModule{path: path}, []visibleModule{}, map[string]bool{})
+	if lsp.isForwardingOnly(path) {
+		for _, file := range sortedPaths(lsp.forwardedFiles(path, map[string]bool{})) {
+			modules = lsp.forwardedModules(visibleModule{path: file}, modules, map[string]bool{})
+		}
+	}
+	configurable := map[string]bool{}
+	for _, module := range modules {
+		for name := range lsp.defaultVariables(module.path) {
+			if name, ok := module.memberName(name); ok {
+				configurable[name] = true
+			}
+		}
+	}
+	return configurable
+}
+
+// statementRange is the range of a statement, with its line when nothing
+// else is on it so removing it leaves no empty line
+func statementRange(input []byte, node *sitter.Node) protocol.Range {
+	start, end := node.StartPoint(), node.EndPoint()
+	lines := strings.Split(string(input), "\n")
+	if strings.TrimSpace(lines[start.Row][:start.Column]) == "" && strings.TrimSpace(lines[end.Row][end.Column:]) == "" {
+		return protocol.Range{Start: protocol.Position{Line: start.Row}, End: protocol.Position{Line: end.Row + 1}}
+	}
+	return rangeFromPoints(start, end)
+}
+
+func isInRanges(point sitter.Point, ranges []protocol.Range) bool {
+	for _, removed := range ranges {
+		start := sitter.Point{Row: removed.Start.Line, Column: removed.Start.Character}
+		end := sitter.Point{Row: removed.End.Line, Column: removed.End.Character}
+		if comparePoints(point, start) >= 0 && comparePoints(point, end) < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// fileMigration turns the @import statements of one file into @use and
+// @forward and puts the namespaces in front of the members it uses
+type fileMigration struct {
+	lsp   *Lsp
+	path  string
+	graph []string
+	// the files the migration starts from, nothing can @use them
+	entries map[string]bool
+	input   []byte
+	root    *sitter.Node
+
+	taken    map[string]bool
+	uses     []*migratedUse
+	use_of   map[string]*migratedUse
+	edits    []protocol.TextEdit
+	removed  []protocol.Range
+	warnings []string
+}
+
+func (migration *fileMigration) warn(point sitter.Point, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	migration.warnings = append(migration.warnings, fmt.Sprintf("%s:%d:%d: %s", migration.path, point.Row+1, point.Column+1, message))
+}
+
+func (migration *fileMigration) addUse(url string, path string) *migratedUse {
+	namespace := defaultNamespace(url)
+	free := namespace
+	for idx := 2; migration.taken[free]; idx++ {
+		free = fmt.Sprintf("%s%d", namespace, idx)
+	}
+	migration.taken[free] = true
+	use := &migratedUse{url: url, namespace: free, path: path}
+	migration.uses = append(migration.uses, use)
+	if path != "" {
+		migration.use_of[path] = use
+	}
+	return use
+}
+
+// builtinNamespace is the namespace of the built-in module in the file,
+// with a new @use if it isnt loaded yet
+func (migration *fileMigration) builtinNamespace(module string) string {
+	url := "sass:" + module
+	for _, statement := range migration.lsp.Modules[migration.path] {
+		if statement.kind == "@use" && statement.url == url {
+			return strings.TrimSuffix(statement.namespace, "*")
+		}
+	}
+	for _, use := range migration.uses {
+		if use.url == url {
+			return use.namespace
+		}
+	}
+	return migration.addUse(url, "").namespace
+}
+
+// configure moves the variables set before the @import that the imported
+// file has with !default into the `with (...)` of the @use
+func (migration *fileMigration) configure(use *migratedUse, import_node *sitter.Node) {
+	defaults := migration.lsp.configurableVariables(use.path)
+	for sibling := import_node.PrevNamedSibling(); sibling != nil; sibling = sibling.PrevNamedSibling() {
+		if sibling.Type() != "declaration" || sibling.NamedChildCount() == 0 || sibling.NamedChild(0).Type() != "variable_name" {
+			continue
+		}
+		name := sibling.NamedChild(0).Content(migration.input)
+		text := sibling.Content(migration.input)
+		if !defaults[name] || strings.Contains(text, "!default") || strings.Contains(text, "!global") {
+			continue
+		}
+		_, value, _ := strings.Cut(text, ":")
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), ";"))
+		// the last one before the @import is the value sass sees
+		use.with = append([]string{name + ": " + value}, use.with...)
+		migration.removed = append(migration.removed, statementRange(migration.input, sibling))
+		defaults[name] = false
+	}
+}
+
+// definesMember checks if the member the call is for is defined in the file
+// itself, locals and parameters included
+func (migration *fileMigration) definesMember(call isDefined) bool {
+	for _, entry := range migration.lsp.definitionMap(call.item_type)[migration.path] {
+		if entry.name == call.name && !isInRanges(entry.start_position, migration.removed) {
+			return true
+		}
+	}
+	if call.item_type != itemTypeVariable {
+		return false
+	}
+	node := migration.root.NamedDescendantForPointRange(call.start_position, call.end_position)
+	for _, local := range localVariables(node, migration.input, call.start_position) {
+		if local.name == call.name {
+			return true
+		}
+	}
+	return false
+}
+
+// useFor finds the @use the members of the file come through, a file that
+// is only forwarded goes through the file that forwards it
+func (migration *fileMigration) useFor(path string) *migratedUse {
+	if use, ok := migration.use_of[path]; ok {
+		return use
+	}
+	for _, use := range migration.uses {
+		if use.path != "" && migration.lsp.isForwardingOnly(use.path) && migration.lsp.forwardedFiles(use.path, map[string]bool{})[path] {
+			return use
+		}
+	}
+	return migration.addUse(moduleUrl(migration.path, path), path)
+}
+
+func (migration *fileMigration) namespaceReferences() {
+	for _, call := range migration.lsp.Calls[migration.path] {
+		if strings.Contains(call.name, ".") || isInRanges(call.start_position, migration.removed) || migration.definesMember(call) {
+			continue
+		}
+		exclude := map[string]bool{migration.path: true}
+		for entry := range migration.entries {
+			exclude[entry] = true
+		}
+		defined_in, ok := migration.lsp.topLevelDefinition(migration.graph, exclude, call.name, call.item_type)
+		if !ok {
+			if entry, ok := migration.lsp.topLevelDefinition(migration.graph, map[string]bool{migration.path: true}, call.name, call.item_type); ok {
+				migration.warn(call.start_position, "%s is defined in the entry point %s, which a module can not @use, move it to a partial", call.name, entry)
+			}
+			// built-in, plain css or undefined
+			continue
+		}
+		use := migration.useFor(defined_in)
+		if use.namespace == "*" {
+			continue
+		}
+		if isPrivateMember(call.name) {
+			migration.warn(call.start_position, "%s is private to %s once it is a module, rename it", call.name, defined_in)
+			continue
+		}
+		migration.edits = append(migration.edits, protocol.TextEdit{
+			Range:   rangeFromPoints(call.start_position, call.end_position),
+			NewText: use.namespace + "." + call.name,
+		})
+	}
+}
+
+func (lsp *Lsp) migrateFile(path string, graph []string, entries map[string]bool) ([]protocol.TextEdit, []*migratedUse, []string) {
+	tree := lsp.Trees[path]
+	input, err := lsp.bytesFromFilePath(path)
+	if tree == nil || err != nil {
+		return nil, nil, nil
+	}
+	migration := &fileMigration{
+		lsp:     lsp,
+		path:    path,
+		graph:   graph,
+		entries: entries,
+		input:   *input,
+		root:    tree.RootNode(),
+		taken:   map[string]bool{},
+		use_of:  map[string]*migratedUse{},
+	}
+	for _, statement := range lsp.Modules[path] {
+		if statement.kind == "@use" {
+			migration.taken[statement.namespace] = true
+		}
+	}
+
+	// the statements of every @import, by where it starts
+	statements := map[sitter.Point][]moduleStatement{}
+	for _, statement := range lsp.Modules[path] {
+		if statement.kind == "@import" {
+			statements[statement.statement_start] = append(statements[statement.statement_start], statement)
+		}
+	}
+	imports := []*sitter.Node{}
+	css_imports := map[*sitter.Node][]string{}
+	for _, start := range sortedPoints(statements) {
+		node := migration.root.NamedDescendantForPointRange(start, statements[start][0].statement_end)
+		for node != nil && node.Type() != "import_statement" {
+			node = node.Parent()
+		}
+		if node == nil {
+			continue
+		}
+		if node.Parent().Type() != "stylesheet" {
+			migration.migrateNestedImport(node, statements[start])
+			continue
+		}
+		imports = append(imports, node)
+		for _, statement := range statements[start] {
+			if isPlainCssImport(*input, statement) {
+				css_imports[node] = append(css_imports[node], fmt.Sprintf("@import \"%s\";", statement.url))
+				continue
+			}
+			target, ok := lsp.resolveModule(path, statement.url)
+			if !ok || lsp.Trees[target] == nil {
+				migration.warn(statement.start_position, "can not find %s, it is loaded with `as *`", statement.url)
+				use := migration.addUse(statement.url, "")
+				use.namespace = "*"
+				continue
+			}
+			if _, ok := migration.use_of[target]; ok {
+				continue
+			}
+			use := migration.addUse(statement.url, target)
+			migration.configure(use, node)
+		}
+	}
+
+	migration.namespaceReferences()
+
+	forward := lsp.isForwardingOnly(path)
+	header := []string{}
+	for _, use := range migration.uses {
+		header = append(header, use.statement(forward && use.path != ""))
+	}
+	for _, removed := range migration.removed {
+		migration.edits = append(migration.edits, protocol.TextEdit{Range: removed})
+	}
+	in_place := len(imports) > 0 && isModuleHeader(imports[0])
+	for idx, node := range imports {
+		lines := css_imports[node]
+		if idx == 0 && in_place {
+			lines = append(header, lines...)
+		}
+		text := strings.Join(lines, "\n")
+		statement_range := statementRange(*input, node)
+		if text != "" && statement_range.End.Character == 0 && statement_range.End.Line > statement_range.Start.Line {
+			text += "\n"
+		}
+		migration.edits = append(migration.edits, protocol.TextEdit{Range: statement_range, NewText: text})
+	}
+	if !in_place && len(header) > 0 {
+		position := lsp.useInsertPosition(path)
+		migration.edits = append([]protocol.TextEdit{{
+			Range:   protocol.Range{Start: position, End: position},
+			NewText: strings.Join(header, "\n") + "\n",
+		}}, migration.edits...)
+	}
+	return migration.edits, migration.uses, migration.warnings
+}
+
+// migrateNestedImport turns an @import in a rule into meta.load-css, which
+// is the only way to load a module there
+func (migration *fileMigration) migrateNestedImport(node *sitter.Node, statements []moduleStatement) {
+	lines := []string{}
+	for _, statement := range statements {
+		if isPlainCssImport(migration.input, statement) {
+			migration.warn(statement.start_position, "plain css @import of %s can not be nested in a module", statement.url)
+			return
+		}
+		lines = append(lines, fmt.Sprintf("@include %s(\"%s\");", namespacedName(migration.builtinNamespace("meta"), "load-css"), statement.url))
+	}
+	migration.edits = append(migration.edits, protocol.TextEdit{
+		Range:   rangeFromPoints(node.StartPoint(), node.EndPoint()),
+		NewText: strings.Join(lines, "\n"+lineIndent(migration.input, node.StartPoint())),
+	})
+}
+
+func sortedPoints[T any](points map[sitter.Point]T) []sitter.Point {
+	sorted := make([]sitter.Point, 0, len(points))
+	for point := range points {
+		sorted = append(sorted, point)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return comparePoints(sorted[i], sorted[j]) < 0
+	})
+	return sorted
+}
+
+// MigrateImports migrates the files the entry points load with @import to
+// @use and @forward, every file of the workspace that no other file loads
+// is an entry point when there are none. The warnings are the things that
+// need a look before the edit is applied.
+func (lsp *Lsp) MigrateImports(entries []string) (protocol.WorkspaceEdit, []string) {
+	if len(entries) == 0 {
+		entries = lsp.entryPoints()
+	}
+	graph := lsp.importGraph(entries)
+	is_entry := map[string]bool{}
+	for _, entry := range entries {
+		is_entry[entry] = true
+	}
+	edit := protocol.WorkspaceEdit{Changes: map[uri.URI][]protocol.TextEdit{}}
+	warnings := []string{}
+	loads := map[string]map[string]bool{}
+	for _, path := range graph {
+		edits, uses, file_warnings := lsp.migrateFile(path, graph, is_entry)
+		warnings = append(warnings, file_warnings...)
+		if len(edits) > 0 {
+			edit.Changes[uri.File(path)] = edits
+		}
+		loads[path] = map[string]bool{}
+		for _, use := range uses {
+			if use.path != "" {
+				loads[path][use.path] = true
+			}
+		}
+	}
+	for _, path := range graph {
+		for _, other := range sortedPaths(loads[path]) {
+			if path < other && loads[other][path] {
+				warnings = append(warnings, fmt.Sprintf("%s and %s would @use each other, sass doesnt allow that, move the shared members to another file", path, other))
+			}
+		}
+	}
+	return edit, warnings
+}
@@ -0,0 +1,164 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func migratedFiles(t *testing.T, lsp *Lsp, edit protocol.WorkspaceEdit) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	for file_uri, edits := range edit.Changes {
+		path := file_uri.Filename()
+		input, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		relative, _ := filepath.Rel(lsp.RootPath, path)
+		files[filepath.ToSlash(relative)] = string(ApplyEdits(input, edits))
+	}
+	return files
+}
+
+func TestMigrateImports(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.scss": `$primary: green;
+@import "variables";
+@import "components/index", "print.css";
+.page {
+  color: $primary;
+  width: double($gutter);
+  @include rounded;
+  @import "nested";
+}
+`,
+		"_variables.scss":        "$primary: red !default;\n$gutter: 10px;\n@function double($x) {\n  @return $x * 2;\n}\n",
+		"components/_index.scss": "// all of them\n@import \"button\";\n",
+		"components/_button.scss": `@mixin rounded($radius: $gutter) {
+  border-radius: $radius;
+}
+.button { padding: $gutter; }
+`,
+		"_nested.scss": ".x { color: $-private; }\n",
+	})
+	lsp := LoadWorkspace(root)
+
+	result, err := lsp.ExecuteCommand(commandMigrateImports, []interface{}{"main.scss"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := migratedFiles(t, lsp, result.(protocol.WorkspaceEdit))
+	expected := map[string]string{
+		"main.scss": `@use "variables" with ($primary: green);
+@use "components/index";
+@use "sass:meta";
+@import "print.css";
+.page {
+  color: variables.$primary;
+  width: variables.double(variables.$gutter);
+  @include index.rounded;
+  @include meta.load-css("nested");
+}
+`,
+		"components/_index.scss": "// all of them\n@forward \"button\";\n",
+		"components/_button.scss": `@use "../variables";
+@mixin rounded($radius: variables.$gutter) {
+  border-radius: $radius;
+}
+.button { padding: variables.$gutter; }
+`,
+	}
+	for path, text := range expected {
+		if files[path] != text {
+			t.Errorf("unexpected %s:\n%s", path, files[path])
+		}
+	}
+	if len(files) != len(expected) {
+		t.Errorf("unexpected files %v", files)
+	}
+}
+
+func TestMigrateImportsForwardedDefaults(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.scss":                 "$primary: red;\n$spacing: 4px;\n@import \"abstracts/index\";\n.page { color: $primary; }\n",
+		"abstracts/_index.scss":     "@import \"variables\";\n@import \"mixins\";\n",
+		"abstracts/_variables.scss": "$primary: blue !default;\n",
+		"abstracts/_mixins.scss":    "@mixin accent { color: $primary; margin: $spacing; }\n",
+	})
+	lsp := LoadWorkspace(root)
+
+	edit, warnings := lsp.MigrateImports(nil)
+	expected_warning := filepath.Join(root, "abstracts/_mixins.scss") + ":1:42: $spacing is defined in the entry point " + filepath.Join(root, "main.scss")
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], expected_warning) {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+	files := migratedFiles(t, lsp, edit)
+	expected := map[string]string{
+		// the index forwards the variables, so the @use of it configures them
+		"main.scss":             "$spacing: 4px;\n@use \"abstracts/index\" with ($primary: red);\n.page { color: index.$primary; }\n",
+		"abstracts/_index.scss": "@forward \"variables\";\n@forward \"mixins\";\n",
+		// not the $primary of main.scss, an entry point can not be used
+		"abstracts/_mixins.scss": "@use \"variables\";\n@mixin accent { color: variables.$primary; margin: $spacing; }\n",
+	}
+	for path, text := range expected {
+		if files[path] != text {
+			t.Errorf("unexpected %s:\n%s", path, files[path])
+		}
+	}
+	if len(files) != len(expected) {
+		t.Errorf("unexpected files %v", files)
+	}
+}
+
+func TestMigrateImportsWarnings(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app.scss":  "@import \"a\";\n@import \"vendor/missing\";\n.app { color: $-secret; }\n",
+		"_a.scss":   "$-secret: red;\n.a { width: $b; }\n@import \"b\";\n",
+		"_b.scss":   "$b: 1px;\n.b { color: $-secret; }\n",
+		"other.css": "",
+	})
+	lsp := LoadWorkspace(root)
+
+	edit, warnings := lsp.MigrateImports(nil)
+	files := migratedFiles(t, lsp, edit)
+	// the @import is after a rule, the @use has to move up
+	if files["_a.scss"] != "@use \"b\";\n$-secret: red;\n.a { width: b.$b; }\n" {
+		t.Errorf("unexpected _a.scss:\n%s", files["_a.scss"])
+	}
+	if files["app.scss"] != "@use \"a\";\n@use \"vendor/missing\" as *;\n.app { color: $-secret; }\n" {
+		t.Errorf("unexpected app.scss:\n%s", files["app.scss"])
+	}
+	joined := strings.Join(warnings, "\n")
+	for _, warning := range []string{
+		"app.scss:2:10: can not find vendor/missing",
+		"app.scss:3:15: $-secret is private to " + filepath.Join(root, "_a.scss"),
+		"_a.scss and " + filepath.Join(root, "_b.scss") + " would @use each other",
+	} {
+		if !strings.Contains(joined, warning) {
+			t.Errorf("missing warning %q in\n%s", warning, joined)
+		}
+	}
+}
+
+func TestEditDiff(t *testing.T) {
+	input := []byte("a\nb\nc\nd\n")
+	edits := []protocol.TextEdit{
+		{Range: protocol.Range{Start: protocol.Position{Line: 0}, End: protocol.Position{Line: 0}}, NewText: "top\n"},
+		{Range: protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 3}}},
+		{Range: protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 3, Character: 1}}, NewText: "D"},
+	}
+	if text := string(ApplyEdits(input, edits)); text != "top\na\nb\nD\n" {
+		t.Fatalf("unexpected text %q", text)
+	}
+	expected := "--- x.scss\n+++ x.scss\n@@ -1,1 +1,2 @@\n-a\n+top\n+a\n@@ -3,2 +4,1 @@\n-c\n-d\n+D\n"
+	if diff := EditDiff("x.scss", input, edits); diff != expected {
+		t.Fatalf("unexpected diff\n%s", diff)
+	}
+}
@@ -0,0 +1,340 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+// the code of the diagnostic for ns.member when the module has no member
+const diagnosticUndefinedMember = "undefined-member"
+
+// a file whose members can be used from another file, and how they are
+// called there
+type visibleModule struct {
+	path string
+	// the namespace of the @use, empty when the members are global, which is
+	// the case for the file itself, @import and `as *`
+	namespace string
+	// the @forward statements the members went through, innermost first
+	forwards []moduleStatement
+	// loaded with @use or @forward, the private members stay in the file
+	is_module bool
+}
+
+// private members are not visible outside of the file that defines them
+func isPrivateMember(name string) bool {
+	name = strings.TrimPrefix(name, "$")
+	return strings.HasPrefix(name, "-") || strings.HasPrefix(name, "_")
+}
+
+func prefixMember(prefix string, name string) string {
+	if strings.HasPrefix(name, "$") {
+		return "$" + prefix + name[1:]
+	}
+	return prefix + name
+}
+
+func containsMember(members []string, name string) bool {
+	for _, member := range members {
+		if member == name {
+			return true
+		}
+	}
+	return false
+}
+
+// memberName is the name a member of the module is known by in the file
+// that sees the module, false if a @forward hides it
+func (module visibleModule) memberName(name string) (string, bool) {
+	if module.is_module && isPrivateMember(name) {
+		return "", false
+	}
+	for _, forward := range module.forwards {
+		name = prefixMember(forward.prefix, name)
+		if len(forward.show) > 0 && !containsMember(forward.show, name) {
+			return "", false
+		}
+		if containsMember(forward.hide, name) {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+// forwardedModules adds the module and everything it forwards
+func (lsp *Lsp) forwardedModules(module visibleModule, modules []visibleModule, seen map[string]bool) []visibleModule {
+	if seen[module.path] {
+		return modules
+	}
+	seen[module.path] = true
+	module.is_module = true
+	modules = append(modules, module)
+	for _, statement := range lsp.Modules[module.path] {
+		if statement.kind != "@forward" {
+			continue
+		}
+		target, ok := lsp.resolveModule(module.path, statement.url)
+		if !ok {
+			continue
+		}
+		forwards := append([]moduleStatement{statement}, module.forwards...)
+		modules = lsp.forwardedModules(visibleModule{path: target, namespace: module.namespace, forwards: forwards}, modules, seen)
+	}
+	return modules
+}
+
+// importedModules adds the file and everything it @imports, and what the
+// imported files forward, it is all global
+func (lsp *Lsp) importedModules(path string, is_imported bool, modules []visibleModule, seen map[string]bool) []visibleModule {
+	if seen[path] {
+		return modules
+	}
+	seen[path] = true
+	modules = append(modules, visibleModule{path: path})
+	for _, statement := range lsp.Modules[path] {
+		target, ok := lsp.resolveModule(path, statement.url)
+		if !ok {
+			continue
+		}
+		switch statement.kind {
+		case "@import":
+			modules = lsp.importedModules(target, true, modules, seen)
+		case "@forward":
+			// an @import of a file brings along what it forwards, a @forward
+			// in the file itself doesnt make anything visible there
+			if is_imported {
+				modules = lsp.forwardedModules(visibleModule{path: target, forwards: []moduleStatement{statement}}, modules, map[string]bool{})
+			}
+		}
+	}
+	return modules
+}
+
+// VisibleModules lists the files whose members can be used in the file, the
+// file itself comes first
+func (lsp *Lsp) VisibleModules(path string) []visibleModule {
+	modules := lsp.importedModules(path, false, []visibleModule{}, map[string]bool{})
+	for _, statement := range lsp.Modules[path] {
+		if statement.kind != "@use" {
+			continue
+		}
+		target, ok := lsp.resolveModule(path, statement.url)
+		if !ok {
+			continue
+		}
+		namespace := statement.namespace
+		if namespace == "*" {
+			namespace = ""
+		}
+		modules = lsp.forwardedModules(visibleModule{path: target, namespace: namespace}, modules, map[string]bool{})
+	}
+	return modules
+}
+
+// freeNamespace picks a namespace for a new @use that doesnt clash with the
+// ones the file already has
+func (lsp *Lsp) freeNamespace(path string, namespace string) string {
+	taken := map[string]bool{}
+	for _, statement := range lsp.Modules[path] {
+		if statement.kind == "@use" {
+			taken[statement.namespace] = true
+		}
+	}
+	free := namespace
+	for idx := 2; taken[free]; idx++ {
+		free = fmt.Sprintf("%s%d", namespace, idx)
+	}
+	return free
+}
+
+// declarationOf finds the declaration node of a variable
+func declarationOf(tree *sitter.Tree, entry isDefined) *sitter.Node {
+	node := tree.RootNode().NamedDescendantForPointRange(entry.start_position, entry.end_position)
+	for node != nil && node.Type() != "declaration" {
+		node = node.Parent()
+	}
+	return node
+}
+
+// isTopLevelVariable checks that a variable can be used from other files,
+// local variables of mixins and rule sets can not
+func (lsp *Lsp) isTopLevelVariable(path string, entry isDefined) bool {
+	tree := lsp.Trees[path]
+	input, err := lsp.bytesFromFilePath(path)
+	if tree == nil || err != nil {
+		return false
+	}
+	node := declarationOf(tree, entry)
+	if node == nil {
+		return false
+	}
+	return node.Parent() != nil && node.Parent().Type() == "stylesheet" ||
+		strings.Contains(node.Content(*input), "!global")
+}
+
+// namespacedName is how a member is written with a namespace
+func namespacedName(namespace string, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// a reference to a member of a module through its namespace, like
+// math.div(...) or colors.$primary, the grammar doesnt know about these
+type namespacedReference struct {
+	namespace string
+	member    string
+	item_type string
+	// the range of the whole reference, namespace included
+	start_position sitter.Point
+	end_position   sitter.Point
+}
+
+var namespacedRegex = regexp.MustCompile(`([A-Za-z_][\w-]*)\.(\$?[A-Za-z_][\w-]*)`)
+
+// maskCommentsAndStrings blanks out comments and strings so text searches
+// dont find things in them, offsets and newlines stay the same
+func maskCommentsAndStrings(input []byte) []byte {
+	masked := make([]byte, len(input))
+	copy(masked, input)
+	blank := func(from int, to int) {
+		for idx := from; idx < to && idx < len(masked); idx++ {
+			if masked[idx] != '\n' {
+				masked[idx] = ' '
+			}
+		}
+	}
+	for idx := 0; idx < len(input); idx++ {
+		switch {
+		case input[idx] == '"' || input[idx] == '\'':
+			end := idx + 1
+			for end < len(input) && input[end] != input[idx] && input[end] != '\n' {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			// the quotes stay, a string is still a string
+			blank(idx+1, end)
+			idx = end
+		case input[idx] == '/' && idx+1 < len(input) && input[idx+1] == '/':
+			end := idx
+			for end < len(input) && input[end] != '\n' {
+				end++
+			}
+			blank(idx, end)
+			idx = end
+		case input[idx] == '/' && idx+1 < len(input) && input[idx+1] == '*':
+			end := strings.Index(string(input[idx+2:]), "*/")
+			if end == -1 {
+				end = len(input)
+			} else {
+				end += idx + 4
+			}
+			blank(idx, end)
+			idx = end - 1
+		}
+	}
+	return masked
+}
+
+// namespacedReferences finds the references to the namespaces of the @use
+// statements of the file
+func (lsp *Lsp) namespacedReferences(path string, input []byte) []namespacedReference {
+	references := []namespacedReference{}
+	namespaces := map[string]bool{}
+	for _, statement := range lsp.Modules[path] {
+		if statement.kind == "@use" && statement.namespace != "*" {
+			namespaces[statement.namespace] = true
+		}
+	}
+	if len(namespaces) == 0 {
+		return references
+	}
+	text := maskCommentsAndStrings(input)
+	for _, match := range namespacedRegex.FindAllSubmatchIndex(text, -1) {
+		start, end := match[0], match[1]
+		namespace := string(text[match[2]:match[3]])
+		member := string(text[match[4]:match[5]])
+		if !namespaces[namespace] || (start > 0 && (isWordChar(text[start-1]) || text[start-1] == '#')) {
+			continue
+		}
+		reference := namespacedReference{namespace: namespace, member: member}
+		is_include := strings.HasSuffix(strings.TrimRight(string(text[:start]), " \t"), "@include")
+		switch {
+		case strings.HasPrefix(member, "$"):
+			reference.item_type = itemTypeVariable
+		case is_include:
+			reference.item_type = itemTypeMixin
+		case end < len(text) && text[end] == '(':
+			reference.item_type = itemTypeFunction
+		default:
+			// something like an element with a class, a.active
+			continue
+		}
+		reference.start_position = pointAtOffset(sitter.Point{}, string(input), start)
+		reference.end_position = pointAtOffset(reference.start_position, string(input[start:]), end-start)
+		references = append(references, reference)
+	}
+	return references
+}
+
+// hasMember checks if the module behind the namespace has the member, known
+// is false when the module can not be found
+func (lsp *Lsp) hasMember(path string, reference namespacedReference) (exists bool, known bool) {
+	statement, ok := lsp.namespaceModule(path, reference.namespace)
+	if !ok {
+		return false, false
+	}
+	if builtin, ok := lookupBuiltinModule(statement.url); ok {
+		_, exists := builtin.member(reference.member, reference.item_type)
+		return exists, true
+	}
+	if strings.HasPrefix(statement.url, "sass:") {
+		return false, false
+	}
+	if _, ok := lsp.resolveModule(path, statement.url); !ok {
+		return false, false
+	}
+	definitions := lsp.definitionMap(reference.item_type)
+	for _, module := range lsp.VisibleModules(path) {
+		if module.namespace != reference.namespace || !module.is_module {
+			continue
+		}
+		for _, entry := range definitions[module.path] {
+			if reference.item_type == itemTypeVariable && !lsp.isTopLevelVariable(module.path, entry) {
+				continue
+			}
+			if name, ok := module.memberName(entry.name); ok && name == reference.member {
+				return true, true
+			}
+		}
+	}
+	return false, true
+}
+
+func (lsp *Lsp) memberDiagnostics(path string) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return diagnostics
+	}
+	for _, reference := range lsp.namespacedReferences(path, *input) {
+		if exists, known := lsp.hasMember(path, reference); exists || !known {
+			continue
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    rangeFromPoints(reference.start_position, reference.end_position),
+			Severity: protocol.DiagnosticSeverityError,
+			Code:     diagnosticUndefinedMember,
+			Source:   "SCSS-LSP",
+			Message:  fmt.Sprintf("undefined member, %s has no %s", reference.namespace, reference.member),
+		})
+	}
+	return diagnostics
+}
@@ -0,0 +1,277 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// one url of a @use, @forward or @import, an @import with multiple urls
+// becomes multiple statements
+type moduleStatement struct {
+	kind string
+	url  string
+	// the namespace for @use, "*" for `as *`
+	namespace string
+	// the prefix for @forward "x" as prefix-*
+	prefix string
+	show   []string
+	hide   []string
+	// whatever is inside the parens of `with (...)`
+	with string
+	// position of the url without the quotes
+	start_position sitter.Point
+	end_position   sitter.Point
+	// position of the whole statement
+	statement_start sitter.Point
+	statement_end   sitter.Point
+}
+
+var (
+	moduleStringRegex    = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+	moduleNamespaceRegex = regexp.MustCompile(`^\s*as\s+([\w-]+\*?|\*)`)
+	moduleShowHideRegex  = regexp.MustCompile(`\b(show|hide)\s+([^;]*?)\s*(?:\bwith\b|;|$)`)
+	moduleWithRegex      = regexp.MustCompile(`(?s)\bwith\s*\((.*)\)`)
+)
+
+// pointAtOffset walks the text to find the point of a byte offset in it
+func pointAtOffset(start sitter.Point, text string, offset int) sitter.Point {
+	point := start
+	for _, char := range []byte(text[:offset]) {
+		if char == '\n' {
+			point.Row++
+			point.Column = 0
+		} else {
+			point.Column++
+		}
+	}
+	return point
+}
+
+// defaultNamespace is what sass uses when there is no `as`
+func defaultNamespace(url string) string {
+	if strings.HasPrefix(url, "sass:") {
+		return strings.TrimPrefix(url, "sass:")
+	}
+	name := url
+	if idx := strings.LastIndex(name, "/"); idx != -1 {
+		name = name[idx+1:]
+	}
+	name = strings.TrimPrefix(name, "_")
+	if idx := strings.Index(name, "."); idx != -1 {
+		name = name[:idx]
+	}
+	return name
+}
+
+// parseModuleStatement does the work that the grammar cant, `as`, `show`,
+// `hide` and `with` all end up in ERROR nodes
+func parseModuleStatement(node *sitter.Node, input *[]byte) []moduleStatement {
+	statements := []moduleStatement{}
+	text := node.Content(*input)
+	kind := node.Type()
+	switch kind {
+	case "use_statement":
+		kind = "@use"
+	case "forward_statement":
+		kind = "@forward"
+	case "import_statement":
+		kind = "@import"
+	}
+
+	for _, match := range moduleStringRegex.FindAllStringSubmatchIndex(text, -1) {
+		url_start, url_end := match[2], match[3]
+		if url_start == -1 {
+			url_start, url_end = match[4], match[5]
+		}
+		statement := moduleStatement{
+			kind:            kind,
+			url:             text[url_start:url_end],
+			start_position:  pointAtOffset(node.StartPoint(), text, url_start),
+			end_position:    pointAtOffset(node.StartPoint(), text, url_end),
+			statement_start: node.StartPoint(),
+			statement_end:   node.EndPoint(),
+		}
+		rest := text[match[1]:]
+		switch kind {
+		case "@use":
+			statement.namespace = defaultNamespace(statement.url)
+			if namespace := moduleNamespaceRegex.FindStringSubmatch(rest); namespace != nil {
+				statement.namespace = namespace[1]
+			}
+		case "@forward":
+			if prefix := moduleNamespaceRegex.FindStringSubmatch(rest); prefix != nil {
+				statement.prefix = strings.TrimSuffix(prefix[1], "*")
+			}
+			for _, show_hide := range moduleShowHideRegex.FindAllStringSubmatch(rest, -1) {
+				members := []string{}
+				for _, member := range strings.Split(show_hide[2], ",") {
+					if member = strings.TrimSpace(member); member != "" {
+						members = append(members, member)
+					}
+				}
+				if show_hide[1] == "show" {
+					statement.show = members
+				} else {
+					statement.hide = members
+				}
+			}
+		}
+		if kind != "@import" {
+			if with := moduleWithRegex.FindStringSubmatch(rest); with != nil {
+				statement.with = with[1]
+			}
+		}
+		statements = append(statements, statement)
+		// only @import can have more than one url
+		if kind != "@import" {
+			break
+		}
+	}
+	return statements
+}
+
+func (lsp *Lsp) fileExists(path string) bool {
+	if lsp.Trees[path] != nil {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// moduleCandidates lists the files sass would try for an url, in order
+func moduleCandidates(path string) []string {
+	dir, name := filepath.Split(path)
+	switch filepath.Ext(name) {
+	case ".scss", ".sass", ".css":
+		return []string{path, filepath.Join(dir, "_"+name)}
+	}
+	candidates := []string{}
+	for _, extension := range []string{".scss", ".sass", ".css"} {
+		candidates = append(candidates, filepath.Join(dir, "_"+name+extension), filepath.Join(dir, name+extension))
+	}
+	for _, extension := range []string{".scss", ".sass", ".css"} {
+		candidates = append(candidates, filepath.Join(path, "_index"+extension), filepath.Join(path, "index"+extension))
+	}
+	return candidates
+}
+
+// resolveModule finds the file behind the url of a @use, @forward or @import,
+// first relative to the file then in the load paths
+func (lsp *Lsp) resolveModule(from_path string, url string) (string, bool) {
+	if url == "" || strings.HasPrefix(url, "sass:") || strings.Contains(url, "://") || strings.HasPrefix(url, "//") {
+		return "", false
+	}
+	bases := []string{filepath.Dir(from_path)}
+	if strings.HasPrefix(url, "~") {
+		// webpack style imports from node_modules
+		url = strings.TrimPrefix(url, "~")
+		bases = []string{filepath.Join(lsp.RootPath, "node_modules")}
+	}
+	if lsp.Config != nil {
+		bases = append(bases, lsp.rootRelative(lsp.Config.LoadPaths)...)
+	}
+	for _, base := range bases {
+		for _, candidate := range moduleCandidates(filepath.Join(base, url)) {
+			if lsp.fileExists(candidate) {
+				return candidate, true
+			}
+		}
+	}
+	return "", false
+}
+
+// resolveAsset finds the file behind the url of url(...)
+func (lsp *Lsp) resolveAsset(from_path string, url string) (string, bool) {
+	if idx := strings.IndexAny(url, "?#"); idx != -1 {
+		url = url[:idx]
+	}
+	if url == "" || strings.HasPrefix(url, "data:") || strings.Contains(url, "://") || strings.HasPrefix(url, "//") || strings.Contains(url, "#{") {
+		return "", false
+	}
+	bases := []string{}
+	if !strings.HasPrefix(url, "/") {
+		bases = append(bases, filepath.Dir(from_path))
+	}
+	if lsp.Config != nil {
+		bases = append(bases, lsp.rootRelative(lsp.Config.AssetRoots)...)
+	}
+	for _, base := range bases {
+		candidate := filepath.Join(base, strings.TrimPrefix(url, "/"))
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// urlArguments returns the url(...) calls in the tree with the range of the
+// url itself
+func urlArguments(tree *sitter.Tree, input *[]byte) []isDefined {
+	urls := []isDefined{}
+	walkNamed(tree.RootNode(), func(node *sitter.Node) {
+		if node.Type() != "call_expression" || node.NamedChildCount() < 2 {
+			return
+		}
+		if node.NamedChild(0).Content(*input) != "url" || parentType(node) == "import_statement" {
+			return
+		}
+		arguments := node.NamedChild(1)
+		if arguments.NamedChildCount() != 1 {
+			return
+		}
+		argument := arguments.NamedChild(0)
+		start_position := argument.StartPoint()
+		end_position := argument.EndPoint()
+		text := argument.Content(*input)
+		if argument.Type() == "string_value" && len(text) >= 2 {
+			text = text[1 : len(text)-1]
+			start_position.Column++
+			end_position.Column--
+		}
+		urls = append(urls, isDefined{name: text, body: node.Content(*input), start_position: start_position, end_position: end_position})
+	})
+	return urls
+}
+
+func (lsp *Lsp) GetDocumentLinks(path string) []protocol.DocumentLink {
+	links := []protocol.DocumentLink{}
+	tree := lsp.Trees[path]
+	if tree == nil {
+		return links
+	}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return links
+	}
+
+	for _, statement := range lsp.Modules[path] {
+		target, ok := lsp.resolveModule(path, statement.url)
+		if !ok {
+			continue
+		}
+		links = append(links, protocol.DocumentLink{
+			Range:   rangeFromPoints(statement.start_position, statement.end_position),
+			Target:  protocol.DocumentURI(uri.File(target)),
+			Tooltip: target,
+		})
+	}
+
+	for _, url := range urlArguments(tree, input) {
+		target, ok := lsp.resolveAsset(path, url.name)
+		if !ok {
+			continue
+		}
+		links = append(links, protocol.DocumentLink{
+			Range:   rangeFromPoints(url.start_position, url.end_position),
+			Target:  protocol.DocumentURI(uri.File(target)),
+			Tooltip: target,
+		})
+	}
+	return links
+}
@@ -0,0 +1,97 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/uri"
+)
+
+// writeFiles creates the files under the root, the directories are created
+// as needed
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func TestParseModuleStatements(t *testing.T) {
+	path := "/virtual/modules.scss"
+	lsp := lspWithSources(t, map[string]string{path: `@use "sass:math";
+@use "../abstracts/mixins" as mx;
+@use "theme" as *;
+@forward "src/list" as list-* hide reset, $gap;
+@import "foo", "bar";
+`})
+	modules := lsp.Modules[path]
+	expected := []moduleStatement{
+		{kind: "@use", url: "sass:math", namespace: "math"},
+		{kind: "@use", url: "../abstracts/mixins", namespace: "mx"},
+		{kind: "@use", url: "theme", namespace: "*"},
+		{kind: "@forward", url: "src/list", prefix: "list-"},
+		{kind: "@import", url: "foo"},
+		{kind: "@import", url: "bar"},
+	}
+	if len(modules) != len(expected) {
+		t.Fatalf("expected %d modules, got %d: %+v", len(expected), len(modules), modules)
+	}
+	for idx := range modules {
+		if modules[idx].kind != expected[idx].kind || modules[idx].url != expected[idx].url ||
+			modules[idx].namespace != expected[idx].namespace || modules[idx].prefix != expected[idx].prefix {
+			t.Fatalf("expected %+v, got %+v at %v", expected[idx], modules[idx], idx)
+		}
+	}
+	if len(modules[3].hide) != 2 || modules[3].hide[0] != "reset" || modules[3].hide[1] != "$gap" {
+		t.Fatalf("expected hide [reset $gap], got %v", modules[3].hide)
+	}
+	if modules[1].start_position.Row != 1 || modules[1].start_position.Column != 6 || modules[1].end_position.Column != 25 {
+		t.Fatalf("wrong url position %+v %+v", modules[1].start_position, modules[1].end_position)
+	}
+}
+
+func TestDocumentLinks(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"scss/main.scss": `@use "abstracts/mixins" as mx;
+@import "vars", "missing";
+.a { background: url("../img/a.png"); }
+.b { src: url(/fonts/x.woff2?#iefix); }
+`,
+		"scss/abstracts/_mixins.scss": "@mixin a() {}\n",
+		"scss/_vars.scss":             "$a: 1;\n",
+		"img/a.png":                   "",
+		"web/fonts/x.woff2":           "",
+		configFileName:                `{"assetRoots": ["web"]}`,
+	})
+	lsp := DefaultLsp()
+	lsp.RootPath = root
+	lsp.LoadConfig(nil)
+	lsp.WalkFromRoot()
+
+	links := lsp.GetDocumentLinks(filepath.Join(root, "scss/main.scss"))
+	expected := []string{
+		filepath.Join(root, "scss/abstracts/_mixins.scss"),
+		filepath.Join(root, "scss/_vars.scss"),
+		filepath.Join(root, "img/a.png"),
+		filepath.Join(root, "web/fonts/x.woff2"),
+	}
+	if len(links) != len(expected) {
+		t.Fatalf("expected %d links, got %d: %+v", len(expected), len(links), links)
+	}
+	for idx := range links {
+		if uri.URI(links[idx].Target).Filename() != expected[idx] {
+			t.Fatalf("expected %s, got %s at %v", expected[idx], links[idx].Target, idx)
+		}
+	}
+	if links[2].Range.Start.Character != 22 || links[2].Range.End.Character != 34 {
+		t.Fatalf("wrong url range %+v", links[2].Range)
+	}
+}
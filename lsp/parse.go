@@ -19,6 +19,10 @@ type Parser struct {
 	mixinCallQuery    *sitter.Query
 	functionCallQuery *sitter.Query
 	variableCallQuery *sitter.Query
+	extendQuery       *sitter.Query
+	moduleQuery       *sitter.Query
+	placeholderQuery  *sitter.Query
+	propertyQuery     *sitter.Query
 }
 func NewParser() *Parser {
 	parser := sitter.NewParser()
@@ -33,15 +37,21 @@ func NewParser() *Parser {
 	mixinCallQuery, err6 := sitter.NewQuery([]byte("(include_statement (identifier) @dec)"), binding.GetLanguage())
 	functionCallQuery, err7 := sitter.NewQuery([]byte("(call_expression (function_name) @dec)"), binding.GetLanguage())
 	variableCallQuery, err8 := sitter.NewQuery([]byte("(variable_value) @dec"), binding.GetLanguage())
+	// @extend %placeholder doesnt parse into an extend_statement, so match the
+	// keyword itself, it shows up inside ERROR nodes too
+	extendQuery, err9 := sitter.NewQuery([]byte(`"@extend" @dec`), binding.GetLanguage())
+	moduleQuery, err10 := sitter.NewQuery([]byte("[(use_statement) (forward_statement) (import_statement)] @dec"), binding.GetLanguage())
+	placeholderQuery, err11 := sitter.NewQuery([]byte("(placeholder) @dec"), binding.GetLanguage())
+	propertyQuery, err12 := sitter.NewQuery([]byte("(declaration (property_name) @dec)"), binding.GetLanguage())
 
-	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || err6 != nil || err7 != nil || err8 != nil {
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || err6 != nil || err7 != nil || err8 != nil || err9 != nil || err10 != nil || err11 != nil || err12 != nil {
 		fmt.Println(err1)
 		fmt.Println(err2)
 		fmt.Println(err3)
 		fmt.Println(err4)
 		fmt.Println(err5)
     // excellent error handling
-    panic(fmt.Errorf("%v %v %v %v %v %v %v %v", err1, err2, err3, err4, err5, err6, err7, err8))
+    panic(fmt.Errorf("%v %v %v %v %v %v %v %v %v %v %v %v", err1, err2, err3, err4, err5, err6, err7, err8, err9, err10, err11, err12))
   }
 
 	return &Parser{
@@ -54,6 +64,10 @@ func NewParser() *Parser {
 		mixinCallQuery:    mixinCallQuery,
 		functionCallQuery: functionCallQuery,
 		variableCallQuery: variableCallQuery,
+		extendQuery:       extendQuery,
+		moduleQuery:       moduleQuery,
+		placeholderQuery:  placeholderQuery,
+		propertyQuery:     propertyQuery,
 	}
 }
 
@@ -143,7 +157,7 @@ func (p *Parser) ParseMixinsInTree(tree *sitter.Tree, input *[]byte) []isDefined
 		body := name.Content(*input) + parameters.Content(*input)
 		start_position := mixin_statement_node.StartPoint()
 		end_position := mixin_statement_node.EndPoint()
-		mixins = append(mixins, isDefined{name: name.Content(*input), body: body, start_position: start_position, end_position: end_position})
+		mixins = append(mixins, isDefined{name: name.Content(*input), body: body, start_position: start_position, end_position: end_position, doc: sassDocBefore(*input, mixin_statement_node.StartByte())})
 	}
 	return mixins
 }
@@ -167,7 +181,7 @@ func (p *Parser) ParseFunctionsInTree(tree *sitter.Tree, input *[]byte) []isDefi
 		body := name.Content(*input) + parameters.Content(*input)
 		start_position := function_statement_node.StartPoint()
 		end_position := function_statement_node.EndPoint()
-		functions = append(functions, isDefined{name: name.Content(*input), body: body, start_position: start_position, end_position: end_position})
+		functions = append(functions, isDefined{name: name.Content(*input), body: body, start_position: start_position, end_position: end_position, doc: sassDocBefore(*input, function_statement_node.StartByte())})
 	}
 	return functions
 }
@@ -176,9 +190,12 @@ func (p *Parser) ParseCalls(tree *sitter.Tree, input *[]byte) []isDefined {
 	cursor := sitter.NewQueryCursor()
 	root := tree.RootNode()
 	queries := []*sitter.Query{p.mixinCallQuery, p.functionCallQuery, p.variableCallQuery}
+	item_types := []string{itemTypeMixin, itemTypeFunction, itemTypeVariable}
 	captures := []isDefined{}
+	// calls in the same mixin share the same caller
+	callers := map[uint32]*isDefined{}
 
-	for _, query := range queries {
+	for idx, query := range queries {
 		cursor.Exec(query, root)
 		for {
 			match, ok := cursor.NextMatch()
@@ -189,13 +206,76 @@ func (p *Parser) ParseCalls(tree *sitter.Tree, input *[]byte) []isDefined {
 			text := node.Content(*input)
 			start_position := node.StartPoint()
 			end_position := node.EndPoint()
-			captures = append(captures, isDefined{name: text, body: text, start_position: start_position, end_position: end_position})
+			var caller *isDefined
+			if caller_node := enclosingCaller(node); caller_node != nil {
+				caller = callers[caller_node.StartByte()]
+				if caller == nil {
+					caller = p.parseCaller(caller_node, input)
+					callers[caller_node.StartByte()] = caller
+				}
+			}
+			captures = append(captures, isDefined{name: text, body: text, start_position: start_position, end_position: end_position, item_type: item_types[idx], caller: caller})
 		}
 	}
 
 	return captures
 }
 
+// enclosingCaller finds the mixin or function a call is in, calls that are in
+// neither belong to the closest rule set
+func enclosingCaller(node *sitter.Node) *sitter.Node {
+	var rule_set *sitter.Node
+	for ancestor := node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		switch ancestor.Type() {
+		case "mixin_statement", "function_statement":
+			return ancestor
+		case "rule_set":
+			if rule_set == nil {
+				rule_set = ancestor
+			}
+		}
+	}
+	return rule_set
+}
+
+// enclosingExtender finds the selector that gets the styles of an @extend, a
+// mixin if the @extend is directly in one
+func enclosingExtender(node *sitter.Node) *sitter.Node {
+	for ancestor := node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		switch ancestor.Type() {
+		case "rule_set", "placeholder", "mixin_statement":
+			return ancestor
+		}
+	}
+	return nil
+}
+
+func (p *Parser) parseCaller(node *sitter.Node, input *[]byte) *isDefined {
+	caller := &isDefined{start_position: node.StartPoint(), end_position: node.EndPoint()}
+	switch node.Type() {
+	case "placeholder":
+		caller.item_type = itemTypePlaceholder
+		caller.name = "%" + node.NamedChild(0).Content(*input)
+		caller.body = caller.name
+	case "mixin_statement", "function_statement":
+		caller.item_type = itemTypeMixin
+		if node.Type() == "function_statement" {
+			caller.item_type = itemTypeFunction
+		}
+		name := node.NamedChild(0)
+		caller.name = name.Content(*input)
+		caller.body = caller.name
+		if parameters := node.NamedChild(1); parameters != nil && parameters.Type() == "parameters" {
+			caller.body += parameters.Content(*input)
+		}
+	default:
+		caller.item_type = itemTypeRuleSet
+		caller.name = p.parseRuleSet(node, input)
+		caller.body = caller.name
+	}
+	return caller
+}
+
 func (p *Parser) ParseVariablesInTree(tree *sitter.Tree, input *[]byte) []isDefined {
 	cursor := sitter.NewQueryCursor()
 	root := tree.RootNode()
@@ -213,7 +293,114 @@ func (p *Parser) ParseVariablesInTree(tree *sitter.Tree, input *[]byte) []isDefi
 		body := declaration_node.Content(*input)
 		start_position := declaration_node.StartPoint()
 		end_position := declaration_node.EndPoint()
-		variables = append(variables, isDefined{name: name.Content(*input), body: body, start_position: start_position, end_position: end_position})
+		variables = append(variables, isDefined{name: name.Content(*input), body: body, start_position: start_position, end_position: end_position, doc: sassDocBefore(*input, declaration_node.StartByte())})
 	}
 	return variables
 }
+
+// ParseExtendsInTree returns the targets of every @extend in the tree, the name
+// is the selector being extended, like "%placeholder" or ".class"
+func (p *Parser) ParseExtendsInTree(tree *sitter.Tree, input *[]byte) []isDefined {
+	cursor := sitter.NewQueryCursor()
+	root := tree.RootNode()
+	cursor.Exec(p.extendQuery, root)
+	extends := make([]isDefined, 0)
+	for {
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+		keyword := match.Captures[0].Node
+		// the target is everything after the keyword until the semicolon
+		var first, last *sitter.Node
+		for sibling := keyword.NextSibling(); sibling != nil; sibling = sibling.NextSibling() {
+			if sibling.Type() == ";" || sibling.Type() == "}" || strings.HasPrefix(sibling.Type(), "@") {
+				break
+			}
+			if first == nil {
+				first = sibling
+			}
+			last = sibling
+		}
+		if first == nil {
+			continue
+		}
+		text := string((*input)[first.StartByte():last.EndByte()])
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "!optional"))
+		if text == "" {
+			continue
+		}
+		start_position := first.StartPoint()
+		end_position := sitter.Point{Row: start_position.Row, Column: start_position.Column + uint32(len(text))}
+		item_type := itemTypeRuleSet
+		if strings.HasPrefix(text, "%") {
+			item_type = itemTypePlaceholder
+		}
+		var caller *isDefined
+		if extender := enclosingExtender(keyword); extender != nil {
+			caller = p.parseCaller(extender, input)
+		}
+		extends = append(extends, isDefined{name: text, body: "@extend " + text, start_position: start_position, end_position: end_position, item_type: item_type, caller: caller})
+	}
+	return extends
+}
+
+// ParseModulesInTree returns every @use, @forward and @import in the tree
+func (p *Parser) ParseModulesInTree(tree *sitter.Tree, input *[]byte) []moduleStatement {
+	cursor := sitter.NewQueryCursor()
+	root := tree.RootNode()
+	cursor.Exec(p.moduleQuery, root)
+	modules := make([]moduleStatement, 0)
+	for {
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+		modules = append(modules, parseModuleStatement(match.Captures[0].Node, input)...)
+	}
+	return modules
+}
+
+// ParsePlaceholdersInTree returns the %placeholder selectors, the name
+// includes the %
+func (p *Parser) ParsePlaceholdersInTree(tree *sitter.Tree, input *[]byte) []isDefined {
+	cursor := sitter.NewQueryCursor()
+	root := tree.RootNode()
+	cursor.Exec(p.placeholderQuery, root)
+	placeholders := make([]isDefined, 0)
+	for {
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+		// always placeholder node
+		placeholder_node := match.Captures[0].Node
+		name := "%" + placeholder_node.NamedChild(0).Content(*input)
+		start_position := placeholder_node.StartPoint()
+		end_position := placeholder_node.EndPoint()
+		placeholders = append(placeholders, isDefined{name: name, body: name, start_position: start_position, end_position: end_position, doc: sassDocBefore(*input, placeholder_node.StartByte())})
+	}
+	return placeholders
+}
+
+// ParsePropertiesInTree returns the property of every declaration, the body
+// is the whole declaration
+func (p *Parser) ParsePropertiesInTree(tree *sitter.Tree, input *[]byte) []isDefined {
+	cursor := sitter.NewQueryCursor()
+	root := tree.RootNode()
+	cursor.Exec(p.propertyQuery, root)
+	properties := make([]isDefined, 0)
+	for {
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+		// always property_name node
+		property_node := match.Captures[0].Node
+		start_position := property_node.StartPoint()
+		end_position := property_node.EndPoint()
+		body := property_node.Parent().Content(*input)
+		properties = append(properties, isDefined{name: property_node.Content(*input), body: body, start_position: start_position, end_position: end_position})
+	}
+	return properties
+}
@@ -2,21 +2,26 @@ package lsp
 
 import (
 	sitter "github.com/smacker/go-tree-sitter"
+	"os"
 	"testing"
 )
 
 func TestAndSelectors(t *testing.T) {
 	lsp := DefaultLsp()
 
-	lsp.RootPath = "/home/ron/programs/scss-lsp/test_dir"
-	test_tree := "/home/ron/programs/scss-lsp/test_dir/file_a.scss"
+	lsp.RootPath = "../test_dir"
+	test_tree := "../test_dir/file_a.scss"
 
 	if lsp == nil {
 		t.Fatalf("failed to create lsp")
 	}
 	lsp.WalkFromRoot()
 	local_parser := NewParser()
-	entries := local_parser.ParseTree(lsp.Trees[test_tree])
+	input, err := os.ReadFile(test_tree)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", test_tree, err)
+	}
+	entries := local_parser.ParseTree(lsp.Trees[test_tree], &input)
 	// TODO TEST FOR POSITIONS
 	expected := []Entry{
 		{
@@ -42,15 +47,19 @@ func TestAndSelectors(t *testing.T) {
 func TestTreeParse(t *testing.T) {
 	lsp := DefaultLsp()
 
-	lsp.RootPath = "/home/ron/programs/scss-lsp/test_dir"
-	test_tree := "/home/ron/programs/scss-lsp/test_dir/file_b.scss"
+	lsp.RootPath = "../test_dir"
+	test_tree := "../test_dir/file_b.scss"
 
 	if lsp == nil {
 		t.Fatalf("failed to create lsp")
 	}
 	lsp.WalkFromRoot()
 	local_parser := NewParser()
-	entries := local_parser.ParseTree(lsp.Trees[test_tree])
+	input, err := os.ReadFile(test_tree)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", test_tree, err)
+	}
+	entries := local_parser.ParseTree(lsp.Trees[test_tree], &input)
 	// TODO TEST FOR POSITIONS
 	expected := []Entry{
 		{
@@ -137,8 +146,8 @@ func TestTreeParse(t *testing.T) {
 func TestMixinParse(t *testing.T) {
 	lsp := DefaultLsp()
 
-	lsp.RootPath = "/home/ron/programs/scss-lsp/test_dir"
-	test_tree := "/home/ron/programs/scss-lsp/test_dir/mixins_functions.scss"
+	lsp.RootPath = "../test_dir"
+	test_tree := "../test_dir/mixins_functions.scss"
 
 	if lsp == nil {
 		t.Fatalf("failed to create lsp")
@@ -146,8 +155,12 @@ func TestMixinParse(t *testing.T) {
 
 	lsp.WalkFromRoot()
 	local_parser := NewParser()
+	input, err := os.ReadFile(test_tree)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", test_tree, err)
+	}
 
-	entries := local_parser.ParseMixinsInTree(lsp.Trees[test_tree])
+	entries := local_parser.ParseMixinsInTree(lsp.Trees[test_tree], &input)
 	expected := []isDefined{
 		{
 			name:     "test_mixin_a",
@@ -184,8 +197,8 @@ func TestMixinParse(t *testing.T) {
 func TestFunctionParse(t *testing.T) {
 	lsp := DefaultLsp()
 
-	lsp.RootPath = "/home/ron/programs/scss-lsp/test_dir"
-	test_tree := "/home/ron/programs/scss-lsp/test_dir/mixins_functions.scss"
+	lsp.RootPath = "../test_dir"
+	test_tree := "../test_dir/mixins_functions.scss"
 
 	if lsp == nil {
 		t.Fatalf("failed to create lsp")
@@ -193,8 +206,12 @@ func TestFunctionParse(t *testing.T) {
 
 	lsp.WalkFromRoot()
 	local_parser := NewParser()
+	input, err := os.ReadFile(test_tree)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", test_tree, err)
+	}
 
-	entries := local_parser.ParseFunctionsInTree(lsp.Trees[test_tree])
+	entries := local_parser.ParseFunctionsInTree(lsp.Trees[test_tree], &input)
 	expected := []isDefined{
 		{
 			name:     "test_function_a",
@@ -233,8 +250,8 @@ func TestFunctionParse(t *testing.T) {
 func TestVariablesParse(t *testing.T) {
 	lsp := DefaultLsp()
 
-	lsp.RootPath = "/home/ron/programs/scss-lsp/test_dir"
-	test_tree := "/home/ron/programs/scss-lsp/test_dir/variables.scss"
+	lsp.RootPath = "../test_dir"
+	test_tree := "../test_dir/variables.scss"
 
 	if lsp == nil {
 		t.Fatalf("failed to create lsp")
@@ -242,8 +259,12 @@ func TestVariablesParse(t *testing.T) {
 
 	lsp.WalkFromRoot()
 	local_parser := NewParser()
+	input, err := os.ReadFile(test_tree)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", test_tree, err)
+	}
 
-	entries := local_parser.ParseVariablesInTree(lsp.Trees[test_tree])
+	entries := local_parser.ParseVariablesInTree(lsp.Trees[test_tree], &input)
 	expected := []isDefined{
     {
       name: "$color1",
@@ -0,0 +1,285 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// the code of the diagnostic for properties that a later one in the same
+// block overrides
+const diagnosticDuplicateProperty = "duplicate-property"
+
+// the properties a shorthand sets, shorthands like border set other
+// shorthands, longhandsOf follows those
+var shorthandLonghands = map[string][]string{
+	"animation":       {"animation-name", "animation-duration", "animation-timing-function", "animation-delay", "animation-iteration-count", "animation-direction", "animation-fill-mode", "animation-play-state"},
+	"background":      {"background-color", "background-image", "background-position", "background-size", "background-repeat", "background-attachment", "background-origin", "background-clip"},
+	"border":          {"border-width", "border-style", "border-color", "border-top", "border-right", "border-bottom", "border-left"},
+	"border-top":      {"border-top-width", "border-top-style", "border-top-color"},
+	"border-right":    {"border-right-width", "border-right-style", "border-right-color"},
+	"border-bottom":   {"border-bottom-width", "border-bottom-style", "border-bottom-color"},
+	"border-left":     {"border-left-width", "border-left-style", "border-left-color"},
+	"border-width":    {"border-top-width", "border-right-width", "border-bottom-width", "border-left-width"},
+	"border-style":    {"border-top-style", "border-right-style", "border-bottom-style", "border-left-style"},
+	"border-color":    {"border-top-color", "border-right-color", "border-bottom-color", "border-left-color"},
+	"border-radius":   {"border-top-left-radius", "border-top-right-radius", "border-bottom-right-radius", "border-bottom-left-radius"},
+	"columns":         {"column-width", "column-count"},
+	"flex":            {"flex-grow", "flex-shrink", "flex-basis"},
+	"flex-flow":       {"flex-direction", "flex-wrap"},
+	"font":            {"font-style", "font-variant", "font-weight", "font-stretch", "font-size", "line-height", "font-family"},
+	"gap":             {"row-gap", "column-gap"},
+	"grid-area":       {"grid-row-start", "grid-column-start", "grid-row-end", "grid-column-end"},
+	"grid-column":     {"grid-column-start", "grid-column-end"},
+	"grid-row":        {"grid-row-start", "grid-row-end"},
+	"grid-template":   {"grid-template-rows", "grid-template-columns", "grid-template-areas"},
+	"inset":           {"top", "right", "bottom", "left"},
+	"list-style":      {"list-style-type", "list-style-position", "list-style-image"},
+	"margin":          {"margin-top", "margin-right", "margin-bottom", "margin-left"},
+	"outline":         {"outline-width", "outline-style", "outline-color"},
+	"overflow":        {"overflow-x", "overflow-y"},
+	"padding":         {"padding-top", "padding-right", "padding-bottom", "padding-left"},
+	"place-content":   {"align-content", "justify-content"},
+	"place-items":     {"align-items", "justify-items"},
+	"place-self":      {"align-self", "justify-self"},
+	"text-decoration": {"text-decoration-line", "text-decoration-style", "text-decoration-color", "text-decoration-thickness"},
+	"transition":      {"transition-property", "transition-duration", "transition-timing-function", "transition-delay"},
+}
+
+// a value with a vendor prefix is a fallback for browsers that need it, like
+// display: -webkit-box before display: flex
+var vendorValueRegex = regexp.MustCompile(`(^|[^\w-])-(webkit|moz|ms|o)-`)
+
+var importantRegex = regexp.MustCompile(`(?i)!\s*important`)
+
+// the units of the numbers in a value, hex colors left out
+var valueUnitRegex = regexp.MustCompile(`(?:^|[^\w#.-])(?:\d+\.?\d*|\.\d+)([a-zA-Z%]+)`)
+
+// the functions a value calls
+var valueFunctionRegex = regexp.MustCompile(`(?:^|[^\w-])([a-zA-Z_][\w-]*)\(`)
+
+// valueFeatures are the distinct matches of the first group of the regex
+func valueFeatures(regex *regexp.Regexp, value string) string {
+	features := map[string]bool{}
+	for _, match := range regex.FindAllStringSubmatch(value, -1) {
+		features[strings.ToLower(match[1])] = true
+	}
+	return strings.Join(sortedPaths(features), " ")
+}
+
+// isFallback checks if the earlier value is there for browsers that dont
+// know the later one, a vendor prefix, another unit like 100vh before 100dvh
+// or another function like red before linear-gradient(...)
+func isFallback(earlier string, later string) bool {
+	if vendorValueRegex.MatchString(earlier) || vendorValueRegex.MatchString(later) {
+		return true
+	}
+	if valueFeatures(valueFunctionRegex, earlier) != valueFeatures(valueFunctionRegex, later) {
+		return true
+	}
+	// 0 before 2px is a plain override
+	earlier_units, later_units := valueFeatures(valueUnitRegex, earlier), valueFeatures(valueUnitRegex, later)
+	return earlier_units != "" && later_units != "" && earlier_units != later_units
+}
+
+// longhandsOf are all the properties a shorthand sets
+func longhandsOf(property string) []string {
+	longhands := []string{}
+	for _, longhand := range shorthandLonghands[property] {
+		longhands = append(longhands, longhand)
+		longhands = append(longhands, longhandsOf(longhand)...)
+	}
+	return longhands
+}
+
+// a property that a block sets, with a declaration or an @include
+type blockProperty struct {
+	name  string
+	value string
+	// the property name of the declaration, or the name of the mixin of the
+	// @include
+	node *sitter.Node
+	// the mixin the property comes from, empty for declarations
+	mixin string
+}
+
+// statementBlock is the block of a mixin, rule set and the like
+func statementBlock(node *sitter.Node) *sitter.Node {
+	for idx := 0; idx < int(node.NamedChildCount()); idx++ {
+		if child := node.NamedChild(idx); child.Type() == "block" {
+			return child
+		}
+	}
+	return nil
+}
+
+// declaredProperty is the property and the value of a declaration, false for
+// variables and interpolated names
+func declaredProperty(node *sitter.Node, input []byte) (*sitter.Node, string, bool) {
+	if node.Type() != "declaration" || node.NamedChildCount() == 0 {
+		return nil, "", false
+	}
+	name := node.NamedChild(0)
+	if name.Type() != "property_name" || strings.Contains(name.Content(input), "#{") {
+		return nil, "", false
+	}
+	return name, declarationValue(node.Content(input)), true
+}
+
+// includedMixin is the name of the mixin of an @include, and its node
+func includedMixin(node *sitter.Node, input []byte) (*sitter.Node, string, bool) {
+	if node.Type() != "include_statement" {
+		return nil, "", false
+	}
+	for idx := 0; idx < int(node.NamedChildCount()); idx++ {
+		if child := node.NamedChild(idx); child.Type() == "identifier" {
+			return child, child.Content(input), true
+		}
+	}
+	return nil, "", false
+}
+
+// mixinProperties are the properties a mixin always sets, with the ones of
+// the mixins it includes, the ones in @if and the like are left out
+func (lsp *Lsp) mixinProperties(path string, name string, seen map[string]bool) []blockProperty {
+	properties := []blockProperty{}
+	definitions := lsp.scopedDefinitions(path, name, itemTypeMixin)
+	if len(definitions) == 0 || seen[definitions[0].path+" "+name] {
+		return properties
+	}
+	definition := definitions[0]
+	seen[definition.path+" "+name] = true
+	tree, input, ok := lsp.treeAndInput(definition.path)
+	if !ok {
+		return properties
+	}
+	node := tree.RootNode().NamedDescendantForPointRange(definition.is_defined.start_position, definition.is_defined.end_position)
+	if node == nil || node.Type() != "mixin_statement" {
+		return properties
+	}
+	block := statementBlock(node)
+	if block == nil {
+		return properties
+	}
+	for idx := 0; idx < int(block.NamedChildCount()); idx++ {
+		child := block.NamedChild(idx)
+		if property, value, ok := declaredProperty(child, input); ok {
+			properties = append(properties, blockProperty{name: strings.ToLower(property.Content(input)), value: value})
+		}
+		if _, mixin, ok := includedMixin(child, input); ok {
+			properties = append(properties, lsp.mixinProperties(definition.path, mixin, seen)...)
+		}
+	}
+	return properties
+}
+
+// blockProperties are the properties the block sets, in order
+func (lsp *Lsp) blockProperties(path string, block *sitter.Node, input []byte) []blockProperty {
+	properties := []blockProperty{}
+	for idx := 0; idx < int(block.NamedChildCount()); idx++ {
+		child := block.NamedChild(idx)
+		if property, value, ok := declaredProperty(child, input); ok {
+			properties = append(properties, blockProperty{name: strings.ToLower(property.Content(input)), value: value, node: property})
+		}
+		if node, mixin, ok := includedMixin(child, input); ok {
+			for _, property := range lsp.mixinProperties(path, mixin, map[string]bool{}) {
+				property.node = node
+				property.mixin = mixin
+				properties = append(properties, property)
+			}
+		}
+	}
+	return properties
+}
+
+// where a property is set, for the messages
+func (property blockProperty) place() string {
+	if property.mixin == "" {
+		return fmt.Sprintf("on line %d", property.node.StartPoint().Row+1)
+	}
+	return fmt.Sprintf("by @include %s on line %d", property.mixin, property.node.StartPoint().Row+1)
+}
+
+// duplicatePropertyDiagnostics finds properties that are set twice in a
+// block, longhands that a later shorthand overrides, and properties that an
+// @include sets again. The diagnostic is on the later one, which wins unless
+// the earlier one is !important. Option "ignore" is a list of properties that
+// can be set twice.
+func duplicatePropertyDiagnostics(context RuleContext, node *sitter.Node) []protocol.Diagnostic {
+	if node.Type() != "block" {
+		return nil
+	}
+	diagnostics := []protocol.Diagnostic{}
+	ignore := map[string]bool{}
+	for _, name := range stringsOption(context.Options, "ignore") {
+		ignore[name] = true
+	}
+	earlier := map[string]blockProperty{}
+	for _, property := range context.Lsp.blockProperties(context.Path, node, context.Input) {
+		if ignore[property.name] {
+			continue
+		}
+		// the other declaration of the pair
+		var other *blockProperty
+		related := ""
+		message := ""
+		previous, ok := earlier[property.name]
+		is_important := importantRegex.MatchString(property.value)
+		switch {
+		case ok && importantRegex.MatchString(previous.value) && !is_important:
+			// the !important one wins, this one has no effect
+			other = &previous
+			related = "the !important " + previous.name
+			if property.mixin == "" {
+				message = fmt.Sprintf("%s has no effect, the one set %s is !important", property.name, previous.place())
+			} else {
+				message = fmt.Sprintf("@include %s sets %s, which has no effect, the one set %s is !important", property.mixin, property.name, previous.place())
+			}
+		case ok && !isFallback(previous.value, property.value):
+			other = &previous
+			if property.mixin == "" {
+				message = fmt.Sprintf("%s is already set %s", property.name, previous.place())
+			} else {
+				message = fmt.Sprintf("@include %s sets %s, which is already set %s", property.mixin, property.name, previous.place())
+			}
+			earlier[property.name] = property
+		default:
+			for _, longhand := range longhandsOf(property.name) {
+				// a shorthand doesnt override an !important longhand
+				if previous, ok := earlier[longhand]; ok && (is_important || !importantRegex.MatchString(previous.value)) {
+					other = &previous
+					if property.mixin == "" {
+						message = fmt.Sprintf("%s overrides the %s set %s", property.name, longhand, previous.place())
+					} else {
+						message = fmt.Sprintf("@include %s sets %s, which overrides the %s set %s", property.mixin, property.name, longhand, previous.place())
+					}
+					break
+				}
+			}
+			earlier[property.name] = property
+		}
+		// the mixin itself is checked where it is defined
+		if other == nil || sameNode(other.node, property.node) {
+			continue
+		}
+		if related == "" {
+			related = "the " + other.name + " that has no effect"
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:   rangeFromPoints(property.node.StartPoint(), property.node.EndPoint()),
+			Message: message,
+			RelatedInformation: []protocol.DiagnosticRelatedInformation{{
+				Location: protocol.Location{
+					URI:   uri.File(context.Path),
+					Range: rangeFromPoints(other.node.StartPoint(), other.node.EndPoint()),
+				},
+				Message: related,
+			}},
+		})
+	}
+	return diagnostics
+}
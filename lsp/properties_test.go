@@ -0,0 +1,96 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestDuplicateProperties(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/_mixins.scss": "@mixin reset { margin: 0; @if true { color: red; } }\n@mixin base { @include reset; color: black; }\n",
+		path: `.a {
+  color: red;
+  margin-top: 1px;
+  color: blue;
+  margin: 0;
+}
+.b {
+  padding: 0;
+  padding-top: 1px;
+  display: -webkit-box;
+  display: flex;
+  border-top-color: red;
+  border: none;
+}
+.c {
+  color: white;
+  @include base;
+}
+.d {
+  @include reset;
+  margin-bottom: 1px;
+  margin: 2px;
+  .e { margin: 0; }
+}
+.f {
+  height: 100vh;
+  height: 100dvh;
+  background: red;
+  background: linear-gradient(red, blue);
+  width: 10px;
+  width: 20px;
+}
+.g {
+  color: red !important;
+  color: blue;
+  margin-top: 1px !important;
+  margin: 0;
+}
+`,
+	})
+
+	diagnostics := diagnosticsWithCode(lsp.getDiagnostics(path), diagnosticDuplicateProperty)
+	messages := []string{}
+	for _, diagnostic := range diagnostics {
+		messages = append(messages, diagnostic.Message)
+	}
+	expected := []string{
+		"color is already set on line 2",
+		"margin overrides the margin-top set on line 3",
+		"border overrides the border-top-color set on line 12",
+		"@include base sets color, which is already set on line 16",
+		"margin is already set by @include reset on line 20",
+		"width is already set on line 30",
+		"color has no effect, the one set on line 34 is !important",
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("unexpected diagnostics %q", messages)
+	}
+	for idx := range expected {
+		if messages[idx] != expected[idx] {
+			t.Fatalf("expected %q, got %q", expected[idx], messages[idx])
+		}
+	}
+	if diagnostics[0].Range.Start != (protocol.Position{Line: 3, Character: 2}) || diagnostics[0].RelatedInformation[0].Location.Range.Start.Line != 1 {
+		t.Fatalf("unexpected diagnostic %+v", diagnostics[0])
+	}
+	if diagnostics[3].Range.Start != (protocol.Position{Line: 16, Character: 11}) {
+		t.Fatalf("expected the diagnostic on the mixin name, got %+v", diagnostics[3])
+	}
+
+	if related := diagnostics[len(diagnostics)-1].RelatedInformation[0]; related.Message != "the !important color" || related.Location.Range.Start.Line != 33 {
+		t.Fatalf("expected the !important color as the related one, got %+v", related)
+	}
+
+	err := json.Unmarshal([]byte(`{"rules": {"duplicate-property": {"options": {"ignore": ["color", "margin"]}}}}`), lsp.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagnostics = diagnosticsWithCode(lsp.getDiagnostics(path), diagnosticDuplicateProperty)
+	if len(diagnostics) != 2 || diagnostics[0].Message != "border overrides the border-top-color set on line 12" {
+		t.Fatalf("unexpected diagnostics %+v", diagnostics)
+	}
+}
@@ -0,0 +1,388 @@
+package lsp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// the formats `scss-lsp check` can print
+const (
+	FormatHuman      = "human"
+	FormatJson       = "json"
+	FormatSarif      = "sarif"
+	FormatCheckstyle = "checkstyle"
+	FormatJunit      = "junit"
+)
+
+// the diagnostics of one file, the path is relative to the root
+type FileDiagnostics struct {
+	Path        string
+	Diagnostics []protocol.Diagnostic
+}
+
+var severityNames = map[string]protocol.DiagnosticSeverity{
+	"error":       protocol.DiagnosticSeverityError,
+	"warning":     protocol.DiagnosticSeverityWarning,
+	"information": protocol.DiagnosticSeverityInformation,
+	"info":        protocol.DiagnosticSeverityInformation,
+	"hint":        protocol.DiagnosticSeverityHint,
+}
+
+// ParseSeverity reads error, warning, information or hint
+func ParseSeverity(name string) (protocol.DiagnosticSeverity, bool) {
+	severity, ok := severityNames[strings.ToLower(name)]
+	return severity, ok
+}
+
+// severityName is the name of a severity, unset is an error like it is for
+// CountAtLeast and the client
+func severityName(severity protocol.DiagnosticSeverity) string {
+	switch severity {
+	case 0, protocol.DiagnosticSeverityError:
+		return "error"
+	case protocol.DiagnosticSeverityWarning:
+		return "warning"
+	case protocol.DiagnosticSeverityInformation:
+		return "information"
+	}
+	return "hint"
+}
+
+// Check runs the diagnostics of the server on the files under paths, every
+// file of the workspace without any
+func (lsp *Lsp) Check(paths []string) []FileDiagnostics {
+	results := []FileDiagnostics{}
+	for _, path := range sortedPaths(lsp.Trees) {
+		if len(paths) > 0 && !isUnderAny(path, paths) {
+			continue
+		}
+		diagnostics := lsp.getDiagnostics(path)
+		sort.SliceStable(diagnostics, func(i, j int) bool {
+			a, b := diagnostics[i].Range.Start, diagnostics[j].Range.Start
+			return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+		})
+		results = append(results, FileDiagnostics{Path: lsp.relativePath(path), Diagnostics: diagnostics})
+	}
+	return results
+}
+
+// isUnderAny is true when path is one of paths or in one of them
+func isUnderAny(path string, paths []string) bool {
+	for _, parent := range paths {
+		relative, err := filepath.Rel(parent, path)
+		if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CountAtLeast counts the diagnostics that are as bad as severity or worse
+func CountAtLeast(results []FileDiagnostics, severity protocol.DiagnosticSeverity) int {
+	count := 0
+	for _, result := range results {
+		for _, diagnostic := range result.Diagnostics {
+			// unset is an error for the client too
+			if diagnostic.Severity == 0 || diagnostic.Severity <= severity {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// FormatDiagnostics prints the results in one of the formats
+func FormatDiagnostics(format string, results []FileDiagnostics) (string, error) {
+	switch format {
+	case FormatHuman:
+		return humanReport(results), nil
+	case FormatJson:
+		return jsonReport(results)
+	case FormatSarif:
+		return sarifReport(results)
+	case FormatCheckstyle:
+		return checkstyleReport(results)
+	case FormatJunit:
+		return junitReport(results)
+	}
+	return "", fmt.Errorf("unknown format %s", format)
+}
+
+func humanReport(results []FileDiagnostics) string {
+	var sb strings.Builder
+	counts := map[string]int{}
+	total := 0
+	for _, result := range results {
+		for _, diagnostic := range result.Diagnostics {
+			severity := severityName(diagnostic.Severity)
+			counts[severity]++
+			total++
+			sb.WriteString(fmt.Sprintf("%s:%d:%d: %s: %s", result.Path, diagnostic.Range.Start.Line+1, diagnostic.Range.Start.Character+1, severity, diagnostic.Message))
+			if code := diagnosticCode(diagnostic); code != "" {
+				sb.WriteString(" [" + code + "]")
+			}
+			sb.WriteString("\n")
+		}
+	}
+	if total == 0 {
+		return "no problems\n"
+	}
+	summary := []string{}
+	for _, severity := range []string{"error", "warning", "information", "hint"} {
+		if counts[severity] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("%d problems (%s)\n", total, strings.Join(summary, ", ")))
+	return sb.String()
+}
+
+type jsonDiagnostic struct {
+	Path      string `json:"path"`
+	Line      uint32 `json:"line"`
+	Column    uint32 `json:"column"`
+	EndLine   uint32 `json:"endLine"`
+	EndColumn uint32 `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Code      string `json:"code,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Message   string `json:"message"`
+}
+
+func jsonReport(results []FileDiagnostics) (string, error) {
+	diagnostics := []jsonDiagnostic{}
+	for _, result := range results {
+		for _, diagnostic := range result.Diagnostics {
+			diagnostics = append(diagnostics, jsonDiagnostic{
+				Path:      result.Path,
+				Line:      diagnostic.Range.Start.Line + 1,
+				Column:    diagnostic.Range.Start.Character + 1,
+				EndLine:   diagnostic.Range.End.Line + 1,
+				EndColumn: diagnostic.Range.End.Character + 1,
+				Severity:  severityName(diagnostic.Severity),
+				Code:      diagnosticCode(diagnostic),
+				Source:    diagnostic.Source,
+				Message:   diagnostic.Message,
+			})
+		}
+	}
+	output, err := json.MarshalIndent(diagnostics, "", "  ")
+	return string(output) + "\n", err
+}
+
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html, only
+// what code scanning tools need
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleId    string          `json:"ruleId,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   uint32 `json:"startLine"`
+	StartColumn uint32 `json:"startColumn"`
+	EndLine     uint32 `json:"endLine"`
+	EndColumn   uint32 `json:"endColumn"`
+}
+
+func sarifReport(results []FileDiagnostics) (string, error) {
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "scss-lsp", Rules: []sarifRule{}}}, Results: []sarifResult{}}
+	rules := map[string]bool{}
+	for _, result := range results {
+		for _, diagnostic := range result.Diagnostics {
+			code := diagnosticCode(diagnostic)
+			if code != "" && !rules[code] {
+				rules[code] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{Id: code})
+			}
+			level := "note"
+			switch diagnostic.Severity {
+			case 0, protocol.DiagnosticSeverityError:
+				level = "error"
+			case protocol.DiagnosticSeverityWarning:
+				level = "warning"
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleId:  code,
+				Level:   level,
+				Message: sarifMessage{Text: diagnostic.Message},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{Uri: filepath.ToSlash(result.Path)},
+					Region: sarifRegion{
+						StartLine:   diagnostic.Range.Start.Line + 1,
+						StartColumn: diagnostic.Range.Start.Character + 1,
+						EndLine:     diagnostic.Range.End.Line + 1,
+						EndColumn:   diagnostic.Range.End.Character + 1,
+					},
+				}}},
+			})
+		}
+	}
+	output, err := json.MarshalIndent(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	return string(output) + "\n", err
+}
+
+type checkstyleReportXml struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     uint32 `xml:"line,attr"`
+	Column   uint32 `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+func checkstyleReport(results []FileDiagnostics) (string, error) {
+	report := checkstyleReportXml{Version: "4.3"}
+	for _, result := range results {
+		file := checkstyleFile{Name: result.Path}
+		for _, diagnostic := range result.Diagnostics {
+			severity := "info"
+			switch diagnostic.Severity {
+			case 0, protocol.DiagnosticSeverityError:
+				severity = "error"
+			case protocol.DiagnosticSeverityWarning:
+				severity = "warning"
+			}
+			file.Errors = append(file.Errors, checkstyleError{
+				Line:     diagnostic.Range.Start.Line + 1,
+				Column:   diagnostic.Range.Start.Character + 1,
+				Severity: severity,
+				Message:  diagnostic.Message,
+				Source:   "scss-lsp." + diagnosticCode(diagnostic),
+			})
+		}
+		report.Files = append(report.Files, file)
+	}
+	return marshalXml(report)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitReport has a test case for every file, a file with diagnostics fails
+// once with all of them, the schema allows only one failure per test case
+func junitReport(results []FileDiagnostics) (string, error) {
+	suite := junitTestSuite{Name: "scss-lsp", Tests: len(results)}
+	for _, result := range results {
+		test_case := junitTestCase{Name: result.Path, ClassName: "scss-lsp"}
+		if len(result.Diagnostics) > 0 {
+			lines := []string{}
+			codes := []string{}
+			seen := map[string]bool{}
+			for _, diagnostic := range result.Diagnostics {
+				line := fmt.Sprintf("%s:%d:%d: %s: %s", result.Path, diagnostic.Range.Start.Line+1, diagnostic.Range.Start.Character+1, severityName(diagnostic.Severity), diagnostic.Message)
+				if code := diagnosticCode(diagnostic); code != "" {
+					line += " [" + code + "]"
+					if !seen[code] {
+						seen[code] = true
+						codes = append(codes, code)
+					}
+				}
+				lines = append(lines, line)
+			}
+			message := result.Diagnostics[0].Message
+			if len(result.Diagnostics) > 1 {
+				message = fmt.Sprintf("%d problems", len(result.Diagnostics))
+			}
+			test_case.Failure = &junitFailure{
+				Message: message,
+				Type:    strings.Join(codes, " "),
+				Text:    strings.Join(lines, "\n"),
+			}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, test_case)
+	}
+	return marshalXml(junitTestSuites{Suites: []junitTestSuite{suite}})
+}
+
+func marshalXml(value interface{}) (string, error) {
+	output, err := xml.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(output) + "\n", nil
+}
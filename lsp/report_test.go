@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestCheck(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.scss":       ".a {\n  width: darken(red, 1%);\n  @include nope;\n}\n",
+		"themes/old.scss": ".b { @include missing; }\n",
+		"themes/ok.scss":  ".c { color: red; }\n",
+	})
+	lsp := LoadWorkspace(root)
+
+	results := lsp.Check(nil)
+	if len(results) != 3 {
+		t.Fatalf("expected every file, got %+v", results)
+	}
+	results = lsp.Check([]string{filepath.Join(root, "themes")})
+	if len(results) != 2 || results[0].Path != "themes/ok.scss" || len(results[1].Diagnostics) != 1 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	results = lsp.Check([]string{filepath.Join(root, "main.scss")})
+	if CountAtLeast(results, protocol.DiagnosticSeverityError) != 1 || CountAtLeast(results, protocol.DiagnosticSeverityWarning) != 2 {
+		t.Fatalf("unexpected counts for %+v", results)
+	}
+	human, _ := FormatDiagnostics(FormatHuman, results)
+	expected := "main.scss:2:10: warning: darken() is deprecated, use color.adjust from sass:color [deprecated-global-function]\n" +
+		"main.scss:3:12: error: undefined [undefined]\n" +
+		"2 problems (1 error, 1 warning)\n"
+	if human != expected {
+		t.Fatalf("unexpected report\n%s", human)
+	}
+
+	checkstyle, _ := FormatDiagnostics(FormatCheckstyle, results)
+	if !strings.Contains(checkstyle, `<error line="3" column="12" severity="error" message="undefined" source="scss-lsp.undefined"></error>`) {
+		t.Fatalf("unexpected report\n%s", checkstyle)
+	}
+	// one failure for the file, with every diagnostic
+	junit, _ := FormatDiagnostics(FormatJunit, results)
+	if !strings.Contains(junit, `<testsuite name="scss-lsp" tests="1" failures="1">`) || strings.Count(junit, "<failure ") != 1 ||
+		!strings.Contains(junit, `<failure message="2 problems" type="deprecated-global-function undefined">main.scss:2:10: warning: darken()`) ||
+		!strings.Contains(junit, "main.scss:3:12: error: undefined [undefined]</failure>") {
+		t.Fatalf("unexpected report\n%s", junit)
+	}
+	sarif, _ := FormatDiagnostics(FormatSarif, results)
+	var log sarifLog
+	if err := json.Unmarshal([]byte(sarif), &log); err != nil {
+		t.Fatal(err)
+	}
+	if len(log.Runs[0].Results) != 2 || log.Runs[0].Results[1].Level != "error" || log.Runs[0].Results[1].Locations[0].PhysicalLocation.Region.StartLine != 3 {
+		t.Fatalf("unexpected report\n%s", sarif)
+	}
+	// unset is an error in every report
+	unset := []FileDiagnostics{{Path: "a.scss", Diagnostics: []protocol.Diagnostic{{Message: "from a plugin"}}}}
+	report, _ := FormatDiagnostics(FormatJson, unset)
+	diagnostics := []jsonDiagnostic{}
+	if err := json.Unmarshal([]byte(report), &diagnostics); err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Severity != "error" {
+		t.Fatalf("unexpected report\n%s", report)
+	}
+	if _, err := FormatDiagnostics("xml", results); err == nil {
+		t.Fatalf("expected an error for an unknown format")
+	}
+}
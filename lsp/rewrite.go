@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// the command that rewrites every match of a query, the arguments are the
+// query, the replacement and the paths to look in, the result is a
+// RewriteResult to review and apply
+const commandRewrite = "scss-lsp.rewrite"
+
+// the capture that is replaced, the whole match when the query has none
+const rewriteCapture = "match"
+
+// RewriteMatch is one thing a rewrite changes, for the preview
+type RewriteMatch struct {
+	Path        string         `json:"path"`
+	Range       protocol.Range `json:"range"`
+	Text        string         `json:"text"`
+	Replacement string         `json:"replacement"`
+}
+
+type RewriteResult struct {
+	Edit    protocol.WorkspaceEdit `json:"edit"`
+	Matches []RewriteMatch         `json:"matches"`
+}
+
+// a match of a rewrite, the replacement goes from the start of start to the
+// end of end
+type rewriteCandidate struct {
+	start *sitter.Node
+	end   *sitter.Node
+	text  string
+}
+
+// captureSpans are the first and last nodes of every capture of a match, a
+// capture can be on more than one node with a quantifier
+func captureSpans(query *sitter.Query, match *sitter.QueryMatch) map[string][2]*sitter.Node {
+	spans := map[string][2]*sitter.Node{}
+	for _, capture := range match.Captures {
+		name := query.CaptureNameForId(capture.Index)
+		span, ok := spans[name]
+		if !ok {
+			span = [2]*sitter.Node{capture.Node, capture.Node}
+		}
+		if capture.Node.StartByte() < span[0].StartByte() {
+			span[0] = capture.Node
+		}
+		if capture.Node.EndByte() > span[1].EndByte() {
+			span[1] = capture.Node
+		}
+		spans[name] = span
+	}
+	return spans
+}
+
+// fillTemplate puts the text of the captures in place of their {{name}}
+func fillTemplate(template string, captures map[string]string) string {
+	return captureTemplateRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := captureTemplateRegex.FindStringSubmatch(placeholder)[1]
+		if text, ok := captures[name]; ok {
+			return text
+		}
+		return placeholder
+	})
+}
+
+// Rewrite replaces what the query matches in the files of the workspace
+// with the replacement, where {{name}} is the text of a capture. The @match
+// capture is what gets replaced, without it the text from the first capture
+// of a match to the end of the last one. Matches inside one that is already
+// replaced are left alone. Without paths every file is looked at.
+func (lsp *Lsp) Rewrite(query_text string, replacement string, paths []string) (RewriteResult, error) {
+	result := RewriteResult{
+		Edit:    protocol.WorkspaceEdit{Changes: map[uri.URI][]protocol.TextEdit{}},
+		Matches: []RewriteMatch{},
+	}
+	query, err := compileQuery(query_text)
+	if err != nil {
+		return result, err
+	}
+	if query.CaptureCount() == 0 {
+		return result, fmt.Errorf("the query captures nothing")
+	}
+	for _, path := range sortedPaths(lsp.Trees) {
+		if len(paths) > 0 && !isUnderAny(path, paths) {
+			continue
+		}
+		input, err := lsp.bytesFromFilePath(path)
+		if err != nil {
+			continue
+		}
+		found := []rewriteCandidate{}
+		cursor := sitter.NewQueryCursor()
+		cursor.Exec(query, lsp.Trees[path].RootNode())
+		for {
+			match, ok := cursor.NextMatch()
+			if !ok {
+				break
+			}
+			match = cursor.FilterPredicates(match, *input)
+			if len(match.Captures) == 0 {
+				continue
+			}
+			spans := captureSpans(query, match)
+			captures := map[string]string{}
+			var start, end *sitter.Node
+			for name, span := range spans {
+				captures[name] = string((*input)[span[0].StartByte():span[1].EndByte()])
+				if start == nil || span[0].StartByte() < start.StartByte() {
+					start = span[0]
+				}
+				if end == nil || span[1].EndByte() > end.EndByte() {
+					end = span[1]
+				}
+			}
+			if span, ok := spans[rewriteCapture]; ok {
+				start, end = span[0], span[1]
+			}
+			found = append(found, rewriteCandidate{start: start, end: end, text: fillTemplate(replacement, captures)})
+		}
+		// the matches of patterns that come later in the query can start
+		// earlier in the file, the outer one of two that overlap wins
+		sort.SliceStable(found, func(i, j int) bool {
+			if found[i].start.StartByte() != found[j].start.StartByte() {
+				return found[i].start.StartByte() < found[j].start.StartByte()
+			}
+			return found[i].end.EndByte() > found[j].end.EndByte()
+		})
+		edits := []protocol.TextEdit{}
+		replaced := uint32(0)
+		for _, candidate := range found {
+			if len(edits) > 0 && candidate.start.StartByte() < replaced {
+				continue
+			}
+			replaced = candidate.end.EndByte()
+			edit := protocol.TextEdit{
+				Range:   rangeFromPoints(candidate.start.StartPoint(), candidate.end.EndPoint()),
+				NewText: candidate.text,
+			}
+			edits = append(edits, edit)
+			result.Matches = append(result.Matches, RewriteMatch{
+				Path:        lsp.relativePath(path),
+				Range:       edit.Range,
+				Text:        string((*input)[candidate.start.StartByte():candidate.end.EndByte()]),
+				Replacement: edit.NewText,
+			})
+		}
+		if len(edits) > 0 {
+			result.Edit.Changes[uri.File(path)] = edits
+		}
+	}
+	return result, nil
+}
+
+// rewriteArguments reads the arguments of the rewrite command, the paths can
+// be uris or relative to the root
+func (lsp *Lsp) rewriteArguments(arguments []interface{}) (string, string, []string, error) {
+	texts := []string{}
+	for _, argument := range arguments {
+		text, ok := argument.(string)
+		if !ok {
+			return "", "", nil, fmt.Errorf("%s expects the query, the replacement and the paths", commandRewrite)
+		}
+		texts = append(texts, text)
+	}
+	if len(texts) < 2 {
+		return "", "", nil, fmt.Errorf("%s expects the query, the replacement and the paths", commandRewrite)
+	}
+	paths := []string{}
+	for _, path := range texts[2:] {
+		if strings.HasPrefix(path, "file://") {
+			path = uri.URI(path).Filename()
+		} else if !filepath.IsAbs(path) {
+			path = filepath.Join(lsp.RootPath, path)
+		}
+		paths = append(paths, path)
+	}
+	return texts[0], texts[1], paths, nil
+}
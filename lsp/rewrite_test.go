@@ -0,0 +1,63 @@
+package lsp
+
+import (
+	"testing"
+
+	"go.lsp.dev/uri"
+)
+
+func TestRewrite(t *testing.T) {
+	main := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		main: `.a {
+  @include media-breakpoint-up(md) {
+    @include media-breakpoint-up(lg);
+  }
+  @include other(md);
+}
+`,
+		"/virtual/vendor/lib.scss": ".b { @include media-breakpoint-up(sm); }\n",
+	})
+	lsp.RootPath = "/virtual"
+
+	query := `((include_statement (identifier) @name . (arguments (argument) @bp)) @match (#eq? @name "media-breakpoint-up"))`
+	result, err := lsp.ExecuteCommand(commandRewrite, []interface{}{query, "@include mq.up({{bp}}) {{missing}}", "main.scss"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewrite := result.(RewriteResult)
+	if len(rewrite.Matches) != 1 || len(rewrite.Edit.Changes) != 1 {
+		t.Fatalf("expected only the outer include of main.scss, got %+v", rewrite.Matches)
+	}
+	if rewrite.Matches[0].Path != "main.scss" || rewrite.Matches[0].Replacement != "@include mq.up(md) {{missing}}" || rewrite.Matches[0].Range.Start.Line != 1 {
+		t.Fatalf("unexpected match %+v", rewrite.Matches[0])
+	}
+
+	// without @match the text from the first capture to the last is replaced
+	result, err = lsp.ExecuteCommand(commandRewrite, []interface{}{
+		`((identifier) @name . (arguments (argument) @bp) @arguments (#eq? @name "media-breakpoint-up"))`,
+		"mq.up({{bp}})",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewrite = result.(RewriteResult)
+	if len(rewrite.Matches) != 3 {
+		t.Fatalf("unexpected matches %+v", rewrite.Matches)
+	}
+	input, _ := lsp.bytesFromFilePath(main)
+	expected := ".a {\n  @include mq.up(md) {\n    @include mq.up(lg);\n  }\n  @include other(md);\n}\n"
+	if rewritten := string(ApplyEdits(*input, rewrite.Edit.Changes[uri.File(main)])); rewritten != expected {
+		t.Fatalf("unexpected rewrite %q", rewritten)
+	}
+
+	if _, err := lsp.Rewrite("(include_statement", "", nil); err == nil {
+		t.Fatalf("expected an error for a broken query")
+	}
+	if _, err := lsp.Rewrite("(include_statement)", "", nil); err == nil {
+		t.Fatalf("expected an error for a query without captures")
+	}
+	if _, err := lsp.ExecuteCommand(commandRewrite, []interface{}{"(include_statement) @match"}); err == nil {
+		t.Fatalf("expected an error without a replacement")
+	}
+}
@@ -0,0 +1,398 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// what turns a rule off in the config
+const ruleOff = "off"
+
+// what a rule gets to look at, one file at a time
+type RuleContext struct {
+	Lsp   *Lsp
+	Path  string
+	Input []byte
+	Tree  *sitter.Tree
+	// from the config, for the directory of the file
+	Options map[string]interface{}
+}
+
+// a lint rule, its id is the code of the diagnostics it reports
+type Rule interface {
+	Id() string
+	// what the rule checks, shown by `scss-lsp check -rules`
+	Description() string
+	DefaultSeverity() protocol.DiagnosticSeverity
+	// Check finds the problems of one file, the engine fills in the
+	// severity, the code, the source and the link to the docs
+	Check(context RuleContext) []protocol.Diagnostic
+	// Fixes are the quick fixes for one of the diagnostics of the rule
+	Fixes(lsp *Lsp, path string, diagnostic protocol.Diagnostic) []protocol.CodeAction
+}
+
+// lintRule is a Rule made of functions, the rules of the server are one
+type lintRule struct {
+	id          string
+	description string
+	severity    protocol.DiagnosticSeverity
+	check       func(context RuleContext) []protocol.Diagnostic
+	// nil when there is nothing to fix
+	fixes quickFixProvider
+}
+
+func (rule lintRule) Id() string {
+	return rule.id
+}
+
+func (rule lintRule) Description() string {
+	return rule.description
+}
+
+func (rule lintRule) DefaultSeverity() protocol.DiagnosticSeverity {
+	return rule.severity
+}
+
+func (rule lintRule) Check(context RuleContext) []protocol.Diagnostic {
+	if rule.check == nil {
+		return []protocol.Diagnostic{}
+	}
+	return rule.check(context)
+}
+
+func (rule lintRule) Fixes(lsp *Lsp, path string, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+	if rule.fixes == nil {
+		return []protocol.CodeAction{}
+	}
+	return rule.fixes(lsp, path, diagnostic)
+}
+
+// visitNodes makes a check out of a function that looks at every named node
+// of the tree
+func visitNodes(visit func(context RuleContext, node *sitter.Node) []protocol.Diagnostic) func(context RuleContext) []protocol.Diagnostic {
+	return func(context RuleContext) []protocol.Diagnostic {
+		diagnostics := []protocol.Diagnostic{}
+		walkNamed(context.Tree.RootNode(), func(node *sitter.Node) {
+			diagnostics = append(diagnostics, visit(context, node)...)
+		})
+		return diagnostics
+	}
+}
+
+// queryMatches makes a check out of a function that looks at every match of
+// a query, the matches that fail the #eq? and #match? predicates of the
+// query are left out
+func queryMatches(query *sitter.Query, visit func(context RuleContext, match *sitter.QueryMatch) []protocol.Diagnostic) func(context RuleContext) []protocol.Diagnostic {
+	return func(context RuleContext) []protocol.Diagnostic {
+		diagnostics := []protocol.Diagnostic{}
+		cursor := sitter.NewQueryCursor()
+		cursor.Exec(query, context.Tree.RootNode())
+		for {
+			match, ok := cursor.NextMatch()
+			if !ok {
+				break
+			}
+			match = cursor.FilterPredicates(match, context.Input)
+			if len(match.Captures) == 0 {
+				continue
+			}
+			diagnostics = append(diagnostics, visit(context, match)...)
+		}
+		return diagnostics
+	}
+}
+
+// the rules in the order their diagnostics are reported
+var ruleRegistry = []Rule{
+	lintRule{
+		id:          diagnosticUndefined,
+		description: "Calls to mixins, functions and variables that are not defined anywhere in the workspace. Option \"ignore\" is a list of names to never report.",
+		severity:    protocol.DiagnosticSeverityError,
+		check:       undefinedDiagnostics,
+		fixes:       undefinedQuickFixes,
+	},
+	lintRule{
+		id:          diagnosticUndefinedMember,
+		description: "Members of a module that the module does not have, like `colors.$missing`.",
+		severity:    protocol.DiagnosticSeverityError,
+		check: func(context RuleContext) []protocol.Diagnostic {
+			return context.Lsp.memberDiagnostics(context.Path)
+		},
+	},
+	deprecationRule(diagnosticDeprecatedImport, "@import, which Dart Sass is removing in favour of @use and @forward.", (*Lsp).importDeprecations),
+	deprecationRule(diagnosticDeprecatedGlobal, "Global functions that moved to the built-in modules, like darken() to color.adjust().", (*Lsp).globalFunctionDeprecations),
+	deprecationRule(diagnosticDeprecatedDivision, "Division with /, which is a separator in plain CSS, use math.div() instead.", (*Lsp).divisionDeprecations),
+	deprecationRule(diagnosticDeprecatedNewGlobal, "!global assignments to variables that are not declared at the top of the file yet.", (*Lsp).newGlobalDeprecations),
+	lintRule{
+		id:          diagnosticDeprecatedMember,
+		description: "Uses of mixins, functions, variables and placeholders whose SassDoc says @deprecated.",
+		severity:    protocol.DiagnosticSeverityWarning,
+		check: func(context RuleContext) []protocol.Diagnostic {
+			return context.Lsp.sassDocDiagnostics(context.Path)
+		},
+	},
+	lintRule{
+		id:          diagnosticDuplicateDefinition,
+		description: "Mixins and functions defined twice in a file or in two files that one file loads, and variables of two modules that one file loads without a namespace.",
+		severity:    protocol.DiagnosticSeverityWarning,
+		check:       duplicateDefinitionDiagnostics,
+	},
+	lintRule{
+		id:          diagnosticShadowedVariable,
+		description: "Local variables with the name of a global one, sass makes a new variable for them instead of assigning the global one.",
+		severity:    protocol.DiagnosticSeverityInformation,
+		check:       shadowedVariableDiagnostics,
+	},
+	lintRule{
+		id:          diagnosticIneffectiveDefault,
+		description: "!default declarations after the variable has a value, and values given to !default variables of a file after it is loaded.",
+		severity:    protocol.DiagnosticSeverityWarning,
+		check:       ineffectiveDefaultDiagnostics,
+	},
+	lintRule{
+		id:          diagnosticDuplicateProperty,
+		description: "Properties set twice in a block, longhands that a later shorthand overrides, like margin-top and then margin, and properties an @include sets again. Values with a vendor prefix, another unit like 100vh and 100dvh or another function like red and linear-gradient(...) are fallbacks and left alone, and a declaration after an !important one is the one reported. Option \"ignore\" is a list of properties to never report.",
+		severity:    protocol.DiagnosticSeverityWarning,
+		check:       visitNodes(duplicatePropertyDiagnostics),
+	},
+	lintRule{
+		id:          diagnosticUnusedSymbol,
+		description: "Variables, mixins, functions, placeholders and keyframes that nothing in the workspace uses. Variables with !default and the members of the publicEntryPoints of the config count as used.",
+		severity:    protocol.DiagnosticSeverityHint,
+		check:       unusedSymbolDiagnostics,
+	},
+	lintRule{
+		id:          diagnosticUnusedSuppression,
+		description: "scss-lsp-disable comments that do not hide anything, and the rules they name that do not exist. They are reported after every other rule ran.",
+		severity:    protocol.DiagnosticSeverityWarning,
+		fixes:       unusedSuppressionQuickFixes,
+	},
+}
+
+// RegisterRule adds a rule to the ones every file is checked with
+func RegisterRule(rule Rule) error {
+	if _, ok := lookupRule(rule.Id()); ok {
+		return fmt.Errorf("there already is a rule %s", rule.Id())
+	}
+	ruleRegistry = append(ruleRegistry, rule)
+	return nil
+}
+
+// Rules are the registered rules, in the order they run
+func Rules() []Rule {
+	return ruleRegistry
+}
+
+func lookupRule(id string) (Rule, bool) {
+	return findRule(ruleRegistry, id)
+}
+
+func findRule(rules []Rule, id string) (Rule, bool) {
+	for _, rule := range rules {
+		if rule.Id() == id {
+			return rule, true
+		}
+	}
+	return nil, false
+}
+
+// rules are the registered rules and then the custom rules of the config
+func (lsp *Lsp) rules() []Rule {
+	rules := append([]Rule{}, ruleRegistry...)
+	return append(rules, lsp.customRules...)
+}
+
+func (lsp *Lsp) lookupRule(id string) (Rule, bool) {
+	return findRule(lsp.rules(), id)
+}
+
+// ruleConfigErrors are the rules of the config and its overrides that are
+// not rules, and the severities that are neither one nor off, ruleSetting
+// would just leave those alone
+func (lsp *Lsp) ruleConfigErrors() []string {
+	errors := []string{}
+	check := func(where string, rules map[string]RuleConfig) {
+		for _, id := range sortedPaths(rules) {
+			if _, ok := lsp.lookupRule(id); !ok {
+				errors = append(errors, fmt.Sprintf("%s: unknown rule %s", where, id))
+				continue
+			}
+			severity := rules[id].Severity
+			if _, ok := ParseSeverity(severity); !ok && severity != "" && severity != ruleOff {
+				errors = append(errors, fmt.Sprintf("%s.%s: unknown severity %s", where, id, severity))
+			}
+		}
+	}
+	check("rules", lsp.Config.Rules)
+	for idx, override := range lsp.Config.Overrides {
+		check(fmt.Sprintf("overrides[%d].rules", idx), override.Rules)
+	}
+	return errors
+}
+
+// ruleSetting is what the config says about a rule for one file, the rules
+// of the config first and then the overrides for the directories of the
+// file, in order
+func (lsp *Lsp) ruleSetting(path string, rule Rule) (protocol.DiagnosticSeverity, map[string]interface{}, bool) {
+	severity := rule.DefaultSeverity()
+	options := map[string]interface{}{}
+	enabled := true
+	// only a severity turns the rule on or off, options alone keep it as it
+	// was
+	apply := func(config RuleConfig) {
+		if config.Severity == ruleOff {
+			enabled = false
+			return
+		}
+		if parsed, ok := ParseSeverity(config.Severity); ok {
+			severity = parsed
+			enabled = true
+		}
+		for key, value := range config.Options {
+			options[key] = value
+		}
+	}
+
+	if config, ok := lsp.Config.Rules[rule.Id()]; ok {
+		apply(config)
+	}
+	for _, override := range lsp.Config.Overrides {
+		config, ok := override.Rules[rule.Id()]
+		if !ok || !isUnderAny(path, lsp.rootRelative([]string{override.Directory})) {
+			continue
+		}
+		// an override can turn a rule back on with just a severity
+		apply(config)
+	}
+	return severity, options, enabled
+}
+
+func (lsp *Lsp) isRuleEnabled(path string, id string) bool {
+	rule, ok := lsp.lookupRule(id)
+	if !ok {
+		return false
+	}
+	_, _, enabled := lsp.ruleSetting(path, rule)
+	return enabled
+}
+
+// ruleDocs links to the docs of a rule, nil when the config has no url for
+// them
+func (lsp *Lsp) ruleDocs(id string) *protocol.CodeDescription {
+	if lsp.Config.RuleDocsUrl == "" {
+		return nil
+	}
+	return &protocol.CodeDescription{Href: uri.URI(lsp.Config.RuleDocsUrl + "#" + id)}
+}
+
+// finishDiagnostics gives the diagnostics of a rule what the config says
+// they get, nothing when the rule is off for the file
+func (lsp *Lsp) finishDiagnostics(path string, rule Rule, diagnostics []protocol.Diagnostic) []protocol.Diagnostic {
+	severity, _, enabled := lsp.ruleSetting(path, rule)
+	if !enabled {
+		return []protocol.Diagnostic{}
+	}
+	for idx := range diagnostics {
+		diagnostics[idx].Severity = severity
+		diagnostics[idx].Code = rule.Id()
+		diagnostics[idx].CodeDescription = lsp.ruleDocs(rule.Id())
+		if diagnostics[idx].Source == "" {
+			diagnostics[idx].Source = "SCSS-LSP"
+		}
+	}
+	return diagnostics
+}
+
+// runRules checks a file with every rule that is on for it
+func (lsp *Lsp) runRules(path string) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+	tree, ok := lsp.Trees[path]
+	input, err := lsp.bytesFromFilePath(path)
+	if !ok || err != nil {
+		return diagnostics
+	}
+	for _, rule := range lsp.rules() {
+		_, options, enabled := lsp.ruleSetting(path, rule)
+		if !enabled {
+			continue
+		}
+		found := rule.Check(RuleContext{Lsp: lsp, Path: path, Input: *input, Tree: tree, Options: options})
+		diagnostics = append(diagnostics, lsp.finishDiagnostics(path, rule, found)...)
+	}
+	return diagnostics
+}
+
+// stringsOption reads an option that is a list of strings
+func stringsOption(options map[string]interface{}, key string) []string {
+	values := []string{}
+	list, _ := options[key].([]interface{})
+	for _, value := range list {
+		if text, ok := value.(string); ok {
+			values = append(values, text)
+		}
+	}
+	return values
+}
+
+func undefinedDiagnostics(context RuleContext) []protocol.Diagnostic {
+	lsp := context.Lsp
+	diagnostics := []protocol.Diagnostic{}
+	ignore := stringsOption(context.Options, "ignore")
+	for _, entry := range lsp.Calls[context.Path] {
+		if lsp.isCallAllowed(entry.name) || lsp.isBuiltinCall(context.Path, entry) {
+			continue
+		}
+		ignored := false
+		for _, name := range ignore {
+			ignored = ignored || name == entry.name
+		}
+		if ignored || lsp.doesCallExist(entry.name) {
+			continue
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:   rangeFromPoints(entry.start_position, entry.end_position),
+			Message: "undefined",
+		})
+	}
+	return diagnostics
+}
+
+// RuleConfig is how a rule is set up in the config, either just the
+// severity, "off" or an object with the severity and the options of the rule
+type RuleConfig struct {
+	Severity string                 `json:"severity"`
+	Options  map[string]interface{} `json:"options"`
+}
+
+func (config *RuleConfig) UnmarshalJSON(data []byte) error {
+	var severity string
+	if err := json.Unmarshal(data, &severity); err == nil {
+		config.Severity = severity
+		return nil
+	}
+	// a type without the method, or this would call itself
+	type plain RuleConfig
+	return json.Unmarshal(data, (*plain)(config))
+}
+
+// the rules for the files in a directory, the directory is relative to the
+// root
+type RuleOverride struct {
+	Directory string                `json:"directory"`
+	Rules     map[string]RuleConfig `json:"rules"`
+}
+
+// RuleDocs lists every rule, its default severity and what it checks, the
+// custom rules of the config too
+func (lsp *Lsp) RuleDocs() string {
+	var sb strings.Builder
+	for _, rule := range lsp.rules() {
+		sb.WriteString(fmt.Sprintf("%s (%s)\n    %s\n", rule.Id(), severityName(rule.DefaultSeverity()), rule.Description()))
+	}
+	return sb.String()
+}
@@ -0,0 +1,120 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+func TestRuleConfig(t *testing.T) {
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/main.scss":       ".a {\n  @include injected;\n  @include missing;\n  width: darken(red, 1%);\n}\n",
+		"/virtual/legacy/old.scss": ".b { @include missing; width: darken(red, 1%); }\n",
+	})
+	lsp.RootPath = "/virtual"
+	err := json.Unmarshal([]byte(`{
+		"ruleDocsUrl": "https://example.com/rules",
+		"rules": {
+			"deprecated-global-function": "error",
+			"undefined": {"options": {"ignore": ["injected"]}}
+		},
+		"overrides": [
+			{"directory": "legacy", "rules": {"undefined": "off", "deprecated-global-function": "hint"}}
+		]
+	}`), lsp.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diagnostics := lsp.getDiagnostics("/virtual/main.scss")
+	if len(diagnostics) != 2 {
+		t.Fatalf("unexpected diagnostics %+v", diagnostics)
+	}
+	if diagnostics[0].Code != diagnosticUndefined || diagnostics[0].Range.Start.Line != 2 {
+		t.Fatalf("expected only the missing mixin, got %+v", diagnostics[0])
+	}
+	if diagnostics[0].CodeDescription == nil || diagnostics[0].CodeDescription.Href != "https://example.com/rules#undefined" {
+		t.Fatalf("unexpected docs %+v", diagnostics[0].CodeDescription)
+	}
+	if diagnostics[1].Severity != protocol.DiagnosticSeverityError {
+		t.Fatalf("expected the configured severity, got %+v", diagnostics[1])
+	}
+
+	diagnostics = lsp.getDiagnostics("/virtual/legacy/old.scss")
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnosticDeprecatedGlobal || diagnostics[0].Severity != protocol.DiagnosticSeverityHint {
+		t.Fatalf("unexpected diagnostics %+v", diagnostics)
+	}
+
+	// options alone dont turn a rule back on
+	lsp.Config = DefaultConfig()
+	err = json.Unmarshal([]byte(`{
+		"rules": {"undefined": "off"},
+		"overrides": [
+			{"directory": "legacy", "rules": {"undefined": {"options": {"ignore": ["injected"]}}}}
+		]
+	}`), lsp.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagnostics := diagnosticsWithCode(lsp.getDiagnostics("/virtual/legacy/old.scss"), diagnosticUndefined); len(diagnostics) != 0 {
+		t.Fatalf("expected the rule to stay off, got %+v", diagnostics)
+	}
+}
+
+func TestRegisterRule(t *testing.T) {
+	registry := ruleRegistry
+	defer func() { ruleRegistry = registry }()
+
+	err := RegisterRule(lintRule{
+		id:       "no-important",
+		severity: protocol.DiagnosticSeverityInformation,
+		check: visitNodes(func(context RuleContext, node *sitter.Node) []protocol.Diagnostic {
+			if node.Type() != "important" {
+				return nil
+			}
+			return []protocol.Diagnostic{{
+				Range:   rangeFromPoints(node.StartPoint(), node.EndPoint()),
+				Message: "!important is not allowed",
+			}}
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterRule(lintRule{id: diagnosticUndefined}); err == nil {
+		t.Fatalf("expected an error for a rule that already exists")
+	}
+
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{path: ".a { color: red !important; }\n"})
+	diagnostics := lsp.getDiagnostics(path)
+	if len(diagnostics) != 1 || diagnostics[0].Code != "no-important" || diagnostics[0].Source != "SCSS-LSP" || diagnostics[0].Range.Start.Character != 16 {
+		t.Fatalf("unexpected diagnostics %+v", diagnostics)
+	}
+	if diagnostics[0].CodeDescription != nil {
+		t.Fatalf("expected no docs without a url, got %+v", diagnostics[0].CodeDescription)
+	}
+}
+
+func TestRuleConfigErrors(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".scss-lsp.json": `{
+			"rules": {"undefind": "error", "undefined": "warn", "unused-symbol": {"options": {}}},
+			"overrides": [{"directory": "legacy", "rules": {"undefined": {"severity": "err"}, "shadowed-variable": "off"}}]
+		}`,
+		"main.scss": ".a { top: 0; }\n",
+	})
+	lsp := LoadWorkspace(root)
+	expected := []string{
+		"rules: unknown rule undefind",
+		"rules.undefined: unknown severity warn",
+		"overrides[0].rules.undefined: unknown severity err",
+	}
+	if strings.Join(lsp.ConfigErrors, "|") != strings.Join(expected, "|") {
+		t.Fatalf("unexpected config errors %q", lsp.ConfigErrors)
+	}
+}
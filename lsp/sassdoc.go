@@ -0,0 +1,355 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+const diagnosticDeprecatedMember = "deprecated-member"
+
+// @param {Type} $name [default] - description
+var sassDocParamRegex = regexp.MustCompile(`^(?:\{([^}]*)\}\s*)?(\$?[\w-]+)(?:\s*\[([^\]]*)\])?\s*(?:-\s*)?(.*)$`)
+
+// @return {Type} description
+var sassDocTypeRegex = regexp.MustCompile(`^(?:\{([^}]*)\}\s*)?(.*)$`)
+
+type sassDocParameter struct {
+	name          string
+	value_type    string
+	default_value string
+	description   string
+}
+
+type sassDocExample struct {
+	language    string
+	description string
+	code        string
+}
+
+// the SassDoc of a mixin, function, variable or placeholder, the /// block
+// right above it
+type sassDoc struct {
+	description string
+	parameters  []sassDocParameter
+	return_type string
+	returns     string
+	examples    []sassDocExample
+	deprecated  bool
+	// why and what to use instead, can be empty
+	deprecation string
+	see         []string
+	group       string
+	access      string
+}
+
+// sassDocBefore parses the /// lines right above offset, nil if there are
+// none. //// is a comment about the whole file and not part of it.
+func sassDocBefore(input []byte, offset uint32) *sassDoc {
+	line_start := int(offset)
+	for line_start > 0 && input[line_start-1] != '\n' {
+		line_start--
+	}
+	if strings.TrimSpace(string(input[line_start:offset])) != "" {
+		return nil
+	}
+	lines := []string{}
+	for end := line_start - 1; end >= 0; {
+		start := end
+		for start > 0 && input[start-1] != '\n' {
+			start--
+		}
+		line := strings.TrimSpace(string(input[start:end]))
+		if !strings.HasPrefix(line, "///") || strings.HasPrefix(line, "////") {
+			break
+		}
+		lines = append([]string{strings.TrimPrefix(line, "///")}, lines...)
+		end = start - 1
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return parseSassDoc(lines)
+}
+
+// parseSassDoc parses the lines of a doc comment without the slashes, lines
+// that dont start an annotation continue the one before
+func parseSassDoc(lines []string) *sassDoc {
+	doc := &sassDoc{}
+	annotation := ""
+	description := []string{}
+	// the text of the current annotation and the lines after it
+	text := []string{}
+	flush := func() {
+		value := strings.TrimSpace(strings.Join(text, " "))
+		switch annotation {
+		case "":
+			return
+		case "@param", "@arg", "@argument", "@parameter":
+			match := sassDocParamRegex.FindStringSubmatch(value)
+			if match == nil {
+				return
+			}
+			name := match[2]
+			if !strings.HasPrefix(name, "$") {
+				name = "$" + name
+			}
+			doc.parameters = append(doc.parameters, sassDocParameter{
+				name:          name,
+				value_type:    strings.TrimSpace(match[1]),
+				default_value: strings.TrimSpace(match[3]),
+				description:   strings.TrimSpace(match[4]),
+			})
+		case "@return", "@returns":
+			match := sassDocTypeRegex.FindStringSubmatch(value)
+			doc.return_type = strings.TrimSpace(match[1])
+			doc.returns = strings.TrimSpace(match[2])
+		case "@example":
+			example := sassDocExample{}
+			if len(text) > 0 {
+				header, example_description, _ := strings.Cut(text[0], " - ")
+				example.language = strings.TrimSpace(header)
+				example.description = strings.TrimSpace(example_description)
+				example.code = dedent(text[1:])
+			}
+			doc.examples = append(doc.examples, example)
+		case "@deprecated":
+			doc.deprecated = true
+			doc.deprecation = value
+		case "@see":
+			if value != "" {
+				doc.see = append(doc.see, value)
+			}
+		case "@group":
+			doc.group = value
+		case "@access":
+			doc.access = value
+		}
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		// indented lines never start an annotation, an @include in an
+		// example is code
+		if strings.HasPrefix(strings.TrimPrefix(line, " "), "@") {
+			flush()
+			annotation, trimmed, _ = strings.Cut(trimmed, " ")
+			text = []string{strings.TrimSpace(trimmed)}
+			continue
+		}
+		switch annotation {
+		case "":
+			description = append(description, trimmed)
+		case "@example":
+			// the indentation of the code matters
+			text = append(text, strings.TrimPrefix(line, " "))
+		default:
+			text = append(text, trimmed)
+		}
+	}
+	flush()
+	doc.description = strings.TrimSpace(strings.Join(description, "\n"))
+	return doc
+}
+
+// dedent removes the indentation the lines have in common and the blank
+// lines around them
+func dedent(lines []string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		width := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == -1 || width < indent {
+			indent = width
+		}
+	}
+	dedented := []string{}
+	for _, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			line = line[indent:]
+		}
+		dedented = append(dedented, strings.TrimRight(line, " \t"))
+	}
+	return strings.Join(dedented, "\n")
+}
+
+func (doc *sassDoc) isDeprecated() bool {
+	return doc != nil && doc.deprecated
+}
+
+func (doc *sassDoc) isPrivate() bool {
+	return doc != nil && doc.access == "private"
+}
+
+func (doc *sassDoc) parameter(name string) (sassDocParameter, bool) {
+	if doc == nil {
+		return sassDocParameter{}, false
+	}
+	name = strings.TrimSuffix(name, "...")
+	for _, parameter := range doc.parameters {
+		if parameter.name == name {
+			return parameter, true
+		}
+	}
+	return sassDocParameter{}, false
+}
+
+// sassDocDescription is the description with the deprecation in front of it,
+// for the places that only show plain text
+func sassDocDescription(doc *sassDoc) string {
+	if doc == nil {
+		return ""
+	}
+	if !doc.deprecated {
+		return doc.description
+	}
+	deprecated := "Deprecated"
+	if doc.deprecation != "" {
+		deprecated += ": " + doc.deprecation
+	}
+	return strings.TrimSpace(deprecated + "\n\n" + doc.description)
+}
+
+// definitionDocumentation is the markdown for the doc comment of a definition,
+// with the parameters of its signature next to what the comment says of them
+func definitionDocumentation(entry isDefined, parameters []string) string {
+	doc := entry.doc
+	sections := []string{}
+	if doc.isDeprecated() {
+		deprecated := "**Deprecated**"
+		if doc.deprecation != "" {
+			deprecated += ": " + doc.deprecation
+		}
+		sections = append(sections, deprecated)
+	}
+	if doc != nil && doc.description != "" {
+		sections = append(sections, doc.description)
+	}
+	if len(parameters) > 0 {
+		list := []string{"Parameters:"}
+		for _, parameter := range parameters {
+			name, value, has_default := strings.Cut(parameter, ":")
+			name = strings.TrimSpace(name)
+			line := "- `" + name + "`"
+			documented, _ := doc.parameter(name)
+			if documented.value_type != "" {
+				line += " `{" + documented.value_type + "}`"
+			}
+			if has_default {
+				line += " defaults to `" + strings.TrimSpace(value) + "`"
+			} else if documented.default_value != "" {
+				line += " defaults to `" + documented.default_value + "`"
+			}
+			if documented.description != "" {
+				line += " - " + documented.description
+			}
+			list = append(list, line)
+		}
+		sections = append(sections, strings.Join(list, "\n"))
+	}
+	if doc == nil {
+		return strings.Join(sections, "\n\n")
+	}
+	if doc.return_type != "" || doc.returns != "" {
+		returns := "Returns:"
+		if doc.return_type != "" {
+			returns += " `{" + doc.return_type + "}`"
+		}
+		if doc.returns != "" {
+			returns += " " + doc.returns
+		}
+		sections = append(sections, returns)
+	}
+	for _, example := range doc.examples {
+		title := "Example:"
+		if example.description != "" {
+			title = "Example: " + example.description
+		}
+		language := example.language
+		if language == "" {
+			language = "scss"
+		}
+		sections = append(sections, title+"\n```"+language+"\n"+example.code+"\n```")
+	}
+	if len(doc.see) > 0 {
+		sections = append(sections, "See: "+strings.Join(doc.see, ", "))
+	}
+	if doc.group != "" {
+		sections = append(sections, "Group: "+doc.group)
+	}
+	if doc.isPrivate() {
+		sections = append(sections, "Private")
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// sassDocDiagnostics warns about the use of members that are @deprecated,
+// when it is not clear which definition is used all of them have to be
+func (lsp *Lsp) sassDocDiagnostics(path string) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return diagnostics
+	}
+	tree, ok := lsp.Trees[path]
+	if !ok {
+		return diagnostics
+	}
+	add := func(name string, item_type string, start_position sitter.Point, end_position sitter.Point) {
+		definitions := lsp.scopedDefinitions(path, name, item_type)
+		if len(definitions) == 0 {
+			return
+		}
+		for _, definition := range definitions {
+			if !definition.is_defined.doc.isDeprecated() {
+				return
+			}
+		}
+		message := fmt.Sprintf("%s is deprecated", name)
+		if deprecation := definitions[0].is_defined.doc.deprecation; deprecation != "" {
+			message += ": " + deprecation
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    rangeFromPoints(start_position, end_position),
+			Severity: protocol.DiagnosticSeverityWarning,
+			Code:     diagnosticDeprecatedMember,
+			Source:   "SCSS-LSP",
+			Message:  message,
+			Tags:     []protocol.DiagnosticTag{protocol.DiagnosticTagDeprecated},
+		})
+	}
+
+	for _, entry := range lsp.Calls[path] {
+		if entry.item_type == itemTypeVariable {
+			node := tree.RootNode().NamedDescendantForPointRange(entry.start_position, entry.end_position)
+			is_local := false
+			for _, local := range localVariables(node, *input, entry.start_position) {
+				is_local = is_local || local.name == entry.name
+			}
+			if is_local {
+				continue
+			}
+		}
+		add(entry.name, entry.item_type, entry.start_position, entry.end_position)
+	}
+	for _, reference := range lsp.namespacedReferences(path, *input) {
+		add(reference.namespace+"."+reference.member, reference.item_type, reference.start_position, reference.end_position)
+	}
+	for _, entry := range lsp.Extends[path] {
+		if entry.item_type == itemTypePlaceholder {
+			add(entry.name, itemTypePlaceholder, entry.start_position, entry.end_position)
+		}
+	}
+	return diagnostics
+}
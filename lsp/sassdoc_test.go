@@ -0,0 +1,139 @@
+package lsp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+const sassDocLibrary = `//// the whole file, not the mixin
+/// Adds a shadow
+/// to the element
+/// @param {Length} $size [1px] - how far it reaches
+/// @param {Color} $color - the color of the
+///   shadow
+/// @example scss - A big one
+///   .card {
+///     @include shadow(4px);
+///   }
+/// @see elevate
+/// @group effects
+@mixin shadow($size: 1px, $color: black) {}
+
+/// @deprecated use elevate instead
+@mixin old-shadow {}
+
+/// @access private
+/// @return {Number} twice the value
+@function double($x) {
+  @return $x * 2;
+}
+
+/// @deprecated
+$old-gap: 1px;
+`
+
+func TestParseSassDoc(t *testing.T) {
+	input := []byte(sassDocLibrary)
+	doc := sassDocBefore(input, uint32(strings.Index(sassDocLibrary, "@mixin shadow")))
+	expected := &sassDoc{
+		description: "Adds a shadow\nto the element",
+		parameters: []sassDocParameter{
+			{name: "$size", value_type: "Length", default_value: "1px", description: "how far it reaches"},
+			{name: "$color", value_type: "Color", description: "the color of the shadow"},
+		},
+		examples: []sassDocExample{{language: "scss", description: "A big one", code: ".card {\n  @include shadow(4px);\n}"}},
+		see:      []string{"elevate"},
+		group:    "effects",
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Fatalf("unexpected doc %+v", doc)
+	}
+
+	doc = sassDocBefore(input, uint32(strings.Index(sassDocLibrary, "@function")))
+	if !doc.isPrivate() || doc.return_type != "Number" || doc.returns != "twice the value" {
+		t.Fatalf("unexpected doc %+v", doc)
+	}
+	doc = sassDocBefore(input, uint32(strings.Index(sassDocLibrary, "@mixin old-shadow")))
+	if !doc.isDeprecated() || doc.deprecation != "use elevate instead" {
+		t.Fatalf("unexpected doc %+v", doc)
+	}
+	if doc := sassDocBefore(input, uint32(strings.Index(sassDocLibrary, "@return"))); doc != nil {
+		t.Fatalf("expected no doc, got %+v", doc)
+	}
+}
+
+func TestSassDocInTheEditor(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/_effects.scss": sassDocLibrary,
+		path: `@use "effects";
+.a {
+  @include effects.shadow(2px, red);
+  @include effects.old-shadow;
+  width: effects.$old-gap;
+}
+`,
+	})
+
+	hover := lsp.GetHoverInfo(path, sitter.Point{Row: 2, Column: 21})
+	for _, expected := range []string{
+		"Adds a shadow\nto the element",
+		"- `$size` `{Length}` defaults to `1px` - how far it reaches",
+		"- `$color` `{Color}` defaults to `black` - the color of the shadow",
+		"Example: A big one\n```scss\n.card {\n  @include shadow(4px);\n}\n```",
+		"See: elevate",
+		"Group: effects",
+	} {
+		if !strings.Contains(hover, expected) {
+			t.Fatalf("expected %q in hover %q", expected, hover)
+		}
+	}
+
+	help := lsp.GetSignatureHelp(path, protocol.Position{Line: 2, Character: 32})
+	if help == nil || help.Signatures[0].Documentation != "Adds a shadow\nto the element" {
+		t.Fatalf("unexpected signature help %+v", help)
+	}
+	if documentation := help.Signatures[0].Parameters[1].Documentation; documentation != "the color of the shadow" {
+		t.Fatalf("unexpected parameter documentation %v", documentation)
+	}
+
+	messages := []string{}
+	for _, diagnostic := range lsp.getDiagnostics(path) {
+		if diagnostic.Code == diagnosticDeprecatedMember {
+			messages = append(messages, diagnostic.Message)
+		}
+	}
+	expected := []string{"effects.old-shadow is deprecated: use elevate instead", "effects.$old-gap is deprecated"}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Fatalf("unexpected diagnostics %v", messages)
+	}
+}
+
+func TestSassDocCompletion(t *testing.T) {
+	path := "/virtual/main.scss"
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/_effects.scss": sassDocLibrary,
+		path:                     ".a {\n  @include \n}\n",
+	})
+	labels := completionLabels(lsp.GetCompletions(path, protocol.Position{Line: 1, Character: 11}))
+	documentation, ok := labels["effects.shadow"].Documentation.(protocol.MarkupContent)
+	if !ok || !strings.Contains(documentation.Value, "Adds a shadow") {
+		t.Fatalf("unexpected documentation %+v", labels["effects.shadow"].Documentation)
+	}
+	if tags := labels["effects.old-shadow"].Tags; len(tags) != 1 || tags[0] != protocol.CompletionItemTagDeprecated {
+		t.Fatalf("expected effects.old-shadow to be deprecated, got %v", tags)
+	}
+
+	lsp = lspWithSources(t, map[string]string{
+		"/virtual/_effects.scss": sassDocLibrary,
+		path:                     ".a {\n  width: \n}\n",
+	})
+	labels = completionLabels(lsp.GetCompletions(path, protocol.Position{Line: 1, Character: 9}))
+	if _, ok := labels["effects.double"]; ok {
+		t.Fatalf("did not expect the @access private function")
+	}
+}
@@ -0,0 +1,378 @@
+package lsp
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// a single place in a tree where a symbol is used or defined
+type symbolOccurrence struct {
+	node      *sitter.Node
+	name      string
+	item_type string
+	is_write  bool
+	// only set for @extend targets, they dont have a node of their own
+	start_position sitter.Point
+	end_position   sitter.Point
+}
+
+func (occurrence symbolOccurrence) startPoint() sitter.Point {
+	if occurrence.node != nil {
+		return occurrence.node.StartPoint()
+	}
+	return occurrence.start_position
+}
+
+func (occurrence symbolOccurrence) endPoint() sitter.Point {
+	if occurrence.node != nil {
+		return occurrence.node.EndPoint()
+	}
+	return occurrence.end_position
+}
+
+const (
+	itemTypeMixin       = "@mixin"
+	itemTypeFunction    = "@function"
+	itemTypeVariable    = "$variable"
+	itemTypePlaceholder = "%placeholder"
+	itemTypeParent      = "&"
+	itemTypeRuleSet     = "rule_set"
+	itemTypeKeyframes   = "@keyframes"
+)
+
+func parentType(node *sitter.Node) string {
+	parent := node.Parent()
+	if parent == nil {
+		return ""
+	}
+	return parent.Type()
+}
+
+// classifySymbol figures out if the node is something that can be resolved,
+// everything else is ignored
+func classifySymbol(node *sitter.Node, input *[]byte) (symbolOccurrence, bool) {
+	if node == nil {
+		return symbolOccurrence{}, false
+	}
+	text := node.Content(*input)
+	occurrence := symbolOccurrence{node: node, name: text}
+	switch node.Type() {
+	case "variable_name":
+		parent_type := parentType(node)
+		if parent_type != "declaration" && parent_type != "parameter" {
+			return occurrence, false
+		}
+		occurrence.item_type = itemTypeVariable
+		occurrence.is_write = true
+	case "variable_value":
+		occurrence.item_type = itemTypeVariable
+	case "variable":
+		if parentType(node) != "for_statement" {
+			return occurrence, false
+		}
+		occurrence.item_type = itemTypeVariable
+		occurrence.is_write = true
+	case "key", "value":
+		if parentType(node) != "each_statement" {
+			return occurrence, false
+		}
+		occurrence.item_type = itemTypeVariable
+		occurrence.is_write = true
+	case "name":
+		switch parentType(node) {
+		case "mixin_statement":
+			occurrence.item_type = itemTypeMixin
+		case "function_statement":
+			occurrence.item_type = itemTypeFunction
+		case "placeholder":
+			occurrence.item_type = itemTypePlaceholder
+			occurrence.name = "%" + text
+		default:
+			return occurrence, false
+		}
+		occurrence.is_write = true
+	case "identifier":
+		if parentType(node) != "include_statement" {
+			return occurrence, false
+		}
+		occurrence.item_type = itemTypeMixin
+	case "function_name":
+		if parentType(node) != "call_expression" {
+			return occurrence, false
+		}
+		occurrence.item_type = itemTypeFunction
+	case "nesting_selector":
+		occurrence.item_type = itemTypeParent
+	default:
+		return occurrence, false
+	}
+	return occurrence, true
+}
+
+func isGlobalDeclaration(node *sitter.Node, input *[]byte) bool {
+	parent := node.Parent()
+	return parent != nil && parent.Type() == "declaration" && strings.Contains(parent.Content(*input), "!global")
+}
+
+// declaresVariable checks if the scope node introduces the variable itself,
+// only declarations before the occurrence count
+func declaresVariable(scope *sitter.Node, name string, before *sitter.Node, input *[]byte) bool {
+	switch scope.Type() {
+	case "block":
+		for i := 0; i < int(scope.NamedChildCount()); i++ {
+			child := scope.NamedChild(i)
+			if child.Type() != "declaration" || child.StartByte() > before.StartByte() {
+				continue
+			}
+			variable := child.NamedChild(0)
+			if variable == nil || variable.Type() != "variable_name" || variable.Content(*input) != name {
+				continue
+			}
+			if !isGlobalDeclaration(variable, input) {
+				return true
+			}
+		}
+	case "mixin_statement", "function_statement":
+		for i := 0; i < int(scope.NamedChildCount()); i++ {
+			parameters := scope.NamedChild(i)
+			if parameters.Type() != "parameters" {
+				continue
+			}
+			for j := 0; j < int(parameters.NamedChildCount()); j++ {
+				variable := parameters.NamedChild(j).NamedChild(0)
+				if variable != nil && variable.Content(*input) == name {
+					return true
+				}
+			}
+		}
+	case "for_statement", "each_statement":
+		for i := 0; i < int(scope.NamedChildCount()); i++ {
+			child := scope.NamedChild(i)
+			switch child.Type() {
+			case "variable", "key", "value":
+				if child.Content(*input) == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// variableScope returns the node the variable belongs to, the root node means
+// it is a global variable
+func variableScope(occurrence symbolOccurrence, input *[]byte) *sitter.Node {
+	node := occurrence.node
+	var root *sitter.Node
+	for ancestor := node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		root = ancestor
+	}
+	if occurrence.is_write && isGlobalDeclaration(node, input) {
+		return root
+	}
+	for ancestor := node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		if ancestor.Type() == "stylesheet" {
+			return ancestor
+		}
+		if declaresVariable(ancestor, occurrence.name, node, input) {
+			return ancestor
+		}
+	}
+	return root
+}
+
+// parentRuleSet is the rule set that a & refers to
+func parentRuleSet(node *sitter.Node) *sitter.Node {
+	seen_own_rule_set := false
+	for ancestor := node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		if ancestor.Type() != "rule_set" {
+			continue
+		}
+		if seen_own_rule_set {
+			return ancestor
+		}
+		seen_own_rule_set = true
+	}
+	return nil
+}
+
+// scopeOf is what two occurrences need to share to be the same symbol
+func scopeOf(occurrence symbolOccurrence, input *[]byte) *sitter.Node {
+	switch occurrence.item_type {
+	case itemTypeVariable:
+		return variableScope(occurrence, input)
+	case itemTypeParent:
+		return parentRuleSet(occurrence.node)
+	}
+	return nil
+}
+
+func sameNode(a *sitter.Node, b *sitter.Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(b)
+}
+
+func walkNamed(node *sitter.Node, visit func(node *sitter.Node)) {
+	visit(node)
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		walkNamed(node.NamedChild(i), visit)
+	}
+}
+
+// extendOccurrences turns @extend %placeholder targets into occurrences
+func (lsp *Lsp) extendOccurrences(tree *sitter.Tree, input *[]byte) []symbolOccurrence {
+	occurrences := []symbolOccurrence{}
+	for _, entry := range lsp.Parser.ParseExtendsInTree(tree, input) {
+		if !strings.HasPrefix(entry.name, "%") {
+			continue
+		}
+		occurrences = append(occurrences, symbolOccurrence{
+			name:           entry.name,
+			item_type:      itemTypePlaceholder,
+			start_position: entry.start_position,
+			end_position:   entry.end_position,
+		})
+	}
+	return occurrences
+}
+
+// symbolAtPosition resolves whatever symbol is under the cursor
+func (lsp *Lsp) symbolAtPosition(path string, position sitter.Point) (symbolOccurrence, bool) {
+	tree := lsp.Trees[path]
+	if tree == nil {
+		return symbolOccurrence{}, false
+	}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return symbolOccurrence{}, false
+	}
+	node := tree.RootNode().NamedDescendantForPointRange(position, position)
+	if occurrence, ok := classifySymbol(node, input); ok {
+		return occurrence, true
+	}
+	for _, occurrence := range lsp.extendOccurrences(tree, input) {
+		if isPointInRange(position, occurrence.start_position, occurrence.end_position) {
+			return occurrence, true
+		}
+	}
+	return symbolOccurrence{}, false
+}
+
+// findOccurrences returns every occurrence of the symbol in the file that
+// resolves to the same scope
+func (lsp *Lsp) findOccurrences(path string, target symbolOccurrence) []symbolOccurrence {
+	occurrences := []symbolOccurrence{}
+	tree := lsp.Trees[path]
+	if tree == nil {
+		return occurrences
+	}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return occurrences
+	}
+
+	if target.item_type == itemTypePlaceholder {
+		for _, occurrence := range lsp.extendOccurrences(tree, input) {
+			if occurrence.name == target.name {
+				occurrences = append(occurrences, occurrence)
+			}
+		}
+	}
+
+	var target_scope *sitter.Node
+	if target.node != nil {
+		target_scope = scopeOf(target, input)
+	}
+	walkNamed(tree.RootNode(), func(node *sitter.Node) {
+		occurrence, ok := classifySymbol(node, input)
+		if !ok || occurrence.item_type != target.item_type {
+			return
+		}
+		if target.item_type != itemTypeParent && occurrence.name != target.name {
+			return
+		}
+		if target.node != nil && !sameNode(scopeOf(occurrence, input), target_scope) {
+			return
+		}
+		occurrences = append(occurrences, occurrence)
+	})
+	return occurrences
+}
+
+func (lsp *Lsp) GetDocumentHighlights(path string, position sitter.Point) []protocol.DocumentHighlight {
+	highlights := []protocol.DocumentHighlight{}
+	target, ok := lsp.symbolAtPosition(path, position)
+	if !ok {
+		return highlights
+	}
+	if target.item_type == itemTypeParent {
+		parent := parentRuleSet(target.node)
+		if parent == nil {
+			return highlights
+		}
+		// the selectors the & stands for count as the "definition"
+		highlights = append(highlights, protocol.DocumentHighlight{
+			Range: rangeFromNode(parent.NamedChild(0)),
+			Kind:  protocol.DocumentHighlightKindWrite,
+		})
+	}
+	for _, occurrence := range lsp.findOccurrences(path, target) {
+		kind := protocol.DocumentHighlightKindRead
+		if occurrence.is_write {
+			kind = protocol.DocumentHighlightKindWrite
+		}
+		highlights = append(highlights, protocol.DocumentHighlight{
+			Range: rangeFromPoints(occurrence.startPoint(), occurrence.endPoint()),
+			Kind:  kind,
+		})
+	}
+	return highlights
+}
+
+// getLocalDefinition handles the symbols that can be resolved without looking
+// at other files: local variables, parameters, loop variables and &
+func (lsp *Lsp) getLocalDefinition(path string, position sitter.Point) *[]protocol.Location {
+	target, ok := lsp.symbolAtPosition(path, position)
+	if !ok || target.node == nil {
+		return nil
+	}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return nil
+	}
+	locations := []protocol.Location{}
+	switch target.item_type {
+	case itemTypeParent:
+		parent := parentRuleSet(target.node)
+		if parent == nil {
+			return nil
+		}
+		locations = append(locations, protocol.Location{
+			URI:   uri.URI("file://" + path),
+			Range: rangeFromNode(parent.NamedChild(0)),
+		})
+	case itemTypeVariable:
+		scope := variableScope(target, input)
+		if scope == nil || scope.Type() == "stylesheet" {
+			return nil
+		}
+		for _, occurrence := range lsp.findOccurrences(path, target) {
+			if occurrence.is_write {
+				locations = append(locations, protocol.Location{
+					URI:   uri.URI("file://" + path),
+					Range: rangeFromNode(occurrence.node),
+				})
+				break
+			}
+		}
+	}
+	if len(locations) == 0 {
+		return nil
+	}
+	return &locations
+}
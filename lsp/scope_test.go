@@ -0,0 +1,129 @@
+package lsp
+
+import (
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+// lspWithSources parses the sources without touching the disk, the cache is
+// used instead of reading the files
+func lspWithSources(t *testing.T, sources map[string]string) *Lsp {
+	t.Helper()
+	lsp := DefaultLsp()
+	for path, source := range sources {
+		input := []byte(source)
+		lsp.Cache[path] = input
+		if _, err := lsp.UpdateTreeBytes(path, &input); err != nil {
+			t.Fatalf("failed to parse %s: %v", path, err)
+		}
+	}
+	return lsp
+}
+
+const scopeSource = `$size: 1px;
+@mixin m($size, $b: $size) {
+  $inner: $size;
+  width: $inner + $b;
+}
+.a {
+  height: $size;
+  &:hover { color: red; }
+  & > .b { top: 0; }
+  @include m($size);
+}
+`
+
+func TestDocumentHighlightLocalVariable(t *testing.T) {
+	path := "/virtual/scope.scss"
+	lsp := lspWithSources(t, map[string]string{path: scopeSource})
+
+	// the $size parameter of the mixin
+	highlights := lsp.GetDocumentHighlights(path, sitter.Point{Row: 1, Column: 10})
+	expected := []protocol.DocumentHighlight{
+		{Range: rangeFromPoints(sitter.Point{Row: 1, Column: 9}, sitter.Point{Row: 1, Column: 14}), Kind: protocol.DocumentHighlightKindWrite},
+		{Range: rangeFromPoints(sitter.Point{Row: 1, Column: 20}, sitter.Point{Row: 1, Column: 25}), Kind: protocol.DocumentHighlightKindRead},
+		{Range: rangeFromPoints(sitter.Point{Row: 2, Column: 10}, sitter.Point{Row: 2, Column: 15}), Kind: protocol.DocumentHighlightKindRead},
+	}
+	if len(highlights) != len(expected) {
+		t.Fatalf("expected %d highlights, got %d: %+v", len(expected), len(highlights), highlights)
+	}
+	for idx := range highlights {
+		if highlights[idx] != expected[idx] {
+			t.Fatalf("expected %+v, got %+v at %v", expected[idx], highlights[idx], idx)
+		}
+	}
+}
+
+func TestDocumentHighlightGlobalVariable(t *testing.T) {
+	path := "/virtual/scope.scss"
+	lsp := lspWithSources(t, map[string]string{path: scopeSource})
+
+	// $size used in .a is the global one, the mixin parameter is not included
+	highlights := lsp.GetDocumentHighlights(path, sitter.Point{Row: 6, Column: 11})
+	expected := []protocol.DocumentHighlight{
+		{Range: rangeFromPoints(sitter.Point{Row: 0, Column: 0}, sitter.Point{Row: 0, Column: 5}), Kind: protocol.DocumentHighlightKindWrite},
+		{Range: rangeFromPoints(sitter.Point{Row: 6, Column: 10}, sitter.Point{Row: 6, Column: 15}), Kind: protocol.DocumentHighlightKindRead},
+		{Range: rangeFromPoints(sitter.Point{Row: 9, Column: 13}, sitter.Point{Row: 9, Column: 18}), Kind: protocol.DocumentHighlightKindRead},
+	}
+	if len(highlights) != len(expected) {
+		t.Fatalf("expected %d highlights, got %d: %+v", len(expected), len(highlights), highlights)
+	}
+	for idx := range highlights {
+		if highlights[idx] != expected[idx] {
+			t.Fatalf("expected %+v, got %+v at %v", expected[idx], highlights[idx], idx)
+		}
+	}
+}
+
+func TestDocumentHighlightParentSelector(t *testing.T) {
+	path := "/virtual/scope.scss"
+	lsp := lspWithSources(t, map[string]string{path: scopeSource})
+
+	highlights := lsp.GetDocumentHighlights(path, sitter.Point{Row: 7, Column: 2})
+	if len(highlights) != 3 {
+		t.Fatalf("expected 3 highlights, got %d: %+v", len(highlights), highlights)
+	}
+	if highlights[0].Kind != protocol.DocumentHighlightKindWrite || highlights[0].Range.Start.Line != 5 {
+		t.Fatalf("expected the parent selector first, got %+v", highlights[0])
+	}
+}
+
+func TestLocalDefinition(t *testing.T) {
+	path := "/virtual/scope.scss"
+	lsp := lspWithSources(t, map[string]string{path: scopeSource})
+
+	locations := lsp.GetDefinitionInfo(path, sitter.Point{Row: 3, Column: 11})
+	if locations == nil || len(*locations) != 1 {
+		t.Fatalf("expected 1 location, got %+v", locations)
+	}
+	if (*locations)[0].Range != rangeFromPoints(sitter.Point{Row: 2, Column: 2}, sitter.Point{Row: 2, Column: 8}) {
+		t.Fatalf("expected $inner declaration, got %+v", (*locations)[0].Range)
+	}
+}
+
+func TestDocumentHighlightPlaceholder(t *testing.T) {
+	path := "/virtual/placeholder.scss"
+	lsp := lspWithSources(t, map[string]string{path: `%bar {
+  color: red;
+}
+.btn {
+  @extend %bar;
+}
+`})
+
+	highlights := lsp.GetDocumentHighlights(path, sitter.Point{Row: 4, Column: 12})
+	expected := []protocol.DocumentHighlight{
+		{Range: rangeFromPoints(sitter.Point{Row: 4, Column: 10}, sitter.Point{Row: 4, Column: 14}), Kind: protocol.DocumentHighlightKindRead},
+		{Range: rangeFromPoints(sitter.Point{Row: 0, Column: 1}, sitter.Point{Row: 0, Column: 4}), Kind: protocol.DocumentHighlightKindWrite},
+	}
+	if len(highlights) != len(expected) {
+		t.Fatalf("expected %d highlights, got %d: %+v", len(expected), len(highlights), highlights)
+	}
+	for idx := range highlights {
+		if highlights[idx] != expected[idx] {
+			t.Fatalf("expected %+v, got %+v at %v", expected[idx], highlights[idx], idx)
+		}
+	}
+}
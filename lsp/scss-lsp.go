@@ -33,6 +33,23 @@ type Lsp struct {
 	Variables     map[string][]isDefined
 	Calls         map[string][]isDefined
 	CallWhitelist []string
+	Modules       map[string][]moduleStatement
+	Placeholders  map[string][]isDefined
+	Extends       map[string][]isDefined
+	Properties    map[string][]isDefined
+	Config        *Config
+	// nil without a baseline file
+	Baseline *Baseline
+	// the rules of the config, run after the ones of the registry
+	customRules []Rule
+	// the problems with the config, they are logged but the cli has no log
+	ConfigErrors []string
+	// what the whole workspace uses, the files that load each file and what
+	// those see, nil until a rule needs them again after a tree or the config
+	// changes
+	uses    *symbolUses
+	loaders map[string][]string
+	scopes  map[string]map[string][]scopeDefinition
 }
 
 type Entry struct {
@@ -46,6 +63,14 @@ type isDefined struct {
 	body           string
 	start_position sitter.Point
 	end_position   sitter.Point
+	// only set for calls, what is being called, "@mixin", "@function" or
+	// "$variable"
+	item_type string
+	// only set for calls, the mixin, function or rule set the call is in, nil
+	// if it is at the top of the file
+	caller *isDefined
+	// only set for definitions with a /// comment above them
+	doc *sassDoc
 }
 
 func DefaultLsp() *Lsp {
@@ -59,6 +84,11 @@ func DefaultLsp() *Lsp {
 		Variables:       make(map[string][]isDefined),
 		Calls:           make(map[string][]isDefined),
 		Cache:           make(map[string][]byte),
+		Modules:         make(map[string][]moduleStatement),
+		Placeholders:    make(map[string][]isDefined),
+		Extends:         make(map[string][]isDefined),
+		Properties:      make(map[string][]isDefined),
+		Config:          DefaultConfig(),
 		CallWhitelist:   []string{
       "url",
       "var",
@@ -68,16 +98,23 @@ func DefaultLsp() *Lsp {
       "linear-gradient",
       "repeat",
       "nth-child",
-      "darken",
-      "lighten",
       "rgba",
       "rgb",
       "translate",
-      "mix",
     },
 	}
 }
 
+// LoadWorkspace parses every file under the root like initialize does, for
+// running without a client
+func LoadWorkspace(root string) *Lsp {
+	lsp := DefaultLsp()
+	lsp.RootPath = root
+	lsp.LoadConfig(nil)
+	lsp.WalkFromRoot()
+	return lsp
+}
+
 func (lsp *Lsp) getWordAtPosition(data *string, line, column int) (string, error) {
 	// Convert byte array to string
 	// Split the content into lines
@@ -128,6 +165,11 @@ func (lsp *Lsp) WalkFromRoot() {
 	// just tested it, and it is still super fast
 	exclude_dirs := []string{".git", "build", "vendor", "contrib"}
 	filepath.WalkDir(lsp.RootPath, func(path string, d os.DirEntry, err error) error {
+		// an unreadable dir, or a root that doesnt exist
+		if err != nil {
+			lsp.Log(err.Error(), protocol.MessageTypeError)
+			return nil
+		}
 		for _, e := range exclude_dirs {
 			if e == d.Name() && d.IsDir() {
 				return filepath.SkipDir
@@ -187,11 +229,25 @@ func (lsp *Lsp) ParseAndSaveTree(path string) (*sitter.Tree, error) {
 func (lsp *Lsp) UpdateTree(tree *sitter.Tree, path string, input *[]byte) {
 	// this doesnt work great if there are more lsps
 	// so i need to figure out how to turn off specific capabilities of other lsps
+	// the css data (css_data.go) covers properties now, so a separate css lsp
+	// is not needed anymore
 	lsp.SelectorEntries[path] = lsp.Parser.ParseTree(tree, input)
 	lsp.Mixins[path] = lsp.Parser.ParseMixinsInTree(tree, input)
 	lsp.Functions[path] = lsp.Parser.ParseFunctionsInTree(tree, input)
 	lsp.Variables[path] = lsp.Parser.ParseVariablesInTree(tree, input)
 	lsp.Calls[path] = lsp.Parser.ParseCalls(tree, input)
+	lsp.Modules[path] = lsp.Parser.ParseModulesInTree(tree, input)
+	lsp.Placeholders[path] = lsp.Parser.ParsePlaceholdersInTree(tree, input)
+	lsp.Extends[path] = lsp.Parser.ParseExtendsInTree(tree, input)
+	lsp.Properties[path] = lsp.Parser.ParsePropertiesInTree(tree, input)
+	lsp.forgetWorkspace()
+}
+
+// forgetWorkspace drops what the rules keep about the whole workspace
+func (lsp *Lsp) forgetWorkspace() {
+	lsp.uses = nil
+	lsp.loaders = nil
+	lsp.scopes = nil
 }
 
 func (lsp *Lsp) findHoverableByNameInMap(name *string, in_this *map[string][]isDefined, item_type *string) *[]isDefinedInfo {
@@ -219,6 +275,7 @@ func (lsp *Lsp) findHoverableByName(name *string) *[]isDefinedInfo {
 	mixin := "@mixin"
 	function := "@function"
 	variable := "$variable"
+	placeholder := itemTypePlaceholder
 
 	is_defined_object := lsp.findHoverableByNameInMap(name, &lsp.Mixins, &mixin)
 	defined_array = append(defined_array, *is_defined_object...)
@@ -229,6 +286,9 @@ func (lsp *Lsp) findHoverableByName(name *string) *[]isDefinedInfo {
 	is_defined_object = lsp.findHoverableByNameInMap(name, &lsp.Variables, &variable)
 	defined_array = append(defined_array, *is_defined_object...)
 
+	is_defined_object = lsp.findHoverableByNameInMap(name, &lsp.Placeholders, &placeholder)
+	defined_array = append(defined_array, *is_defined_object...)
+
 	return &defined_array
 }
 
@@ -283,23 +343,15 @@ func (lsp *Lsp) GetHoverInfo(path string, position sitter.Point) string {
 	root := tree.RootNode()
 	node := root.NamedDescendantForPointRange(position, position)
 	input := node.Content(*bytes)
-	definitions := *lsp.findHoverableByName(&input)
-
-	if len(definitions) == 0 {
-		return ""
+	if node.Type() == "property_name" {
+		if property, ok := lookupCssProperty(input); ok {
+			return property.documentation()
+		}
 	}
-	var sb strings.Builder
-	// no sass parser for markdown? unlucky
-	// probably can make a neovim plugin maybe?, we just need to use sass parser
-	// in the sass part of markdown hmm
-	sb.WriteString("```css\n")
-	sb.WriteString(definitions[0].is_defined.body)
-	sb.WriteString("\n```")
-	sb.WriteString("\n")
-	sb.WriteString(definitions[0].item_type)
-	sb.WriteString(" defined in: ")
-	sb.WriteString(definitions[0].path)
-	return sb.String()
+	if hover, ok := lsp.builtinHover(path, *bytes, node, position); ok {
+		return hover
+	}
+	return lsp.symbolHover(path, *bytes, node, position)
 }
 
 func (lsp *Lsp) UpdateTreeBytes(path string, input *[]byte) (*sitter.Tree, error) {
@@ -321,6 +373,10 @@ func (lsp *Lsp) GetDefinitionInfo(path string, position sitter.Point) *[]protoco
 	if tree == nil {
 		return nil
 	}
+	// local variables and & never need to look at other files
+	if local_definition := lsp.getLocalDefinition(path, position); local_definition != nil {
+		return local_definition
+	}
 	ts_tree := tree
 	root := ts_tree.RootNode()
 	node := root.NamedDescendantForPointRange(position, position)
@@ -332,6 +388,11 @@ func (lsp *Lsp) GetDefinitionInfo(path string, position sitter.Point) *[]protoco
 		return nil
 	}
 	word := node.Content(*input)
+	// the node of a placeholder is only the name without the %, and @extend
+	// %placeholder doesnt have a node at all
+	if target, ok := lsp.symbolAtPosition(path, position); ok && target.item_type == itemTypePlaceholder {
+		word = target.name
+	}
 	definitions := *lsp.findHoverableByName(&word)
 	if len(definitions) == 0 {
 		return nil
@@ -428,6 +489,17 @@ func (lsp *Lsp) gatherSymbolsInPath(path string) []protocol.SymbolInformation {
 		})
 	}
 
+	for _, entry := range lsp.Placeholders[path] {
+		items = append(items, protocol.SymbolInformation{
+			Name: entry.name,
+			Kind: protocol.SymbolKindClass,
+			Location: protocol.Location{
+				URI:   uri.URI("file://" + path),
+				Range: rangeFromPoints(entry.start_position, entry.start_position),
+			},
+		})
+	}
+
 	for _, entry := range lsp.SelectorEntries[path] {
 		items = append(items, protocol.SymbolInformation{
 			Name: entry.name,
@@ -463,35 +535,49 @@ func (lsp *Lsp) doesCallExist(call_name string) bool {
 	return false
 }
 
-func (lsp *Lsp) reportDiagnostics(path string) {
-	diagnostics := []protocol.Diagnostic{}
-	for _, entry := range lsp.Calls[path] {
-		for _, call := range lsp.CallWhitelist {
-			if call == entry.name {
-				return
-			}
+func (lsp *Lsp) isCallAllowed(call_name string) bool {
+	allowed := lsp.CallWhitelist
+	if lsp.Config != nil {
+		allowed = append(allowed[:len(allowed):len(allowed)], lsp.Config.AllowedFunctions...)
+	}
+	for _, call := range allowed {
+		if call == call_name {
+			return true
 		}
-		if !lsp.doesCallExist(entry.name) {
-			diagnostic := protocol.Diagnostic{
-				Range: protocol.Range{
-					Start: protocol.Position{
-						Line:      entry.start_position.Row,
-						Character: entry.start_position.Column,
-					},
-					End: protocol.Position{
-						Line:      entry.end_position.Row,
-						Character: entry.end_position.Column,
-					},
-				},
-				Severity:        protocol.DiagnosticSeverityError,
-				Code:            nil,
-				CodeDescription: &protocol.CodeDescription{},
-				Source:          "SCSS-LSP",
-				Message:         "undefined",
-			}
-			diagnostics = append(diagnostics, diagnostic)
+	}
+	return false
+}
+
+// isBuiltinCall checks for built-in functions and for the parts of
+// namespaced calls the grammar gets wrong, memberDiagnostics checks those
+func (lsp *Lsp) isBuiltinCall(path string, entry isDefined) bool {
+	if strings.Contains(entry.name, ".") {
+		return true
+	}
+	if entry.item_type == itemTypeFunction {
+		if _, ok := lookupBuiltinGlobal(entry.name); ok {
+			return true
 		}
 	}
+	// @include ns.mixin ends up as a call of ns
+	if entry.item_type == itemTypeMixin {
+		if _, ok := lsp.namespaceModule(path, entry.name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getDiagnostics runs the rules on a file, then drops what the comments of
+// the file and the baseline hide
+func (lsp *Lsp) getDiagnostics(path string) []protocol.Diagnostic {
+	diagnostics := lsp.runRules(path)
+	diagnostics = lsp.withoutSuppressed(path, diagnostics)
+	return lsp.withoutBaseline(path, diagnostics)
+}
+
+func (lsp *Lsp) reportDiagnostics(path string) {
+	diagnostics := lsp.getDiagnostics(path)
 	lsp.SendDiagnostic(path, &diagnostics)
 }
 
@@ -506,6 +592,21 @@ func (lsp *Lsp) getReferences(path string, position sitter.Point) []protocol.Loc
 	node := root.NamedDescendantForPointRange(position, position)
 	word := node.Content(*byte_input)
 
+	if target, ok := lsp.symbolAtPosition(path, position); ok && target.item_type == itemTypePlaceholder {
+		word = target.name
+		for path := range lsp.Trees {
+			for _, entry := range lsp.Extends[path] {
+				if entry.name == word {
+					references = append(references, protocol.Location{
+						URI:   uri.URI("file://" + path),
+						Range: rangeFromPoints(entry.start_position, entry.end_position),
+					})
+				}
+			}
+		}
+		return references
+	}
+
 	for path := range lsp.Trees {
 		for _, entry := range lsp.Calls[path] {
 			if entry.name == word {
@@ -555,32 +656,58 @@ func (lsp *Lsp) LspHandler(ctx context.Context, reply rpc2.Replier, req rpc2.Req
 			ctx.Done()
 			return reply(ctx, fmt.Errorf("no root path"), nil)
 		}
+		lsp.LoadConfig(replyParams.InitializationOptions)
 
 		go func() {
 			lsp.WalkFromRoot()
 		}()
 		path := replyParams.RootURI.Filename()
 		lsp.reportDiagnostics(path)
-		return reply(ctx, protocol.InitializeResult{
-			Capabilities: protocol.ServerCapabilities{
-				// this doesnt work as good as i expected, but it works
-				WorkspaceSymbolProvider: true,
-				// this works quite good but if multiple lsps are runnning then it will
-				// only show info from one of them, at least in nvim
-				DocumentSymbolProvider: true,
-				DefinitionProvider:     true,
-				ReferencesProvider:     true,
-				HoverProvider:          true,
-				CompletionProvider: &protocol.CompletionOptions{
-					ResolveProvider:   false,
-					TriggerCharacters: []string{"$", "@"},
-				},
-				TextDocumentSync: protocol.TextDocumentSyncOptions{
-					Change:    protocol.TextDocumentSyncKindFull,
-					OpenClose: true,
-					WillSave:  true,
-					Save: &protocol.SaveOptions{
-						IncludeText: true,
+		return reply(ctx, initializeResult{
+			Capabilities: serverCapabilities{
+				// not in go.lsp.dev/protocol yet
+				TypeHierarchyProvider: true,
+				ServerCapabilities: protocol.ServerCapabilities{
+					// this doesnt work as good as i expected, but it works
+					WorkspaceSymbolProvider: true,
+					// this works quite good but if multiple lsps are runnning then it will
+					// only show info from one of them, at least in nvim
+					DocumentSymbolProvider:    true,
+					DefinitionProvider:        true,
+					ReferencesProvider:        true,
+					DocumentHighlightProvider: true,
+					HoverProvider:             true,
+					SelectionRangeProvider:    true,
+					CallHierarchyProvider:     true,
+					DocumentLinkProvider: &protocol.DocumentLinkOptions{
+						ResolveProvider: false,
+					},
+					CodeActionProvider: &protocol.CodeActionOptions{
+						CodeActionKinds: []protocol.CodeActionKind{protocol.QuickFix},
+					},
+					ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
+						Commands: []string{commandAllowFunction, commandMigrateImports, commandRewrite},
+					},
+					SignatureHelpProvider: &protocol.SignatureHelpOptions{
+						TriggerCharacters: []string{"(", ","},
+					},
+					DocumentFormattingProvider:      true,
+					DocumentRangeFormattingProvider: true,
+					DocumentOnTypeFormattingProvider: &protocol.DocumentOnTypeFormattingOptions{
+						FirstTriggerCharacter: "}",
+						MoreTriggerCharacter:  []string{";"},
+					},
+					CompletionProvider: &protocol.CompletionOptions{
+						ResolveProvider:   false,
+						TriggerCharacters: []string{"$", "@", ".", "%"},
+					},
+					TextDocumentSync: protocol.TextDocumentSyncOptions{
+						Change:    protocol.TextDocumentSyncKindFull,
+						OpenClose: true,
+						WillSave:  true,
+						Save: &protocol.SaveOptions{
+							IncludeText: true,
+						},
 					},
 				},
 			},
@@ -715,106 +842,223 @@ func (lsp *Lsp) LspHandler(ctx context.Context, reply rpc2.Replier, req rpc2.Req
 		}
 		return reply(ctx, definition_info, nil)
 
-	case protocol.MethodTextDocumentDidChange:
+	case protocol.MethodTextDocumentDocumentHighlight:
 		params := req.Params()
-		var replyParams protocol.DidChangeTextDocumentParams
+		var replyParams protocol.DocumentHighlightParams
 		err := json.Unmarshal(params, &replyParams)
 		if err != nil {
 			return reply(ctx, fmt.Errorf("?"), nil)
 		}
 		path := replyParams.TextDocument.URI.Filename()
-		text := []byte(replyParams.ContentChanges[0].Text)
+		position := replyParams.Position
+		tree_point := sitter.Point{
+			Row:    position.Line,
+			Column: position.Character,
+		}
+		return reply(ctx, lsp.GetDocumentHighlights(path, tree_point), nil)
 
-		lsp.Cache[path] = text
+	case protocol.MethodTextDocumentCodeAction:
+		params := req.Params()
+		var replyParams protocol.CodeActionParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
+		}
+		path := replyParams.TextDocument.URI.Filename()
+		return reply(ctx, lsp.GetCodeActions(path, replyParams.Context), nil)
 
-		if lsp.Trees[path] != nil {
-			_, err := lsp.UpdateTreeBytes(path, &text)
-			if err != nil {
-				lsp.Log(err.Error(), protocol.MessageTypeError)
-				return reply(ctx, fmt.Errorf("goodbye"), nil)
-			}
+	case protocol.MethodWorkspaceExecuteCommand:
+		params := req.Params()
+		var replyParams protocol.ExecuteCommandParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
 		}
-		return reply(ctx, fmt.Errorf("goodbye"), nil)
+		result, err := lsp.ExecuteCommand(replyParams.Command, replyParams.Arguments)
+		if err != nil {
+			lsp.Log(err.Error(), protocol.MessageTypeError)
+			return reply(ctx, nil, err)
+		}
+		return reply(ctx, result, nil)
 
-	case protocol.MethodTextDocumentCompletion:
+	case protocol.MethodTextDocumentSignatureHelp:
 		params := req.Params()
-		var replyParams protocol.CompletionParams
+		var replyParams protocol.SignatureHelpParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
+		}
+		path := replyParams.TextDocument.URI.Filename()
+		return reply(ctx, lsp.GetSignatureHelp(path, replyParams.Position), nil)
+
+	case protocol.MethodTextDocumentFormatting:
+		params := req.Params()
+		var replyParams protocol.DocumentFormattingParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
+		}
+		path := replyParams.TextDocument.URI.Filename()
+		return reply(ctx, lsp.FormatDocument(path, replyParams.Options), nil)
+
+	case protocol.MethodTextDocumentRangeFormatting:
+		params := req.Params()
+		var replyParams protocol.DocumentRangeFormattingParams
 		err := json.Unmarshal(params, &replyParams)
 		if err != nil {
 			return reply(ctx, fmt.Errorf("?"), nil)
 		}
+		path := replyParams.TextDocument.URI.Filename()
+		start := sitter.Point{
+			Row:    replyParams.Range.Start.Line,
+			Column: replyParams.Range.Start.Character,
+		}
+		end := sitter.Point{
+			Row:    replyParams.Range.End.Line,
+			Column: replyParams.Range.End.Character,
+		}
+		return reply(ctx, lsp.FormatRange(path, start, end, replyParams.Options), nil)
+
+	case protocol.MethodTextDocumentOnTypeFormatting:
+		params := req.Params()
+		var replyParams protocol.DocumentOnTypeFormattingParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
+		}
+		path := replyParams.TextDocument.URI.Filename()
+		tree_point := sitter.Point{
+			Row:    replyParams.Position.Line,
+			Column: replyParams.Position.Character,
+		}
+		return reply(ctx, lsp.FormatOnType(path, tree_point, replyParams.Options), nil)
+
+	case protocol.MethodTextDocumentDocumentLink:
+		params := req.Params()
+		var replyParams protocol.DocumentLinkParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
+		}
+		path := replyParams.TextDocument.URI.Filename()
+		return reply(ctx, lsp.GetDocumentLinks(path), nil)
+
+	case protocol.MethodTextDocumentPrepareCallHierarchy:
+		params := req.Params()
+		var replyParams protocol.CallHierarchyPrepareParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
+		}
+		path := replyParams.TextDocument.URI.Filename()
 		position := replyParams.Position
-		is_incomplete := replyParams.Context.TriggerKind == protocol.CompletionTriggerKindTriggerForIncompleteCompletions
+		tree_point := sitter.Point{
+			Row:    position.Line,
+			Column: position.Character,
+		}
+		return reply(ctx, lsp.PrepareCallHierarchy(path, tree_point), nil)
 
-		prefix := ""
-		column := position.Character
-		tree := lsp.Trees[replyParams.TextDocument.URI.Filename()]
-		if tree == nil {
-			lsp.ParseAndSaveTree(replyParams.TextDocument.URI.Filename())
-			tree = lsp.Trees[replyParams.TextDocument.URI.Filename()]
+	case protocol.MethodCallHierarchyIncomingCalls:
+		params := req.Params()
+		var replyParams protocol.CallHierarchyIncomingCallsParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
 		}
-		input, err := lsp.bytesFromFilePath(replyParams.TextDocument.URI.Filename())
+		return reply(ctx, lsp.GetIncomingCalls(replyParams.Item), nil)
+
+	case protocol.MethodCallHierarchyOutgoingCalls:
+		params := req.Params()
+		var replyParams protocol.CallHierarchyOutgoingCallsParams
+		err := json.Unmarshal(params, &replyParams)
 		if err != nil {
-			return reply(ctx, fmt.Errorf("error reading file"), nil)
+			return reply(ctx, fmt.Errorf("?"), nil)
 		}
-		input_string := string(*input)
-		prefix, err = lsp.getWordAtPosition(&input_string, int(position.Line), int(column)-1)
+		return reply(ctx, lsp.GetOutgoingCalls(replyParams.Item), nil)
 
+	case methodTextDocumentPrepareTypeHierarchy:
+		params := req.Params()
+		var replyParams typeHierarchyPrepareParams
+		err := json.Unmarshal(params, &replyParams)
 		if err != nil {
-			lsp.Log(err.Error(), protocol.MessageTypeError)
+			return reply(ctx, fmt.Errorf("?"), nil)
 		}
-		trigger_character := replyParams.Context.TriggerCharacter
-		items := []protocol.CompletionItem{}
+		path := replyParams.TextDocument.URI.Filename()
+		position := replyParams.Position
+		tree_point := sitter.Point{
+			Row:    position.Line,
+			Column: position.Character,
+		}
+		return reply(ctx, lsp.PrepareTypeHierarchy(path, tree_point), nil)
 
-		if strings.Contains(prefix, "$") {
-			is_incomplete = true
+	case methodTypeHierarchySupertypes:
+		params := req.Params()
+		var replyParams typeHierarchyItemParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
 		}
+		return reply(ctx, lsp.GetSupertypes(replyParams.Item), nil)
 
-		if trigger_character == "@" {
-			for path := range lsp.Mixins {
-				for _, entry := range lsp.Mixins[path] {
-					items = append(items, protocol.CompletionItem{
-						Label:         entry.name,
-						Kind:          protocol.CompletionItemKindInterface,
-						Documentation: entry.body + "\n\n" + path,
-						InsertText:    "include " + entry.name,
-					})
-				}
-			}
+	case methodTypeHierarchySubtypes:
+		params := req.Params()
+		var replyParams typeHierarchyItemParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
+		}
+		return reply(ctx, lsp.GetSubtypes(replyParams.Item), nil)
 
-			for path := range lsp.Functions {
-				for _, entry := range lsp.Functions[path] {
-					items = append(items, protocol.CompletionItem{
-						Label:         entry.name,
-						Kind:          protocol.CompletionItemKindFunction,
-						Documentation: entry.body + "\n\n" + path,
-						InsertText:    entry.name,
-					})
-				}
-			}
+	case methodTextDocumentSelectionRange:
+		params := req.Params()
+		var replyParams selectionRangeParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
 		}
+		path := replyParams.TextDocument.URI.Filename()
+		tree_points := []sitter.Point{}
+		for _, position := range replyParams.Positions {
+			tree_points = append(tree_points, sitter.Point{
+				Row:    position.Line,
+				Column: position.Character,
+			})
+		}
+		return reply(ctx, lsp.GetSelectionRanges(path, tree_points), nil)
 
-		if len(prefix) > 2 {
-			is_incomplete = true
-			prefix = prefix[1:]
-			for path := range lsp.Variables {
-				for _, entry := range lsp.Variables[path] {
-					if !strings.Contains(entry.name, prefix) {
-						continue
-					}
-					items = append(items, protocol.CompletionItem{
-						Label:         entry.name,
-						Kind:          protocol.CompletionItemKindVariable,
-						Documentation: entry.body + "\n\n" + path,
-						InsertText:    entry.name,
-					})
-				}
+	case protocol.MethodTextDocumentDidChange:
+		params := req.Params()
+		var replyParams protocol.DidChangeTextDocumentParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
+		}
+		path := replyParams.TextDocument.URI.Filename()
+		text := []byte(replyParams.ContentChanges[0].Text)
+
+		lsp.Cache[path] = text
+
+		if lsp.Trees[path] != nil {
+			_, err := lsp.UpdateTreeBytes(path, &text)
+			if err != nil {
+				lsp.Log(err.Error(), protocol.MessageTypeError)
+				return reply(ctx, fmt.Errorf("goodbye"), nil)
 			}
 		}
-		return reply(ctx, protocol.CompletionList{
-			IsIncomplete: is_incomplete,
-			Items:        items,
-		}, nil)
+		return reply(ctx, fmt.Errorf("goodbye"), nil)
+
+	case protocol.MethodTextDocumentCompletion:
+		params := req.Params()
+		var replyParams protocol.CompletionParams
+		err := json.Unmarshal(params, &replyParams)
+		if err != nil {
+			return reply(ctx, fmt.Errorf("?"), nil)
+		}
+		path := replyParams.TextDocument.URI.Filename()
+		if lsp.Trees[path] == nil {
+			lsp.ParseAndSaveTree(path)
+		}
+		return reply(ctx, lsp.GetCompletions(path, replyParams.Position), nil)
 
 	case protocol.MethodShutdown:
 		// without this pylsp-test throws an error, but it's useless, i think
@@ -838,6 +1082,10 @@ func (lsp *Lsp) Init() {
 }
 
 func (lsp *Lsp) Log(message string, messageType protocol.MessageType) {
+	// there is no connection in the tests
+	if lsp.RootConn == nil {
+		return
+	}
 	lsp.RootConn.Notify(context.Background(), protocol.MethodWindowLogMessage, protocol.LogMessageParams{
 		Message: fmt.Sprintf("SCSS-LSP: %s", message),
 		Type:    messageType,
@@ -845,6 +1093,9 @@ func (lsp *Lsp) Log(message string, messageType protocol.MessageType) {
 }
 
 func (lsp *Lsp) SendDiagnostic(path string, diagnostics *[]protocol.Diagnostic) {
+	if lsp.RootConn == nil {
+		return
+	}
 	lsp.RootConn.Notify(context.Background(), protocol.MethodTextDocumentPublishDiagnostics, protocol.PublishDiagnosticsParams{
 		URI:         uri.URI("file://" + path),
 		Version:     0,
@@ -852,6 +1103,17 @@ func (lsp *Lsp) SendDiagnostic(path string, diagnostics *[]protocol.Diagnostic)
 	})
 }
 
+// the capabilities that go.lsp.dev/protocol doesnt know about yet go here
+type serverCapabilities struct {
+	protocol.ServerCapabilities
+	TypeHierarchyProvider bool `json:"typeHierarchyProvider,omitempty"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities   `json:"capabilities"`
+	ServerInfo   *protocol.ServerInfo `json:"serverInfo,omitempty"`
+}
+
 type rwc struct {
 	r io.ReadCloser
 	w io.WriteCloser
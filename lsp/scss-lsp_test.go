@@ -1,6 +1,7 @@
 package lsp_test
 
 import (
+	"path/filepath"
 	"testing"
 	"go.lsp.dev/uri"
   lsp "scss-lsp/lsp"
@@ -8,10 +9,11 @@ import (
 
 func makeTestLsp() *lsp.Lsp {
 	local_lsp := lsp.DefaultLsp()
-	parsed_uri, err := uri.Parse("file:///home/ron/programs/scss-lsp/test_dir")
+	root, err := filepath.Abs("../test_dir")
 	if err != nil {
 		return nil
 	}
+	parsed_uri := uri.File(root)
 	local_lsp.RootPath = parsed_uri.Filename()
 	return local_lsp
 }
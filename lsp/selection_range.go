@@ -0,0 +1,153 @@
+package lsp
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+// go.lsp.dev/protocol has the SelectionRange type but not the method or the
+// params, so they live here
+const methodTextDocumentSelectionRange = "textDocument/selectionRange"
+
+type selectionRangeParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Positions    []protocol.Position             `json:"positions"`
+}
+
+// nodes whose children are separated by commas, these get expanded one item
+// at a time instead of jumping straight to the whole list
+var commaListTypes = []string{"selectors", "arguments", "parameters"}
+
+func isCommaList(node *sitter.Node) bool {
+	if node == nil {
+		return false
+	}
+	for _, list_type := range commaListTypes {
+		if node.Type() == list_type {
+			return true
+		}
+	}
+	return false
+}
+
+func rangeFromPoints(start_position sitter.Point, end_position sitter.Point) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{
+			Line:      start_position.Row,
+			Character: start_position.Column,
+		},
+		End: protocol.Position{
+			Line:      end_position.Row,
+			Character: end_position.Column,
+		},
+	}
+}
+
+func rangeFromNode(node *sitter.Node) protocol.Range {
+	return rangeFromPoints(node.StartPoint(), node.EndPoint())
+}
+
+// listItems returns the named children of a comma separated list, comments are
+// not items
+func listItems(list *sitter.Node) []*sitter.Node {
+	items := []*sitter.Node{}
+	for i := 0; i < int(list.NamedChildCount()); i++ {
+		child := list.NamedChild(i)
+		if child.Type() == "comment" || child.Type() == "single_line_comment" {
+			continue
+		}
+		items = append(items, child)
+	}
+	return items
+}
+
+// listExpansion returns the ranges between a single item and the whole list,
+// first growing towards the end of the list then towards the start
+func listExpansion(item *sitter.Node, list *sitter.Node) []protocol.Range {
+	ranges := []protocol.Range{}
+	items := listItems(list)
+	idx := -1
+	for i, entry := range items {
+		if entry.Equal(item) {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return ranges
+	}
+	last := items[len(items)-1]
+	for end := idx + 1; end < len(items); end++ {
+		ranges = append(ranges, rangeFromPoints(item.StartPoint(), items[end].EndPoint()))
+	}
+	for start := idx - 1; start >= 0; start-- {
+		ranges = append(ranges, rangeFromPoints(items[start].StartPoint(), last.EndPoint()))
+	}
+	return ranges
+}
+
+// valueSpan is the range of everything after the colon in a declaration, for
+// things like `margin: 0 auto` where there is no single value node
+func valueSpan(declaration *sitter.Node) (protocol.Range, bool) {
+	values := []*sitter.Node{}
+	for i := 1; i < int(declaration.NamedChildCount()); i++ {
+		values = append(values, declaration.NamedChild(i))
+	}
+	if len(values) < 2 {
+		return protocol.Range{}, false
+	}
+	return rangeFromPoints(values[0].StartPoint(), values[len(values)-1].EndPoint()), true
+}
+
+func (lsp *Lsp) selectionRangeAtPoint(root *sitter.Node, position sitter.Point) protocol.SelectionRange {
+	ranges := []protocol.Range{}
+	add := func(new_range protocol.Range) {
+		if len(ranges) > 0 && ranges[len(ranges)-1] == new_range {
+			return
+		}
+		ranges = append(ranges, new_range)
+	}
+
+	node := root.NamedDescendantForPointRange(position, position)
+	for node != nil {
+		add(rangeFromNode(node))
+		parent := node.Parent()
+		if isCommaList(parent) {
+			for _, list_range := range listExpansion(node, parent) {
+				add(list_range)
+			}
+		}
+		if parent != nil && parent.Type() == "declaration" && !parent.NamedChild(0).Equal(node) {
+			if span, ok := valueSpan(parent); ok {
+				add(span)
+			}
+		}
+		node = parent
+	}
+
+	// the client wants the innermost range with the parents linked to it
+	var selection_range *protocol.SelectionRange
+	for i := len(ranges) - 1; i >= 0; i-- {
+		selection_range = &protocol.SelectionRange{
+			Range:  ranges[i],
+			Parent: selection_range,
+		}
+	}
+	if selection_range == nil {
+		return protocol.SelectionRange{Range: rangeFromPoints(position, position)}
+	}
+	return *selection_range
+}
+
+func (lsp *Lsp) GetSelectionRanges(path string, positions []sitter.Point) []protocol.SelectionRange {
+	selection_ranges := []protocol.SelectionRange{}
+	tree := lsp.Trees[path]
+	if tree == nil {
+		return selection_ranges
+	}
+	root := tree.RootNode()
+	for _, position := range positions {
+		selection_ranges = append(selection_ranges, lsp.selectionRangeAtPoint(root, position))
+	}
+	return selection_ranges
+}
@@ -0,0 +1,92 @@
+package lsp
+
+import (
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+func flattenSelectionRange(selection_range protocol.SelectionRange) []protocol.Range {
+	ranges := []protocol.Range{selection_range.Range}
+	for parent := selection_range.Parent; parent != nil; parent = parent.Parent {
+		ranges = append(ranges, parent.Range)
+	}
+	return ranges
+}
+
+func expectRanges(t *testing.T, got []protocol.Range, expected []protocol.Range) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d ranges, got %d: %+v", len(expected), len(got), got)
+	}
+	for idx := range got {
+		if got[idx] != expected[idx] {
+			t.Fatalf("expected %+v, got %+v at %v", expected[idx], got[idx], idx)
+		}
+	}
+}
+
+func TestSelectionRangeSelectors(t *testing.T) {
+	lsp := DefaultLsp()
+	lsp.RootPath = "../test_dir"
+	test_tree := "../test_dir/selection.scss"
+	lsp.WalkFromRoot()
+
+	// on "panel" in `.card, .panel > .title, .footer {`
+	selection_ranges := lsp.GetSelectionRanges(test_tree, []sitter.Point{{Row: 0, Column: 9}})
+	if len(selection_ranges) != 1 {
+		t.Fatalf("expected 1 selection range, got %d", len(selection_ranges))
+	}
+	expected := []protocol.Range{
+		rangeFromPoints(sitter.Point{Row: 0, Column: 8}, sitter.Point{Row: 0, Column: 13}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 7}, sitter.Point{Row: 0, Column: 13}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 7}, sitter.Point{Row: 0, Column: 22}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 7}, sitter.Point{Row: 0, Column: 31}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 0}, sitter.Point{Row: 0, Column: 31}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 0}, sitter.Point{Row: 7, Column: 1}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 0}, sitter.Point{Row: 8, Column: 0}),
+	}
+	expectRanges(t, flattenSelectionRange(selection_ranges[0]), expected)
+}
+
+func TestSelectionRangeArguments(t *testing.T) {
+	lsp := DefaultLsp()
+	lsp.RootPath = "../test_dir"
+	test_tree := "../test_dir/selection.scss"
+	lsp.WalkFromRoot()
+
+	// on "#000" in `background: mix(#fff, #000, 50%);`
+	selection_ranges := lsp.GetSelectionRanges(test_tree, []sitter.Point{{Row: 2, Column: 25}})
+	expected := []protocol.Range{
+		rangeFromPoints(sitter.Point{Row: 2, Column: 24}, sitter.Point{Row: 2, Column: 28}),
+		rangeFromPoints(sitter.Point{Row: 2, Column: 24}, sitter.Point{Row: 2, Column: 33}),
+		rangeFromPoints(sitter.Point{Row: 2, Column: 18}, sitter.Point{Row: 2, Column: 33}),
+		rangeFromPoints(sitter.Point{Row: 2, Column: 17}, sitter.Point{Row: 2, Column: 34}),
+		rangeFromPoints(sitter.Point{Row: 2, Column: 14}, sitter.Point{Row: 2, Column: 34}),
+		rangeFromPoints(sitter.Point{Row: 2, Column: 2}, sitter.Point{Row: 2, Column: 35}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 32}, sitter.Point{Row: 7, Column: 1}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 0}, sitter.Point{Row: 7, Column: 1}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 0}, sitter.Point{Row: 8, Column: 0}),
+	}
+	expectRanges(t, flattenSelectionRange(selection_ranges[0]), expected)
+}
+
+func TestSelectionRangeDeclarationValues(t *testing.T) {
+	lsp := DefaultLsp()
+	lsp.RootPath = "../test_dir"
+	test_tree := "../test_dir/selection.scss"
+	lsp.WalkFromRoot()
+
+	// on "auto" in `margin: 0 auto;`
+	selection_ranges := lsp.GetSelectionRanges(test_tree, []sitter.Point{{Row: 1, Column: 13}})
+	expected := []protocol.Range{
+		rangeFromPoints(sitter.Point{Row: 1, Column: 12}, sitter.Point{Row: 1, Column: 16}),
+		rangeFromPoints(sitter.Point{Row: 1, Column: 10}, sitter.Point{Row: 1, Column: 16}),
+		rangeFromPoints(sitter.Point{Row: 1, Column: 2}, sitter.Point{Row: 1, Column: 17}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 32}, sitter.Point{Row: 7, Column: 1}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 0}, sitter.Point{Row: 7, Column: 1}),
+		rangeFromPoints(sitter.Point{Row: 0, Column: 0}, sitter.Point{Row: 8, Column: 0}),
+	}
+	expectRanges(t, flattenSelectionRange(selection_ranges[0]), expected)
+}
@@ -0,0 +1,163 @@
+package lsp
+
+import (
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// a mixin or function as shown in signature help
+type callSignature struct {
+	label         string
+	parameters    []string
+	documentation string
+	// only set for mixins and functions of the workspace
+	doc *sassDoc
+}
+
+// openCall finds the call the cursor is in the arguments of, the name is
+// with the namespace, argument is the index of the argument at the cursor
+func openCall(input []byte, offset int) (name string, argument int, is_include bool, ok bool) {
+	text := maskCommentsAndStrings(input)
+	depth := 0
+	open := -1
+	for idx := min(offset, len(text)) - 1; idx >= 0 && open == -1; idx-- {
+		switch text[idx] {
+		case ')', ']':
+			depth++
+		case '(', '[':
+			if depth == 0 {
+				if text[idx] == '[' {
+					return "", 0, false, false
+				}
+				open = idx
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				argument++
+			}
+		case ';', '{', '}':
+			return "", 0, false, false
+		}
+	}
+	if open == -1 {
+		return "", 0, false, false
+	}
+	start := open
+	for start > 0 && isWordChar(text[start-1]) {
+		start--
+	}
+	name = string(text[start:open])
+	if name == "" || strings.HasPrefix(name, "$") {
+		return "", 0, false, false
+	}
+	is_include = strings.HasSuffix(strings.TrimRight(string(text[:start]), " \t"), "@include")
+	return name, argument, is_include, true
+}
+
+// memberDefinition finds the definition of ns.member in the modules the file
+// uses
+func (lsp *Lsp) memberDefinition(path string, namespace string, member string, item_type string) (isDefined, bool) {
+	definitions := lsp.definitionMap(item_type)
+	for _, module := range lsp.VisibleModules(path) {
+		if module.namespace != namespace || !module.is_module {
+			continue
+		}
+		for _, entry := range definitions[module.path] {
+			if name, ok := module.memberName(entry.name); ok && name == member {
+				return entry, true
+			}
+		}
+	}
+	return isDefined{}, false
+}
+
+func (lsp *Lsp) signatureOf(path string, name string, is_include bool) (callSignature, bool) {
+	item_type := itemTypeFunction
+	if is_include {
+		item_type = itemTypeMixin
+	}
+
+	if namespace, member, found := strings.Cut(name, "."); found {
+		statement, ok := lsp.namespaceModule(path, namespace)
+		if !ok {
+			return callSignature{}, false
+		}
+		if builtin, ok := lookupBuiltinModule(statement.url); ok {
+			builtin_member, ok := builtin.member(member, item_type)
+			if !ok {
+				return callSignature{}, false
+			}
+			return callSignature{
+				label:         builtin_member.signature(namespace),
+				parameters:    builtin_member.Parameters,
+				documentation: builtin_member.Description,
+			}, true
+		}
+		entry, ok := lsp.memberDefinition(path, namespace, member, item_type)
+		if !ok {
+			return callSignature{}, false
+		}
+		parameters := splitParameters(entry.body)
+		return callSignature{
+			label:         name + "(" + strings.Join(parameters, ", ") + ")",
+			parameters:    parameters,
+			documentation: sassDocDescription(entry.doc),
+			doc:           entry.doc,
+		}, true
+	}
+
+	for _, definition := range lsp.definitionsOfType(name, item_type) {
+		parameters := splitParameters(definition.is_defined.body)
+		return callSignature{
+			label:         name + "(" + strings.Join(parameters, ", ") + ")",
+			parameters:    parameters,
+			documentation: strings.TrimSpace(sassDocDescription(definition.is_defined.doc) + "\n\n" + definition.path),
+			doc:           definition.is_defined.doc,
+		}, true
+	}
+	if global, ok := lookupBuiltinGlobal(name); ok && !is_include {
+		return callSignature{label: global.signature(), parameters: global.parameters, documentation: global.description}, true
+	}
+	return callSignature{}, false
+}
+
+func (lsp *Lsp) GetSignatureHelp(path string, position protocol.Position) *protocol.SignatureHelp {
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return nil
+	}
+	name, argument, is_include, ok := openCall(*input, offsetOfPosition(*input, position))
+	if !ok {
+		return nil
+	}
+	signature, ok := lsp.signatureOf(path, name, is_include)
+	if !ok {
+		return nil
+	}
+
+	parameters := []protocol.ParameterInformation{}
+	for _, parameter := range signature.parameters {
+		information := protocol.ParameterInformation{Label: parameter}
+		name, _, _ := strings.Cut(parameter, ":")
+		if documented, ok := signature.doc.parameter(strings.TrimSpace(name)); ok {
+			information.Documentation = documented.description
+		}
+		parameters = append(parameters, information)
+	}
+	// everything past the end goes to the rest argument
+	active := argument
+	if active >= len(parameters) && len(parameters) > 0 {
+		active = len(parameters) - 1
+	}
+	return &protocol.SignatureHelp{
+		Signatures: []protocol.SignatureInformation{{
+			Label:           signature.label,
+			Documentation:   signature.documentation,
+			Parameters:      parameters,
+			ActiveParameter: uint32(active),
+		}},
+		ActiveParameter: uint32(active),
+	}
+}
@@ -0,0 +1,261 @@
+package lsp
+
+import (
+	"fmt"
+	"html/template"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// what the style guide calls the members that have no @group
+const styleGuideDefaultGroup = "general"
+
+// values that are shown with a swatch, named colors are only the basic ones
+var colorValueRegex = regexp.MustCompile(`^(?i:#[0-9a-f]{3,8}|(?:rgb|rgba|hsl|hsla|hwb)\([\d\s.,%/+-]+\)|black|silver|gray|white|maroon|red|purple|fuchsia|green|lime|olive|yellow|navy|blue|teal|aqua|orange|rebeccapurple|transparent)$`)
+
+// the order of the kinds in a group
+var styleGuideKinds = []string{itemTypeVariable, itemTypeMixin, itemTypeFunction, itemTypePlaceholder}
+
+type StyleGuideParameter struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type StyleGuideExample struct {
+	Language    string `json:"language"`
+	Description string `json:"description,omitempty"`
+	Code        string `json:"code"`
+}
+
+// one public member of the workspace
+type StyleGuideItem struct {
+	Kind        string                `json:"kind"`
+	Name        string                `json:"name"`
+	Signature   string                `json:"signature"`
+	Description string                `json:"description,omitempty"`
+	Parameters  []StyleGuideParameter `json:"parameters,omitempty"`
+	ReturnType  string                `json:"returnType,omitempty"`
+	Returns     string                `json:"returns,omitempty"`
+	Examples    []StyleGuideExample   `json:"examples,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Deprecation string                `json:"deprecation,omitempty"`
+	See         []string              `json:"see,omitempty"`
+	// only set for variables, the value as far as it can be resolved
+	Value string `json:"value,omitempty"`
+	// only set for variables that are a color
+	Color string `json:"color,omitempty"`
+	// relative to the root of the workspace
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Source string `json:"source"`
+}
+
+type StyleGuideGroup struct {
+	Name  string           `json:"name"`
+	Items []StyleGuideItem `json:"items"`
+}
+
+// StyleGuide collects the public mixins, functions, variables and
+// placeholders of the workspace by their SassDoc @group. Source links are
+// source_url followed by the path, or just the path without one.
+func (lsp *Lsp) StyleGuide(source_url string) []StyleGuideGroup {
+	groups := map[string][]StyleGuideItem{}
+	for _, item_type := range styleGuideKinds {
+		definitions := lsp.definitionMap(item_type)
+		if item_type == itemTypePlaceholder {
+			definitions = lsp.Placeholders
+		}
+		for _, path := range sortedPaths(definitions) {
+			seen := map[string]bool{}
+			for _, entry := range definitions[path] {
+				if seen[entry.name] || isPrivateMember(entry.name) || entry.doc.isPrivate() {
+					continue
+				}
+				if item_type == itemTypeVariable && !lsp.isTopLevelVariable(path, entry) {
+					continue
+				}
+				seen[entry.name] = true
+				item := lsp.styleGuideItem(path, item_type, entry, source_url)
+				group := styleGuideDefaultGroup
+				if entry.doc != nil && entry.doc.group != "" {
+					group = entry.doc.group
+				}
+				groups[group] = append(groups[group], item)
+			}
+		}
+	}
+
+	names := []string{}
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	style_guide := []StyleGuideGroup{}
+	for _, name := range names {
+		style_guide = append(style_guide, StyleGuideGroup{Name: name, Items: groups[name]})
+	}
+	return style_guide
+}
+
+func (lsp *Lsp) styleGuideItem(path string, item_type string, entry isDefined, source_url string) StyleGuideItem {
+	relative := lsp.relativePath(path)
+	line := int(entry.start_position.Row) + 1
+	item := StyleGuideItem{
+		Kind:      strings.TrimLeft(item_type, "@$%"),
+		Name:      entry.name,
+		Signature: definitionSignature(item_type, entry),
+		Path:      relative,
+		Line:      line,
+		Source:    fmt.Sprintf("%s%s#L%d", source_url, relative, line),
+	}
+	doc := entry.doc
+	if doc == nil {
+		doc = &sassDoc{}
+	}
+	item.Description = doc.description
+	item.ReturnType = doc.return_type
+	item.Returns = doc.returns
+	item.Deprecated = doc.deprecated
+	item.Deprecation = doc.deprecation
+	item.See = doc.see
+	for _, example := range doc.examples {
+		item.Examples = append(item.Examples, StyleGuideExample{Language: example.language, Description: example.description, Code: example.code})
+	}
+	if item_type == itemTypeMixin || item_type == itemTypeFunction {
+		for _, parameter := range definitionParameters(entry) {
+			name, value, _ := strings.Cut(parameter, ":")
+			documented, _ := doc.parameter(strings.TrimSpace(name))
+			if value == "" {
+				value = documented.default_value
+			}
+			item.Parameters = append(item.Parameters, StyleGuideParameter{
+				Name:        strings.TrimSpace(name),
+				Type:        documented.value_type,
+				Default:     strings.TrimSpace(value),
+				Description: documented.description,
+			})
+		}
+	}
+	if item_type == itemTypeVariable {
+		item.Value = lsp.resolveValue(path, declarationValue(entry.body), 0)
+		if colorValueRegex.MatchString(item.Value) {
+			item.Color = item.Value
+		}
+	}
+	return item
+}
+
+// StyleGuideMarkdown renders the style guide as one markdown document
+func StyleGuideMarkdown(groups []StyleGuideGroup) string {
+	var sb strings.Builder
+	sb.WriteString("# Style guide\n")
+	for _, group := range groups {
+		sb.WriteString("\n## " + group.Name + "\n")
+		for _, item := range group.Items {
+			sb.WriteString("\n### " + item.Kind + " " + item.Name + "\n\n")
+			sb.WriteString("```scss\n" + item.Signature + "\n```\n")
+			if item.Deprecated {
+				sb.WriteString("\n**Deprecated**")
+				if item.Deprecation != "" {
+					sb.WriteString(": " + item.Deprecation)
+				}
+				sb.WriteString("\n")
+			}
+			if item.Description != "" {
+				sb.WriteString("\n" + item.Description + "\n")
+			}
+			if item.Value != "" {
+				sb.WriteString("\nValue: `" + item.Value + "`\n")
+			}
+			if len(item.Parameters) > 0 {
+				sb.WriteString("\n| Name | Type | Default | Description |\n| --- | --- | --- | --- |\n")
+				for _, parameter := range item.Parameters {
+					sb.WriteString(fmt.Sprintf("| `%s` | %s | %s | %s |\n", parameter.Name, parameter.Type, markdownCode(parameter.Default), parameter.Description))
+				}
+			}
+			if item.ReturnType != "" || item.Returns != "" {
+				sb.WriteString("\nReturns: " + strings.TrimSpace(markdownCode(item.ReturnType)+" "+item.Returns) + "\n")
+			}
+			for _, example := range item.Examples {
+				sb.WriteString("\nExample")
+				if example.Description != "" {
+					sb.WriteString(": " + example.Description)
+				}
+				sb.WriteString("\n\n```" + example.Language + "\n" + example.Code + "\n```\n")
+			}
+			if len(item.See) > 0 {
+				sb.WriteString("\nSee: " + strings.Join(item.See, ", ") + "\n")
+			}
+			sb.WriteString(fmt.Sprintf("\n[%s:%d](%s)\n", item.Path, item.Line, item.Source))
+		}
+	}
+	return sb.String()
+}
+
+func markdownCode(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "`" + text + "`"
+}
+
+var styleGuideTemplate = template.Must(template.New("style guide").Funcs(template.FuncMap{
+	// the colors only ever come from colorValueRegex
+	"swatch": func(color string) template.CSS {
+		if !colorValueRegex.MatchString(color) {
+			return ""
+		}
+		return template.CSS("background: " + color)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Style guide</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 0 auto; padding: 1em; }
+pre { background: #f5f5f5; padding: 0.5em; overflow-x: auto; }
+nav a { margin-right: 1em; }
+.deprecated { color: #b00; }
+.swatch { display: inline-block; width: 1em; height: 1em; border: 1px solid #ccc; vertical-align: middle; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ddd; padding: 0.2em 0.5em; text-align: left; }
+</style>
+</head>
+<body>
+<h1>Style guide</h1>
+<nav>{{range .}}<a href="#{{.Name}}">{{.Name}}</a>{{end}}</nav>
+{{range .}}<section id="{{.Name}}">
+<h2>{{.Name}}</h2>
+{{range .Items}}<article>
+<h3>{{.Kind}} {{.Name}}</h3>
+<pre><code>{{.Signature}}</code></pre>
+{{if .Deprecated}}<p class="deprecated">Deprecated{{if .Deprecation}}: {{.Deprecation}}{{end}}</p>
+{{end}}{{if .Description}}<p>{{.Description}}</p>
+{{end}}{{if .Value}}<p>{{if .Color}}<span class="swatch" style="{{swatch .Color}}"></span> {{end}}<code>{{.Value}}</code></p>
+{{end}}{{if .Parameters}}<table>
+<tr><th>Name</th><th>Type</th><th>Default</th><th>Description</th></tr>
+{{range .Parameters}}<tr><td><code>{{.Name}}</code></td><td>{{.Type}}</td><td>{{if .Default}}<code>{{.Default}}</code>{{end}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>
+{{end}}{{if or .ReturnType .Returns}}<p>Returns: {{if .ReturnType}}<code>{{.ReturnType}}</code> {{end}}{{.Returns}}</p>
+{{end}}{{range .Examples}}<p>Example{{if .Description}}: {{.Description}}{{end}}</p>
+<pre><code>{{.Code}}</code></pre>
+{{end}}{{if .See}}<p>See: {{range $idx, $see := .See}}{{if $idx}}, {{end}}{{$see}}{{end}}</p>
+{{end}}<p><a href="{{.Source}}">{{.Path}}:{{.Line}}</a></p>
+</article>
+{{end}}</section>
+{{end}}</body>
+</html>
+`))
+
+// StyleGuideHTML renders the style guide as a single page
+func StyleGuideHTML(groups []StyleGuideGroup) (string, error) {
+	var sb strings.Builder
+	if err := styleGuideTemplate.Execute(&sb, groups); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStyleGuide(t *testing.T) {
+	lsp := lspWithSources(t, map[string]string{
+		"/virtual/_effects.scss": sassDocLibrary,
+		"/virtual/_colors.scss":  "/// The brand\n/// @group colors\n$brand: #f00;\n/// @group colors\n$primary: $brand !default;\n$-hidden: 1;\n%card { padding: 1px; }\n",
+	})
+	lsp.RootPath = "/virtual"
+
+	groups := lsp.StyleGuide("https://example.com/")
+	names := []string{}
+	for _, group := range groups {
+		for _, item := range group.Items {
+			names = append(names, group.Name+" "+item.Kind+" "+item.Name)
+		}
+	}
+	expected := "colors variable $brand, colors variable $primary, effects mixin shadow, general variable $old-gap, general mixin old-shadow, general placeholder %card"
+	if strings.Join(names, ", ") != expected {
+		t.Fatalf("unexpected items %v", names)
+	}
+
+	primary := groups[0].Items[1]
+	if primary.Value != "#f00" || primary.Color != "#f00" || primary.Source != "https://example.com/_colors.scss#L5" {
+		t.Fatalf("unexpected item %+v", primary)
+	}
+	shadow := groups[1].Items[0]
+	if len(shadow.Parameters) != 2 || shadow.Parameters[1] != (StyleGuideParameter{Name: "$color", Type: "Color", Default: "black", Description: "the color of the shadow"}) {
+		t.Fatalf("unexpected parameters %+v", shadow.Parameters)
+	}
+
+	markdown := StyleGuideMarkdown(groups)
+	for _, expected := range []string{
+		"## effects\n\n### mixin shadow\n\n```scss\n@mixin shadow($size: 1px, $color: black)\n```",
+		"| `$size` | Length | `1px` | how far it reaches |",
+		"**Deprecated**: use elevate instead",
+		"[_colors.scss:3](https://example.com/_colors.scss#L3)",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Fatalf("expected %q in\n%s", expected, markdown)
+		}
+	}
+
+	html, err := StyleGuideHTML(groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, `<span class="swatch" style="background: #f00"></span>`) {
+		t.Fatalf("expected a swatch in\n%s", html)
+	}
+}
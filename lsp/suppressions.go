@@ -0,0 +1,245 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+const diagnosticUnusedSuppression = "unused-suppression"
+
+// scss-lsp-disable-next-line rule, other-rule -- why
+var suppressionRegex = regexp.MustCompile(`^(?://|/\*)\s*scss-lsp-(disable-next-line|disable-line|disable|enable)\b(.*?)(?:\*/)?$`)
+
+// a comment that hides diagnostics, no rules hides all of them
+type suppression struct {
+	kind  string
+	rules []string
+	// the comment itself
+	start_position sitter.Point
+	end_position   sitter.Point
+	// what it covers, for blocks up to the enable comment or the end of the
+	// file
+	from sitter.Point
+	to   sitter.Point
+	used bool
+}
+
+func (s *suppression) covers(diagnostic protocol.Diagnostic) bool {
+	code := diagnosticCode(diagnostic)
+	if code == diagnosticUnusedSuppression {
+		return false
+	}
+	matches := len(s.rules) == 0
+	for _, rule := range s.rules {
+		matches = matches || rule == code
+	}
+	if !matches {
+		return false
+	}
+	start := sitter.Point{Row: diagnostic.Range.Start.Line, Column: diagnostic.Range.Start.Character}
+	return comparePoints(s.from, start) <= 0 && comparePoints(start, s.to) < 0
+}
+
+func parseSuppressionRules(text string) []string {
+	text, _, _ = strings.Cut(text, "--")
+	rules := []string{}
+	for _, rule := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// findSuppressions reads the suppression comments of a file
+func (lsp *Lsp) findSuppressions(path string) []*suppression {
+	suppressions := []*suppression{}
+	tree, ok := lsp.Trees[path]
+	if !ok {
+		return suppressions
+	}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return suppressions
+	}
+	end_of_file := tree.RootNode().EndPoint()
+	end_of_file.Row++
+	// the disable comments that have not been enabled again
+	open := []*suppression{}
+	walkNamed(tree.RootNode(), func(node *sitter.Node) {
+		if node.Type() != "comment" && node.Type() != "single_line_comment" {
+			return
+		}
+		match := suppressionRegex.FindStringSubmatch(strings.TrimSpace(node.Content(*input)))
+		if match == nil {
+			return
+		}
+		entry := &suppression{
+			kind:           match[1],
+			rules:          parseSuppressionRules(match[2]),
+			start_position: node.StartPoint(),
+			end_position:   node.EndPoint(),
+		}
+		switch entry.kind {
+		case "disable-line":
+			entry.from = sitter.Point{Row: node.StartPoint().Row}
+			entry.to = sitter.Point{Row: node.StartPoint().Row + 1}
+		case "disable-next-line":
+			entry.from = sitter.Point{Row: node.EndPoint().Row + 1}
+			entry.to = sitter.Point{Row: node.EndPoint().Row + 2}
+		case "disable":
+			entry.from = node.EndPoint()
+			entry.to = end_of_file
+			open = append(open, entry)
+		case "enable":
+			still_open := []*suppression{}
+			for _, disable := range open {
+				if len(entry.rules) == 0 || strings.Join(disable.rules, ",") == strings.Join(entry.rules, ",") {
+					disable.to = node.StartPoint()
+					continue
+				}
+				still_open = append(still_open, disable)
+			}
+			open = still_open
+			return
+		}
+		suppressions = append(suppressions, entry)
+	})
+	return suppressions
+}
+
+// withoutSuppressed drops the diagnostics the comments of the file hide and
+// reports the comments that hide nothing
+func (lsp *Lsp) withoutSuppressed(path string, diagnostics []protocol.Diagnostic) []protocol.Diagnostic {
+	suppressions := lsp.findSuppressions(path)
+	if len(suppressions) == 0 {
+		return diagnostics
+	}
+	remaining := []protocol.Diagnostic{}
+	for _, diagnostic := range diagnostics {
+		suppressed := false
+		for _, entry := range suppressions {
+			if entry.covers(diagnostic) {
+				entry.used = true
+				suppressed = true
+			}
+		}
+		if !suppressed {
+			remaining = append(remaining, diagnostic)
+		}
+	}
+	unused := []protocol.Diagnostic{}
+	for _, entry := range suppressions {
+		// a rule that does not exist can not hide anything, the comment is
+		// only unused for the rules that do
+		known := []string{}
+		for _, rule := range entry.rules {
+			if _, ok := lsp.lookupRule(rule); ok {
+				known = append(known, rule)
+				continue
+			}
+			unused = append(unused, protocol.Diagnostic{
+				Range:   rangeFromPoints(entry.start_position, entry.end_position),
+				Message: "unknown rule " + rule,
+			})
+		}
+		// hiding a rule that is off is not a mistake
+		is_off := len(known) > 0
+		for _, rule := range known {
+			is_off = is_off && !lsp.isRuleEnabled(path, rule)
+		}
+		if entry.used || is_off || len(entry.rules) > 0 && len(known) == 0 {
+			continue
+		}
+		message := "unused scss-lsp-" + entry.kind + ", there is nothing to hide"
+		if len(known) > 0 {
+			message = fmt.Sprintf("unused scss-lsp-%s, there is no %s to hide", entry.kind, strings.Join(known, " or "))
+		}
+		unused = append(unused, protocol.Diagnostic{
+			Range:   rangeFromPoints(entry.start_position, entry.end_position),
+			Message: message,
+			Tags:    []protocol.DiagnosticTag{protocol.DiagnosticTagUnnecessary},
+		})
+	}
+	if rule, ok := lsp.lookupRule(diagnosticUnusedSuppression); ok {
+		remaining = append(remaining, lsp.finishDiagnostics(path, rule, unused)...)
+	}
+	return remaining
+}
+
+// suppressionQuickFixes hide a diagnostic with a comment on the line above
+// it, or in the comment that is already there
+func suppressionQuickFixes(lsp *Lsp, path string, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	code := diagnosticCode(diagnostic)
+	if code == "" || code == diagnosticUnusedSuppression || diagnostic.Source != "SCSS-LSP" {
+		return actions
+	}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return actions
+	}
+	lines := strings.Split(string(*input), "\n")
+	row := diagnostic.Range.Start.Line
+	if int(row) >= len(lines) {
+		return actions
+	}
+
+	title := fmt.Sprintf("Disable %s for this line", code)
+	for _, entry := range lsp.findSuppressions(path) {
+		if entry.kind == "disable-next-line" && entry.end_position.Row+1 == row && entry.start_position.Row == entry.end_position.Row && len(entry.rules) > 0 {
+			// the comment ends with the rules or with a -- reason
+			text := lines[entry.start_position.Row][entry.start_position.Column:entry.end_position.Column]
+			offset := len(strings.TrimRight(text, " "))
+			if before, _, found := strings.Cut(text, "--"); found {
+				offset = len(strings.TrimRight(before, " "))
+			}
+			position := protocol.Position{Line: entry.start_position.Row, Character: entry.start_position.Column + uint32(offset)}
+			return append(actions, quickFix(title, diagnostic, path, []protocol.TextEdit{{
+				Range:   protocol.Range{Start: position, End: position},
+				NewText: ", " + code,
+			}}))
+		}
+	}
+	position := protocol.Position{Line: row}
+	actions = append(actions, quickFix(title, diagnostic, path, []protocol.TextEdit{{
+		Range:   protocol.Range{Start: position, End: position},
+		NewText: lineIndent(*input, sitter.Point{Row: row}) + "// scss-lsp-disable-next-line " + code + "\n",
+	}}))
+	top := protocol.Position{}
+	actions = append(actions, quickFix(fmt.Sprintf("Disable %s for the file", code), diagnostic, path, []protocol.TextEdit{{
+		Range:   protocol.Range{Start: top, End: top},
+		NewText: "/* scss-lsp-disable " + code + " */\n",
+	}}))
+	return actions
+}
+
+// unusedSuppressionQuickFixes remove the comment, with its line when there
+// is nothing else on it
+func unusedSuppressionQuickFixes(lsp *Lsp, path string, diagnostic protocol.Diagnostic) []protocol.CodeAction {
+	if len(diagnostic.Tags) == 0 {
+		// an unknown rule, the comment may still hide the others
+		return []protocol.CodeAction{}
+	}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return []protocol.CodeAction{}
+	}
+	lines := strings.Split(string(*input), "\n")
+	start, end := diagnostic.Range.Start, diagnostic.Range.End
+	if int(end.Line) >= len(lines) || int(start.Character) > len(lines[start.Line]) || int(end.Character) > len(lines[end.Line]) {
+		return []protocol.CodeAction{}
+	}
+	if strings.TrimSpace(lines[start.Line][:start.Character]) == "" && strings.TrimSpace(lines[end.Line][end.Character:]) == "" {
+		start = protocol.Position{Line: start.Line}
+		end = protocol.Position{Line: end.Line + 1}
+	} else {
+		before := lines[start.Line][:start.Character]
+		start.Character = uint32(len(strings.TrimRight(before, " \t")))
+	}
+	return []protocol.CodeAction{quickFix("Remove the unused suppression", diagnostic, path, []protocol.TextEdit{{
+		Range: protocol.Range{Start: start, End: end},
+	}})}
+}
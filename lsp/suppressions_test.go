@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestSuppressions(t *testing.T) {
+	path := "/virtual/partial.scss"
+	lsp := lspWithSources(t, map[string]string{
+		path: `// scss-lsp-disable-next-line undefined
+.a { @include injected; }
+.b { @include other; } // scss-lsp-disable-line
+/* scss-lsp-disable undefined */
+.c { @include x; width: darken(red, 1%); }
+/* scss-lsp-enable undefined */
+.d { @include y; }
+// scss-lsp-disable-next-line deprecated-global-function -- from the build
+.e { @include z; }
+// scss-lsp-disable-next-line undefind
+.f { @include w; }
+`,
+	})
+
+	diagnostics := lsp.getDiagnostics(path)
+	found := []string{}
+	for _, diagnostic := range diagnostics {
+		found = append(found, fmt.Sprintf("%s@%d", diagnosticCode(diagnostic), diagnostic.Range.Start.Line))
+	}
+	expected := "undefined@6 undefined@8 undefined@10 deprecated-global-function@4 unused-suppression@7 unused-suppression@9"
+	if strings.Join(found, " ") != expected {
+		t.Fatalf("unexpected diagnostics %v", found)
+	}
+	if diagnostics[4].Message != "unused scss-lsp-disable-next-line, there is no deprecated-global-function to hide" {
+		t.Fatalf("unexpected message %q", diagnostics[4].Message)
+	}
+	// a typo hides nothing and is not just left alone like a rule that is off
+	if diagnostics[5].Message != "unknown rule undefind" {
+		t.Fatalf("unexpected message %q", diagnostics[5].Message)
+	}
+
+	actions := lsp.GetCodeActions(path, protocol.CodeActionContext{Diagnostics: diagnostics[0:1]})
+	if titles := strings.Join(actionTitles(actions), "|"); !strings.HasSuffix(titles, "Disable undefined for this line|Disable undefined for the file") {
+		t.Fatalf("unexpected actions %q", titles)
+	}
+	edit := actions[len(actions)-2].Edit.Changes[uri.File(path)][0]
+	if edit.NewText != "// scss-lsp-disable-next-line undefined\n" || edit.Range.Start != (protocol.Position{Line: 6}) {
+		t.Fatalf("unexpected edit %+v", edit)
+	}
+
+	// the comment above already hides something else
+	actions = lsp.GetCodeActions(path, protocol.CodeActionContext{Diagnostics: diagnostics[1:2]})
+	edit = actions[len(actions)-1].Edit.Changes[uri.File(path)][0]
+	if edit.NewText != ", undefined" || edit.Range.Start != (protocol.Position{Line: 7, Character: 56}) {
+		t.Fatalf("unexpected edit %+v", edit)
+	}
+
+	actions = lsp.GetCodeActions(path, protocol.CodeActionContext{Diagnostics: diagnostics[4:5]})
+	if len(actions) != 1 || actions[0].Title != "Remove the unused suppression" {
+		t.Fatalf("unexpected actions %q", actionTitles(actions))
+	}
+	edit = actions[0].Edit.Changes[uri.File(path)][0]
+	if edit.Range != (protocol.Range{Start: protocol.Position{Line: 7}, End: protocol.Position{Line: 8}}) {
+		t.Fatalf("unexpected edit %+v", edit)
+	}
+	// the other rules of the comment may still hide something
+	if actions := lsp.GetCodeActions(path, protocol.CodeActionContext{Diagnostics: diagnostics[5:]}); len(actions) != 0 {
+		t.Fatalf("unexpected actions %q", actionTitles(actions))
+	}
+}
@@ -0,0 +1,189 @@
+package lsp
+
+import (
+	"regexp"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// type hierarchy is from LSP 3.17, go.lsp.dev/protocol doesnt have it
+const (
+	methodTextDocumentPrepareTypeHierarchy = "textDocument/prepareTypeHierarchy"
+	methodTypeHierarchySupertypes          = "typeHierarchy/supertypes"
+	methodTypeHierarchySubtypes            = "typeHierarchy/subtypes"
+)
+
+type typeHierarchyItem struct {
+	Name           string               `json:"name"`
+	Kind           protocol.SymbolKind  `json:"kind"`
+	Detail         string               `json:"detail,omitempty"`
+	URI            protocol.DocumentURI `json:"uri"`
+	Range          protocol.Range       `json:"range"`
+	SelectionRange protocol.Range       `json:"selectionRange"`
+	Data           interface{}          `json:"data,omitempty"`
+}
+
+type typeHierarchyPrepareParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Position     protocol.Position               `json:"position"`
+}
+
+type typeHierarchyItemParams struct {
+	Item typeHierarchyItem `json:"item"`
+}
+
+// selectors and placeholders are the types, @extend makes the extended
+// selector a supertype of the selector the @extend is in
+func typeHierarchyItemFor(path string, entry *isDefined, item_type string) typeHierarchyItem {
+	kind := protocol.SymbolKindClass
+	switch item_type {
+	case itemTypePlaceholder:
+		kind = protocol.SymbolKindInterface
+	case itemTypeMixin:
+		kind = protocol.SymbolKindMethod
+	}
+	entry_range := rangeFromPoints(entry.start_position, entry.end_position)
+	return typeHierarchyItem{
+		Name:           entry.name,
+		Kind:           kind,
+		Detail:         item_type,
+		URI:            uri.File(path),
+		Range:          entry_range,
+		SelectionRange: entry_range,
+		Data:           item_type,
+	}
+}
+
+// ruleSetItem is the item of the rule set that starts where entry does, the
+// range is the whole rule set and the selection its selectors
+func (lsp *Lsp) ruleSetItem(path string, entry isDefined) typeHierarchyItem {
+	item := typeHierarchyItemFor(path, &entry, itemTypeRuleSet)
+	tree := lsp.Trees[path]
+	if tree == nil {
+		return item
+	}
+	for node := tree.RootNode().NamedDescendantForPointRange(entry.start_position, entry.start_position); node != nil; node = node.Parent() {
+		if node.Type() != "rule_set" || node.StartPoint() != entry.start_position {
+			continue
+		}
+		item.Range = rangeFromPoints(node.StartPoint(), node.EndPoint())
+		item.SelectionRange = item.Range
+		if selectors := node.NamedChild(0); selectors != nil && selectors.Type() == "selectors" {
+			item.SelectionRange = rangeFromPoints(selectors.StartPoint(), selectors.EndPoint())
+		}
+		break
+	}
+	return item
+}
+
+// a selector that starts with an element name, not a class or placeholder
+var elementSelectorRegex = regexp.MustCompile(`^[\w-]`)
+
+// selectorMatches is true when the selector of a rule set, with the nesting
+// resolved like ".card .btn:hover, .link", has target as a part of one of
+// its compounds, that is every rule set an @extend of target changes
+func selectorMatches(selector string, target string) bool {
+	if selector == target {
+		return true
+	}
+	before := ""
+	if elementSelectorRegex.MatchString(target) {
+		// an element, not the end of a class name
+		before = `(?:^|[^\w.#%-])`
+	}
+	return regexp.MustCompile(before + regexp.QuoteMeta(target) + `(?:$|[^\w-])`).MatchString(selector)
+}
+
+// typesNamed finds the placeholders with the name and the rule sets whose
+// selector has it, an @extend .btn changes .btn, .card .btn and .btn:hover
+func (lsp *Lsp) typesNamed(name string) []typeHierarchyItem {
+	items := []typeHierarchyItem{}
+	for _, path := range sortedPaths(lsp.Trees) {
+		for _, entry := range lsp.Placeholders[path] {
+			if entry.name == name {
+				items = append(items, typeHierarchyItemFor(path, &entry, itemTypePlaceholder))
+			}
+		}
+		for _, selector := range lsp.SelectorEntries[path] {
+			if selectorMatches(selector.name, name) {
+				entry := isDefined{name: selector.name, body: selector.name, start_position: selector.start_position, end_position: selector.end_position}
+				items = append(items, lsp.ruleSetItem(path, entry))
+			}
+		}
+	}
+	return items
+}
+
+func (lsp *Lsp) PrepareTypeHierarchy(path string, position sitter.Point) []typeHierarchyItem {
+	items := []typeHierarchyItem{}
+	if target, ok := lsp.symbolAtPosition(path, position); ok && target.item_type == itemTypePlaceholder {
+		return lsp.typesNamed(target.name)
+	}
+	// @extend .class
+	for _, entry := range lsp.Extends[path] {
+		if isPointInRange(position, entry.start_position, entry.end_position) {
+			return lsp.typesNamed(entry.name)
+		}
+	}
+
+	tree := lsp.Trees[path]
+	if tree == nil {
+		return items
+	}
+	input, err := lsp.bytesFromFilePath(path)
+	if err != nil {
+		return items
+	}
+	// otherwise the rule set whose selectors are under the cursor
+	for node := tree.RootNode().NamedDescendantForPointRange(position, position); node != nil; node = node.Parent() {
+		if node.Type() != "selectors" || parentType(node) != "rule_set" {
+			continue
+		}
+		rule_set := lsp.Parser.parseCaller(node.Parent(), input)
+		items = append(items, lsp.ruleSetItem(path, *rule_set))
+		break
+	}
+	return items
+}
+
+func isSameItem(item typeHierarchyItem, path string, entry *isDefined) bool {
+	return item.URI.Filename() == path &&
+		item.Name == entry.name &&
+		item.Range.Start.Line == entry.start_position.Row &&
+		item.Range.Start.Character == entry.start_position.Column
+}
+
+func (lsp *Lsp) GetSupertypes(item typeHierarchyItem) []typeHierarchyItem {
+	items := []typeHierarchyItem{}
+	path := item.URI.Filename()
+	for _, entry := range lsp.Extends[path] {
+		if entry.caller == nil || !isSameItem(item, path, entry.caller) {
+			continue
+		}
+		items = append(items, lsp.typesNamed(entry.name)...)
+	}
+	return items
+}
+
+func (lsp *Lsp) GetSubtypes(item typeHierarchyItem) []typeHierarchyItem {
+	items := []typeHierarchyItem{}
+	for _, path := range sortedPaths(lsp.Extends) {
+		for _, entry := range lsp.Extends[path] {
+			if entry.caller == nil {
+				continue
+			}
+			// a rule set is a supertype of the @extend of any part of it
+			if entry.name != item.Name && (item.Detail != itemTypeRuleSet || !selectorMatches(item.Name, entry.name)) {
+				continue
+			}
+			if entry.caller.item_type == itemTypeRuleSet {
+				items = append(items, lsp.ruleSetItem(path, *entry.caller))
+				continue
+			}
+			items = append(items, typeHierarchyItemFor(path, entry.caller, entry.caller.item_type))
+		}
+	}
+	return items
+}
@@ -0,0 +1,116 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+const typeHierarchySource = `%base {
+  color: red;
+}
+%button {
+  @extend %base;
+}
+.btn {
+  @extend %button;
+}
+.btn-primary {
+  @extend .btn;
+}
+`
+
+func TestPlaceholderDefinitionAndReferences(t *testing.T) {
+	path := "/virtual/extends.scss"
+	lsp := lspWithSources(t, map[string]string{path: typeHierarchySource})
+
+	// on %button in `@extend %button;`
+	definitions := lsp.GetDefinitionInfo(path, sitter.Point{Row: 7, Column: 12})
+	if definitions == nil || len(*definitions) != 1 || (*definitions)[0].Range.Start.Line != 3 {
+		t.Fatalf("expected the %%button placeholder, got %+v", definitions)
+	}
+	references := lsp.getReferences(path, sitter.Point{Row: 3, Column: 2})
+	if len(references) != 1 || references[0].Range.Start.Line != 7 {
+		t.Fatalf("expected the @extend in .btn, got %+v", references)
+	}
+}
+
+func TestTypeHierarchy(t *testing.T) {
+	path := "/virtual/extends.scss"
+	lsp := lspWithSources(t, map[string]string{path: typeHierarchySource})
+
+	items := lsp.PrepareTypeHierarchy(path, sitter.Point{Row: 3, Column: 2})
+	if len(items) != 1 || items[0].Name != "%button" {
+		t.Fatalf("expected %%button, got %+v", items)
+	}
+
+	supertypes := lsp.GetSupertypes(items[0])
+	if len(supertypes) != 1 || supertypes[0].Name != "%base" {
+		t.Fatalf("expected %%base, got %+v", supertypes)
+	}
+	subtypes := lsp.GetSubtypes(items[0])
+	if len(subtypes) != 1 || subtypes[0].Name != ".btn" {
+		t.Fatalf("expected .btn, got %+v", subtypes)
+	}
+
+	// the item goes through the client and comes back
+	data, err := json.Marshal(subtypes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var item typeHierarchyItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		t.Fatal(err)
+	}
+	subtypes = lsp.GetSubtypes(item)
+	if len(subtypes) != 1 || subtypes[0].Name != ".btn-primary" {
+		t.Fatalf("expected .btn-primary, got %+v", subtypes)
+	}
+	supertypes = lsp.GetSupertypes(item)
+	if len(supertypes) != 1 || supertypes[0].Name != "%button" {
+		t.Fatalf("expected %%button, got %+v", supertypes)
+	}
+}
+
+func TestTypeHierarchyNestedSelectors(t *testing.T) {
+	path := "/virtual/nested.scss"
+	lsp := lspWithSources(t, map[string]string{path: `.card {
+  .btn { color: red; }
+}
+.btn:hover, .link { color: blue; }
+.btn-primary { color: green; }
+a.btn { color: black; }
+.submit {
+  @extend .btn;
+}
+`})
+
+	// the selectors are matched with the nesting resolved, any part of them
+	items := lsp.PrepareTypeHierarchy(path, sitter.Point{Row: 7, Column: 12})
+	names := []string{}
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	if strings.Join(names, "|") != ".card .btn|.btn:hover, .link|a.btn" {
+		t.Fatalf("unexpected items %q", names)
+	}
+	expected := protocol.Range{Start: protocol.Position{Line: 1, Character: 2}, End: protocol.Position{Line: 1, Character: 22}}
+	if items[0].Range != expected {
+		t.Fatalf("expected the whole rule set, got %+v", items[0].Range)
+	}
+	expected.End.Character = 6
+	if items[0].SelectionRange != expected {
+		t.Fatalf("expected the selector, got %+v", items[0].SelectionRange)
+	}
+
+	subtypes := lsp.GetSubtypes(items[1])
+	if len(subtypes) != 1 || subtypes[0].Name != ".submit" || subtypes[0].Range.End.Line != 8 {
+		t.Fatalf("expected .submit, got %+v", subtypes)
+	}
+	if supertypes := lsp.GetSupertypes(subtypes[0]); len(supertypes) != 3 {
+		t.Fatalf("unexpected supertypes %+v", supertypes)
+	}
+}
@@ -0,0 +1,298 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.lsp.dev/protocol"
+)
+
+// the code of the diagnostic for definitions nothing in the workspace uses
+const diagnosticUnusedSymbol = "unused-symbol"
+
+var (
+	// the values of animation properties, and of variables since those can
+	// hold the name of an animation too
+	animationValueRegex = regexp.MustCompile(`(?:\banimation(?:-name)?|\$[\w-]+)\s*:\s*([^;{}]*)`)
+	identifierRegex     = regexp.MustCompile(`[A-Za-z_-][\w-]*`)
+	// the ( of a function call or an @include, namespaced ones too
+	callOpenRegex = regexp.MustCompile(`[\w-]\(`)
+	// members can be looked up by a name in a string
+	metaLookupRegex = regexp.MustCompile(`\b(get-function|get-mixin|function-exists|mixin-exists|variable-exists|global-variable-exists)\(\s*(?:\$name\s*:\s*)?["']?([\w-]+)`)
+)
+
+// UnusedSymbol is a definition nothing in the workspace uses
+type UnusedSymbol struct {
+	Path string `json:"path"`
+	// variable, mixin, function, placeholder or keyframes
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// one based, like the ones editors show
+	Line   uint32 `json:"line"`
+	Column uint32 `json:"column"`
+	// the name of the definition
+	start_position sitter.Point
+	end_position   sitter.Point
+}
+
+// what the workspace uses, by the names it calls things with anywhere, and
+// the definitions that namespaces and prefixed forwards lead to
+type symbolUses struct {
+	// item type and name
+	names map[string]bool
+	// item type, path and name of the definition
+	members map[string]bool
+}
+
+func (uses symbolUses) isUsed(path string, item_type string, name string) bool {
+	return uses.names[item_type+" "+name] || uses.members[item_type+" "+path+" "+name]
+}
+
+// keyframesDefinitions are the @keyframes of the file
+func keyframesDefinitions(tree *sitter.Tree, input []byte) []isDefined {
+	definitions := []isDefined{}
+	walkNamed(tree.RootNode(), func(node *sitter.Node) {
+		if node.Type() != "keyframes_name" {
+			return
+		}
+		definitions = append(definitions, isDefined{
+			name:           node.Content(input),
+			body:           node.Content(input),
+			start_position: node.StartPoint(),
+			end_position:   node.EndPoint(),
+			item_type:      itemTypeKeyframes,
+		})
+	})
+	return definitions
+}
+
+// definitionNameRange is where the name of a definition is, the entries of
+// mixins, functions and variables span the whole statement
+func definitionNameRange(tree *sitter.Tree, entry isDefined, item_type string) (sitter.Point, sitter.Point) {
+	var node *sitter.Node
+	switch item_type {
+	case itemTypeVariable:
+		node = declarationOf(tree, entry)
+	case itemTypeMixin, itemTypeFunction, itemTypePlaceholder:
+		node = tree.RootNode().NamedDescendantForPointRange(entry.start_position, entry.end_position)
+	}
+	if node == nil || node.NamedChildCount() == 0 {
+		return entry.start_position, entry.end_position
+	}
+	if item_type == itemTypePlaceholder {
+		// the % is not part of the name node
+		return node.StartPoint(), node.NamedChild(0).EndPoint()
+	}
+	return node.NamedChild(0).StartPoint(), node.NamedChild(0).EndPoint()
+}
+
+// publicModules are the files of the public entry points of the config, and
+// what they import and forward, with the names they are known by outside
+func (lsp *Lsp) publicModules() []visibleModule {
+	modules := []visibleModule{}
+	for _, path := range sortedPaths(lsp.Trees) {
+		if !isUnderAny(path, lsp.rootRelative(lsp.Config.PublicEntryPoints)) {
+			continue
+		}
+		modules = append(modules, lsp.importedModules(path, false, []visibleModule{}, map[string]bool{})...)
+		modules = lsp.forwardedModules(visibleModule{path: path}, modules, map[string]bool{})
+	}
+	return modules
+}
+
+// symbolUses are the uses of the workspace, they are kept until a tree or the
+// config changes, every file checks against the same ones
+func (lsp *Lsp) symbolUses() symbolUses {
+	if lsp.uses == nil {
+		uses := lsp.scanSymbolUses()
+		lsp.uses = &uses
+	}
+	return *lsp.uses
+}
+
+// scanSymbolUses goes through the whole workspace once
+func (lsp *Lsp) scanSymbolUses() symbolUses {
+	uses := symbolUses{names: map[string]bool{}, members: map[string]bool{}}
+	member_types := []string{itemTypeMixin, itemTypeFunction, itemTypeVariable}
+	for _, path := range sortedPaths(lsp.Trees) {
+		input, err := lsp.bytesFromFilePath(path)
+		if err != nil {
+			continue
+		}
+		calls := map[string]bool{}
+		for _, entry := range lsp.Calls[path] {
+			calls[entry.item_type+" "+entry.name] = true
+			uses.names[entry.item_type+" "+entry.name] = true
+		}
+		for _, entry := range lsp.Extends[path] {
+			uses.names[itemTypePlaceholder+" "+entry.name] = true
+		}
+		masked := maskCommentsAndStrings(*input)
+		for _, match := range animationValueRegex.FindAllSubmatch(masked, -1) {
+			for _, name := range identifierRegex.FindAll(match[1], -1) {
+				uses.names[itemTypeKeyframes+" "+string(name)] = true
+			}
+		}
+		// a mixin or function can be handed the name to animate with
+		for _, arguments := range callArguments(masked) {
+			for _, name := range identifierRegex.FindAll(arguments, -1) {
+				uses.names[itemTypeKeyframes+" "+string(name)] = true
+			}
+		}
+		for _, match := range metaLookupRegex.FindAllSubmatch(*input, -1) {
+			name := string(match[2])
+			uses.names[itemTypeMixin+" "+name] = true
+			uses.names[itemTypeFunction+" "+name] = true
+			uses.names[itemTypeVariable+" $"+name] = true
+		}
+
+		references := map[string]bool{}
+		for _, reference := range lsp.namespacedReferences(path, *input) {
+			references[reference.item_type+" "+reference.namespace+"."+reference.member] = true
+		}
+		for _, module := range lsp.VisibleModules(path) {
+			for _, item_type := range member_types {
+				for _, entry := range lsp.definitionMap(item_type)[module.path] {
+					name, ok := module.memberName(entry.name)
+					if !ok || module.namespace == "" && name == entry.name {
+						// the names cover these
+						continue
+					}
+					if module.namespace == "" && calls[item_type+" "+name] || references[item_type+" "+module.namespace+"."+name] {
+						uses.members[item_type+" "+module.path+" "+entry.name] = true
+					}
+				}
+			}
+		}
+	}
+
+	for _, module := range lsp.publicModules() {
+		for _, item_type := range member_types {
+			for _, entry := range lsp.definitionMap(item_type)[module.path] {
+				if _, ok := module.memberName(entry.name); ok {
+					uses.members[item_type+" "+module.path+" "+entry.name] = true
+				}
+			}
+		}
+		for _, entry := range lsp.Placeholders[module.path] {
+			uses.members[itemTypePlaceholder+" "+module.path+" "+entry.name] = true
+		}
+		if tree, input, ok := lsp.treeAndInput(module.path); ok {
+			for _, entry := range keyframesDefinitions(tree, input) {
+				uses.members[itemTypeKeyframes+" "+module.path+" "+entry.name] = true
+			}
+		}
+	}
+	return uses
+}
+
+// callArguments are the arguments of the calls and includes in the masked
+// input, nested calls are part of the arguments around them as well, the
+// grammar doesnt parse namespaced includes so this goes by the text
+func callArguments(masked []byte) [][]byte {
+	arguments := [][]byte{}
+	for _, match := range callOpenRegex.FindAllIndex(masked, -1) {
+		depth := 1
+		for idx := match[1]; idx < len(masked); idx++ {
+			switch masked[idx] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 {
+				arguments = append(arguments, masked[match[1]:idx])
+				break
+			}
+		}
+	}
+	return arguments
+}
+
+func (lsp *Lsp) treeAndInput(path string) (*sitter.Tree, []byte, bool) {
+	tree := lsp.Trees[path]
+	input, err := lsp.bytesFromFilePath(path)
+	if tree == nil || err != nil {
+		return nil, nil, false
+	}
+	return tree, *input, true
+}
+
+// unusedSymbols are the definitions of the file that nothing uses, the
+// variables with !default are left alone since a @use can configure them,
+// and so are local variables
+func (lsp *Lsp) unusedSymbols(path string, uses symbolUses) []UnusedSymbol {
+	unused := []UnusedSymbol{}
+	tree, input, ok := lsp.treeAndInput(path)
+	if !ok {
+		return unused
+	}
+	defaults := lsp.defaultVariables(path)
+	seen := map[string]bool{}
+	add := func(item_type string, entry isDefined) {
+		if seen[item_type+" "+entry.name] || uses.isUsed(path, item_type, entry.name) {
+			return
+		}
+		seen[item_type+" "+entry.name] = true
+		start, end := definitionNameRange(tree, entry, item_type)
+		unused = append(unused, UnusedSymbol{
+			Path:           lsp.relativePath(path),
+			Kind:           strings.TrimLeft(item_type, "@$%"),
+			Name:           entry.name,
+			Line:           start.Row + 1,
+			Column:         start.Column + 1,
+			start_position: start,
+			end_position:   end,
+		})
+	}
+	for _, entry := range lsp.Variables[path] {
+		if !defaults[entry.name] && lsp.isTopLevelVariable(path, entry) {
+			add(itemTypeVariable, entry)
+		}
+	}
+	for _, entry := range lsp.Mixins[path] {
+		add(itemTypeMixin, entry)
+	}
+	for _, entry := range lsp.Functions[path] {
+		add(itemTypeFunction, entry)
+	}
+	for _, entry := range lsp.Placeholders[path] {
+		add(itemTypePlaceholder, entry)
+	}
+	for _, entry := range keyframesDefinitions(tree, input) {
+		add(itemTypeKeyframes, entry)
+	}
+	sort.SliceStable(unused, func(i, j int) bool {
+		return unused[i].Line < unused[j].Line || unused[i].Line == unused[j].Line && unused[i].Column < unused[j].Column
+	})
+	return unused
+}
+
+// UnusedSymbols lists the definitions nothing in the workspace uses, in the
+// files under paths or all of them
+func (lsp *Lsp) UnusedSymbols(paths []string) []UnusedSymbol {
+	unused := []UnusedSymbol{}
+	uses := lsp.symbolUses()
+	for _, path := range sortedPaths(lsp.Trees) {
+		if len(paths) > 0 && !isUnderAny(path, paths) {
+			continue
+		}
+		unused = append(unused, lsp.unusedSymbols(path, uses)...)
+	}
+	return unused
+}
+
+func unusedSymbolDiagnostics(context RuleContext) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+	for _, symbol := range context.Lsp.unusedSymbols(context.Path, context.Lsp.symbolUses()) {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:   rangeFromPoints(symbol.start_position, symbol.end_position),
+			Message: fmt.Sprintf("%s %s is never used", symbol.Kind, symbol.Name),
+			Tags:    []protocol.DiagnosticTag{protocol.DiagnosticTagUnnecessary},
+		})
+	}
+	return diagnostics
+}
@@ -0,0 +1,88 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestUnusedSymbols(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"lib/_buttons.scss": `$radius: 2px !default;
+$unused-color: red;
+@mixin primary { border-radius: $radius; }
+@mixin dead { color: red; }
+@function half($x) { @return math.div($x, 2); }
+@function by-name() { @return 1; }
+%base { margin: 0; }
+%orphan { padding: 0; }
+@keyframes spin { from { top: 0; } }
+@keyframes fade { from { opacity: 0; } }
+.local { $local: 1px; }
+@mixin animate($name) { animation: $name 1s; }
+@keyframes pulse { from { opacity: 0; } }
+@keyframes wobble { from { left: 0; } }
+`,
+		"lib/_index.scss": "@forward \"buttons\" as btn-*;\n",
+		"main.scss": `@use "sass:meta";
+@use "lib";
+.a {
+  @include lib.btn-primary;
+  width: lib.btn-half(2px);
+  @extend %base;
+  animation: spin 1s;
+  // the name can be an argument too
+  @include lib.btn-animate(pulse);
+  transition: timing(wobble 2s);
+  $f: meta.get-function("by-name");
+}
+`,
+	}
+	writeFiles(t, root, files)
+	lsp := LoadWorkspace(root)
+
+	found := []string{}
+	for _, symbol := range lsp.UnusedSymbols(nil) {
+		found = append(found, fmt.Sprintf("%s:%d:%d %s %s", symbol.Path, symbol.Line, symbol.Column, symbol.Kind, symbol.Name))
+	}
+	expected := "lib/_buttons.scss:2:1 variable $unused-color|lib/_buttons.scss:4:8 mixin dead|lib/_buttons.scss:8:1 placeholder %orphan|lib/_buttons.scss:10:12 keyframes fade"
+	if strings.Join(found, "|") != expected {
+		t.Fatalf("unexpected unused symbols %q", found)
+	}
+
+	diagnostics := lsp.getDiagnostics(filepath.Join(root, "lib/_buttons.scss"))
+	unused := []protocol.Diagnostic{}
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Code == diagnosticUnusedSymbol {
+			unused = append(unused, diagnostic)
+		}
+	}
+	if len(unused) != 4 || unused[1].Message != "mixin dead is never used" || unused[1].Severity != protocol.DiagnosticSeverityHint {
+		t.Fatalf("unexpected diagnostics %+v", unused)
+	}
+	if len(unused[1].Tags) != 1 || unused[1].Tags[0] != protocol.DiagnosticTagUnnecessary || unused[1].Range.End != (protocol.Position{Line: 3, Character: 11}) {
+		t.Fatalf("unexpected diagnostic %+v", unused[1])
+	}
+
+	// the uses are kept between files, a new tree has to drop them
+	files["main.scss"] += ".b { @include lib.btn-dead; }\n"
+	writeFiles(t, root, files)
+	if _, err := lsp.ParseAndSaveTree(filepath.Join(root, "main.scss")); err != nil {
+		t.Fatal(err)
+	}
+	if unused := diagnosticsWithCode(lsp.getDiagnostics(filepath.Join(root, "lib/_buttons.scss")), diagnosticUnusedSymbol); len(unused) != 3 {
+		t.Fatalf("expected mixin dead to be used, got %+v", unused)
+	}
+
+	// everything the entry point forwards is for other projects
+	files[".scss-lsp.json"] = `{"publicEntryPoints": ["lib/_index.scss"]}`
+	writeFiles(t, root, files)
+	lsp = LoadWorkspace(root)
+	if unused := lsp.UnusedSymbols(nil); len(unused) != 0 {
+		t.Fatalf("expected the public members to be used, got %+v", unused)
+	}
+}
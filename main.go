@@ -1,10 +1,27 @@
 package main
 
 import (
+	"os"
 	"scss-lsp/lsp"
 )
 
 func main() {
+  // subcommands run without a client, anything else is the server, some
+  // clients pass flags like --stdio
+  if len(os.Args) > 1 {
+    switch os.Args[1] {
+    case "migrate":
+      os.Exit(runMigrate(os.Args[2:]))
+    case "docs":
+      os.Exit(runDocs(os.Args[2:]))
+    case "check":
+      os.Exit(runCheck(os.Args[2:]))
+    case "rewrite":
+      os.Exit(runRewrite(os.Args[2:]))
+    case "unused":
+      os.Exit(runUnused(os.Args[2:]))
+    }
+  }
   lsp := lsp.Lsp{}
   lsp.Init()
 }
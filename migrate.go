@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.lsp.dev/uri"
+	"scss-lsp/lsp"
+)
+
+// runMigrate is `scss-lsp migrate`, it moves the @import graph of the entry
+// points to @use and prints the changes as a diff, or writes them
+func runMigrate(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	root := flags.String("root", ".", "the root of the workspace")
+	write := flags.Bool("write", false, "write the migrated files instead of printing a diff")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: scss-lsp migrate [-root dir] [-write] [entry.scss ...]")
+		fmt.Fprintln(flags.Output(), "without entry points every file that no other file loads is one")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	root_path, err := filepath.Abs(*root)
+	if err == nil {
+		_, err = os.Stat(root_path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	entries := []string{}
+	for _, entry := range flags.Args() {
+		entry_path, err := filepath.Abs(entry)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		entries = append(entries, entry_path)
+	}
+
+	workspace := lsp.LoadWorkspace(root_path)
+	edit, warnings := workspace.MigrateImports(entries)
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+
+	paths := []string{}
+	for file_uri := range edit.Changes {
+		paths = append(paths, string(file_uri))
+	}
+	sort.Strings(paths)
+	for _, file_uri := range paths {
+		edits := edit.Changes[uri.URI(file_uri)]
+		path := uri.URI(file_uri).Filename()
+		input, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if *write {
+			if err := os.WriteFile(path, lsp.ApplyEdits(input, edits), 0644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			continue
+		}
+		relative, err := filepath.Rel(root_path, path)
+		if err != nil {
+			relative = path
+		}
+		fmt.Print(lsp.EditDiff(relative, input, edits))
+	}
+	return 0
+}
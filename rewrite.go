@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.lsp.dev/uri"
+	"scss-lsp/lsp"
+)
+
+// runRewrite is `scss-lsp rewrite`, it replaces what a tree-sitter query
+// matches and prints the changes as a diff, or writes them
+func runRewrite(args []string) int {
+	flags := flag.NewFlagSet("rewrite", flag.ContinueOnError)
+	root := flags.String("root", ".", "the root of the workspace")
+	query := flags.String("query", "", "the tree-sitter query, the @match capture is what gets replaced")
+	query_file := flags.String("query-file", "", "the file to read the query from instead")
+	replace := flags.String("replace", "", "the replacement, {{name}} is the text of the capture @name")
+	write := flags.Bool("write", false, "write the rewritten files instead of printing a diff")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: scss-lsp rewrite [-root dir] -query query | -query-file file -replace template [-write] [paths ...]")
+		fmt.Fprintln(flags.Output(), "without paths every file of the workspace is rewritten")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *query_file != "" {
+		data, err := os.ReadFile(*query_file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		*query = string(data)
+	}
+	if *query == "" {
+		fmt.Fprintln(os.Stderr, "a query is needed")
+		flags.Usage()
+		return 2
+	}
+	root_path, err := filepath.Abs(*root)
+	if err == nil {
+		_, err = os.Stat(root_path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	paths := []string{}
+	for _, path := range flags.Args() {
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		paths = append(paths, absolute)
+	}
+
+	workspace := lsp.LoadWorkspace(root_path)
+	result, err := workspace.Rewrite(*query, *replace, paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	files := []string{}
+	for file_uri := range result.Edit.Changes {
+		files = append(files, string(file_uri))
+	}
+	sort.Strings(files)
+	for _, file_uri := range files {
+		edits := result.Edit.Changes[uri.URI(file_uri)]
+		path := uri.URI(file_uri).Filename()
+		input, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if *write {
+			if err := os.WriteFile(path, lsp.ApplyEdits(input, edits), 0644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			continue
+		}
+		relative, err := filepath.Rel(root_path, path)
+		if err != nil {
+			relative = path
+		}
+		fmt.Print(lsp.EditDiff(relative, input, edits))
+	}
+	fmt.Fprintf(os.Stderr, "%d matches in %d files\n", len(result.Matches), len(files))
+	return 0
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"scss-lsp/lsp"
+)
+
+// runUnused is `scss-lsp unused`, it lists the definitions nothing in the
+// workspace uses
+func runUnused(args []string) int {
+	flags := flag.NewFlagSet("unused", flag.ContinueOnError)
+	root := flags.String("root", ".", "the root of the workspace")
+	format := flags.String("format", lsp.FormatHuman, "human or json")
+	out := flags.String("out", "", "the file to write the report to instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: scss-lsp unused [-root dir] [-format name] [-out file] [paths ...]")
+		fmt.Fprintln(flags.Output(), "without paths every file of the workspace is looked at, the uses are always looked for in all of them")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != lsp.FormatHuman && *format != lsp.FormatJson {
+		fmt.Fprintf(os.Stderr, "unknown format %s\n", *format)
+		flags.Usage()
+		return 2
+	}
+	root_path, err := filepath.Abs(*root)
+	if err == nil {
+		_, err = os.Stat(root_path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	paths := []string{}
+	for _, path := range flags.Args() {
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		paths = append(paths, absolute)
+	}
+
+	workspace := lsp.LoadWorkspace(root_path)
+	for _, message := range workspace.ConfigErrors {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", message)
+	}
+	unused := workspace.UnusedSymbols(paths)
+
+	var report strings.Builder
+	if *format == lsp.FormatJson {
+		data, err := json.MarshalIndent(unused, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		report.Write(data)
+		report.WriteString("\n")
+	} else {
+		for _, symbol := range unused {
+			report.WriteString(fmt.Sprintf("%s:%d:%d: %s %s\n", symbol.Path, symbol.Line, symbol.Column, symbol.Kind, symbol.Name))
+		}
+		fmt.Fprintf(os.Stderr, "%d unused symbols\n", len(unused))
+	}
+	if *out == "" {
+		fmt.Print(report.String())
+	} else if err := os.WriteFile(*out, []byte(report.String()), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}